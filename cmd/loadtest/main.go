@@ -0,0 +1,205 @@
+// loadtestは起動中のサーバーに対して簡易的な負荷をかけ、レイテンシのパーセンタイルを出力するコマンド
+// キャパシティの大まかな確認を目的としており、本格的な負荷試験ツールの代替ではない。
+//
+//	go run github.com/megur0/simple-server/cmd/loadtest -c 20 -d 10s -url http://localhost:8080/friends -url http://localhost:8080/friend/1
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 複数回指定可能なフラグ
+type multiFlag []string
+
+func (f *multiFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *multiFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+type config struct {
+	urls        []string
+	method      string
+	body        string
+	headers     []string
+	concurrency int
+	duration    time.Duration
+	timeout     time.Duration
+}
+
+// 1リクエストの結果
+type result struct {
+	status  int
+	latency time.Duration
+	err     error
+	// 実行時間の終了によってキャンセルされたか
+	canceled bool
+}
+
+func main() {
+	var c config
+	var urls, headers multiFlag
+	flag.Var(&urls, "url", "target url (repeatable; requests are distributed round-robin)")
+	flag.Var(&headers, "H", `request header such as "Authorization: Bearer xxx" (repeatable)`)
+	flag.StringVar(&c.method, "method", http.MethodGet, "http method")
+	flag.StringVar(&c.body, "body", "", "request body")
+	flag.IntVar(&c.concurrency, "c", 10, "number of concurrent workers")
+	flag.DurationVar(&c.duration, "d", 10*time.Second, "duration of the test")
+	flag.DurationVar(&c.timeout, "timeout", 10*time.Second, "timeout of each request")
+	flag.Parse()
+	c.urls = urls
+	c.headers = headers
+
+	if len(c.urls) == 0 || c.concurrency <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	results := run(context.Background(), c)
+	report(os.Stdout, summarize(results, c.duration))
+}
+
+// concurrency個のワーカーでduration間リクエストを送り続ける。
+func run(ctx context.Context, c config) []result {
+	ctx, cancel := context.WithTimeout(ctx, c.duration)
+	defer cancel()
+
+	client := &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        c.concurrency,
+			MaxIdleConnsPerHost: c.concurrency,
+		},
+	}
+
+	var mu sync.Mutex
+	var results []result
+	var wg sync.WaitGroup
+	for worker := range c.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var local []result
+			for i := worker; ctx.Err() == nil; i += c.concurrency {
+				local = append(local, do(ctx, client, c, c.urls[i%len(c.urls)]))
+			}
+			mu.Lock()
+			results = append(results, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func do(ctx context.Context, client *http.Client, c config, url string) result {
+	var body io.Reader
+	if c.body != "" {
+		body = strings.NewReader(c.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, c.method, url, body)
+	if err != nil {
+		return result{err: err}
+	}
+	for _, h := range c.headers {
+		if k, v, ok := strings.Cut(h, ":"); ok {
+			req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		// リクエストのタイムアウト(-timeout)はエラーとして集計するため、実行時間の終了によるキャンセルのみ区別する。
+		return result{err: err, latency: time.Since(start), canceled: ctx.Err() != nil}
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return result{status: res.StatusCode, latency: time.Since(start)}
+}
+
+type summary struct {
+	requests int
+	errors   int
+	rps      float64
+	statuses map[int]int
+	// キーはパーセンタイル(50, 90, 99, 100)
+	percentiles map[int]time.Duration
+}
+
+var reportPercentiles = []int{50, 90, 99, 100}
+
+func summarize(results []result, duration time.Duration) summary {
+	s := summary{
+		statuses:    map[int]int{},
+		percentiles: map[int]time.Duration{},
+	}
+	latencies := []time.Duration{}
+	for _, r := range results {
+		// 終了時のキャンセルによるエラーは集計しない。
+		if r.canceled {
+			continue
+		}
+		s.requests++
+		if r.err != nil {
+			s.errors++
+			continue
+		}
+		s.statuses[r.status]++
+		latencies = append(latencies, r.latency)
+	}
+	if duration > 0 {
+		s.rps = float64(s.requests) / duration.Seconds()
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	for _, p := range reportPercentiles {
+		s.percentiles[p] = percentile(latencies, p)
+	}
+	return s
+}
+
+// ソート済みのlatenciesからpパーセンタイルの値を返す。(nearest-rank method)
+func percentile(latencies []time.Duration, p int) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := (p*len(latencies) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+func report(w io.Writer, s summary) {
+	fmt.Fprintf(w, "requests: %d\n", s.requests)
+	fmt.Fprintf(w, "errors:   %d\n", s.errors)
+	fmt.Fprintf(w, "rps:      %.1f\n", s.rps)
+	fmt.Fprintln(w, "latency:")
+	for _, p := range reportPercentiles {
+		label := fmt.Sprintf("p%d", p)
+		if p == 100 {
+			label = "max"
+		}
+		fmt.Fprintf(w, "  %-4s %s\n", label, s.percentiles[p])
+	}
+	fmt.Fprintln(w, "status:")
+	codes := []int{}
+	for code := range s.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %d: %d\n", code, s.statuses[code])
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestPercentile$ ./cmd/loadtest
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for _, v := range []struct {
+		p      int
+		expect time.Duration
+	}{
+		{p: 50, expect: 50 * time.Millisecond},
+		{p: 90, expect: 90 * time.Millisecond},
+		{p: 99, expect: 99 * time.Millisecond},
+		{p: 100, expect: 100 * time.Millisecond},
+	} {
+		if got := percentile(latencies, v.p); got != v.expect {
+			t.Errorf("p%d: got %s, want %s", v.p, got, v.expect)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("empty: got %s", got)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRun$ ./cmd/loadtest
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	d := 200 * time.Millisecond
+	s := summarize(run(context.Background(), config{
+		urls:        []string{ts.URL + "/a", ts.URL + "/b"},
+		method:      http.MethodGet,
+		concurrency: 4,
+		duration:    d,
+		timeout:     time.Second,
+	}), d)
+	if s.requests == 0 || s.statuses[http.StatusNoContent] != s.requests-s.errors {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRunTimeout$ ./cmd/loadtest
+func TestRunTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	// リクエストのタイムアウトはエラーとして集計され、実行時間の終了によるキャンセルは集計されない。
+	d := 300 * time.Millisecond
+	s := summarize(run(context.Background(), config{
+		urls:        []string{ts.URL},
+		method:      http.MethodGet,
+		concurrency: 2,
+		duration:    d,
+		timeout:     50 * time.Millisecond,
+	}), d)
+	if s.requests == 0 || s.errors != s.requests {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
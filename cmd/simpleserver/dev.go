@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 開発用のサーバー起動の設定
+type devConfig struct {
+	// 監視対象のディレクトリ。ビルドもこのディレクトリで行う。
+	dir string
+	// 監視対象のファイルの拡張子
+	exts []string
+	// ファイルの変更を確認する間隔
+	interval time.Duration
+	// 再起動時にサーバーのgraceful shutdownを待つ時間。
+	// 過ぎた場合はプロセスをkillする。
+	stopTimeout time.Duration
+	// サーバーに渡す引数
+	args []string
+}
+
+// ソースファイルを監視し、変更があればビルドしてサーバーを再起動する。
+// 再起動の際は起動中のサーバーにSIGTERMを送るため、
+// server.StartServerのgraceful shutdownがそのまま利用される。
+// テンプレートなどのGo以外のファイルも、extsに含めることで変更時に再起動される。
+func dev(c context.Context, conf devConfig) error {
+	bin, err := os.CreateTemp("", "simpleserver-dev-*")
+	if err != nil {
+		return err
+	}
+	bin.Close()
+	defer os.Remove(bin.Name())
+
+	var proc *exec.Cmd
+	defer func() { stopProcess(proc, conf.stopTimeout) }()
+
+	var prev map[string]time.Time
+	ticker := time.NewTicker(conf.interval)
+	defer ticker.Stop()
+	for {
+		cur, err := snapshot(conf.dir, conf.exts)
+		if err != nil {
+			return err
+		}
+		if prev == nil || changed(prev, cur) {
+			prev = cur
+			if err := build(conf.dir, bin.Name()); err != nil {
+				// ビルドに失敗した場合は起動中のサーバーをそのまま動かしておく。
+				fmt.Fprintln(os.Stderr, "[dev] build failed:", err)
+			} else {
+				stopProcess(proc, conf.stopTimeout)
+				proc = exec.Command(bin.Name(), conf.args...)
+				proc.Dir = conf.dir
+				proc.Stdout = os.Stdout
+				proc.Stderr = os.Stderr
+				if err := proc.Start(); err != nil {
+					return err
+				}
+				fmt.Println("[dev] server started")
+			}
+		}
+
+		select {
+		case <-c.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func build(dir string, out string) error {
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// プロセスにSIGTERMを送り、終了を待つ。
+// タイムアウトを過ぎた場合や、SIGTERMを送れない環境(Windows)ではkillする。
+func stopProcess(proc *exec.Cmd, timeout time.Duration) {
+	if proc == nil || proc.Process == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		proc.Wait()
+		close(done)
+	}()
+	if err := proc.Process.Signal(syscall.SIGTERM); err != nil {
+		proc.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		proc.Process.Kill()
+		<-done
+	}
+}
+
+// dir配下の監視対象ファイルの更新日時を取得する。
+func snapshot(dir string, exts []string) (map[string]time.Time, error) {
+	files := map[string]time.Time{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.Contains(exts, filepath.Ext(path)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = info.ModTime()
+		return nil
+	})
+	return files, err
+}
+
+func changed(prev map[string]time.Time, cur map[string]time.Time) bool {
+	if len(prev) != len(cur) {
+		return true
+	}
+	for path, t := range cur {
+		if pt, ok := prev[path]; !ok || !pt.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func runDev(conf devConfig) error {
+	c, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return dev(c, conf)
+}
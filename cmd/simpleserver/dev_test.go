@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestSnapshot$ ./cmd/simpleserver
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go")
+	write("README.md")
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write(".git/ignored.go")
+
+	exts := []string{".go", ".html"}
+	prev, err := snapshot(dir, exts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev) != 1 {
+		t.Fatalf("only main.go should be watched: %v", prev)
+	}
+
+	t.Run("変更なし", func(t *testing.T) {
+		cur, err := snapshot(dir, exts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed(prev, cur) {
+			t.Error("should not be changed")
+		}
+	})
+
+	t.Run("ファイルの更新", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, "main.go"), future, future); err != nil {
+			t.Fatal(err)
+		}
+		cur, err := snapshot(dir, exts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed(prev, cur) {
+			t.Error("should be changed")
+		}
+	})
+
+	t.Run("ファイルの追加", func(t *testing.T) {
+		write("index.html")
+		cur, err := snapshot(dir, exts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed(prev, cur) {
+			t.Error("should be changed")
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*$`)
+
+type handlerData struct {
+	Package string
+	// 先頭が大文字の名前(例: User)
+	Name string
+	// 先頭が小文字の名前(例: user)
+	LowerName string
+	// ルートのパス(例: /users)
+	Path string
+}
+
+// 指定されたリソース名のハンドラと、そのテストの雛形を生成する。
+// 既にファイルが存在する場合は上書きせずにエラーとする。
+// ハンドラで共通の処理(handler_helper.go)は、パッケージに存在しない場合のみ生成する。
+func newHandler(dir string, pkg string, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid handler name: %s", name)
+	}
+	if pkg == "" {
+		var err error
+		if pkg, err = detectPackage(dir); err != nil {
+			return err
+		}
+	}
+
+	data := handlerData{
+		Package:   pkg,
+		Name:      strings.ToUpper(name[:1]) + name[1:],
+		LowerName: strings.ToLower(name[:1]) + name[1:],
+		Path:      "/" + strings.ToLower(name) + "s",
+	}
+
+	files := []struct {
+		name string
+		tmpl *template.Template
+	}{
+		{name: strings.ToLower(name) + "_handler.go", tmpl: handlerTemplate},
+		{name: strings.ToLower(name) + "_handler_test.go", tmpl: handlerTestTemplate},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := writeTemplate(path, f.tmpl, data); err != nil {
+			return err
+		}
+		fmt.Println("created:", path)
+	}
+
+	helper := filepath.Join(dir, "handler_helper.go")
+	if _, err := os.Stat(helper); err == nil {
+		return nil
+	}
+	if err := writeTemplate(helper, handlerHelperTemplate, data); err != nil {
+		return err
+	}
+	fmt.Println("created:", helper)
+	return nil
+}
+
+// ディレクトリのパッケージ名を返す。
+// 既存のGoのファイルがある場合はそのパッケージ名、無い場合はディレクトリ名(名前として不正な場合はmain)とする。
+func detectPackage(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if pkg := filepath.Base(abs); validName.MatchString(pkg) {
+		return pkg, nil
+	}
+	return "main", nil
+}
+
+// テンプレートを実行してgofmtした結果をファイルに書き込む。
+func writeTemplate(path string, tmpl *template.Template, data any) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("file already exists: %s", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+var handlerTemplate = template.Must(template.New("handler").Parse(`package {{.Package}}
+
+import (
+	"net/http"
+
+	"github.com/megur0/simple-server/server"
+)
+
+// {{.Name}}のルートを登録する。
+// server.StartServerの前に呼び出す。
+func Register{{.Name}}Routes(middleware ...server.Middleware) {
+	server.Get("{{.Path}}/:id", get{{.Name}}Handler, middleware...)
+	server.Post("{{.Path}}", create{{.Name}}Handler, middleware...)
+}
+
+type get{{.Name}}Request struct {
+	ID string ` + "`param:\"id\"`" + `
+}
+
+type create{{.Name}}Request struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type {{.LowerName}}Response struct {
+	ID   string ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func get{{.Name}}Handler(w http.ResponseWriter, r *http.Request) {
+	handle(w, r, http.StatusOK, get{{.Name}})
+}
+
+func create{{.Name}}Handler(w http.ResponseWriter, r *http.Request) {
+	handle(w, r, http.StatusCreated, create{{.Name}})
+}
+
+// TODO: ロジックを実装する
+func get{{.Name}}(req *get{{.Name}}Request) (*{{.LowerName}}Response, error) {
+	return &{{.LowerName}}Response{ID: req.ID}, nil
+}
+
+// TODO: ロジックを実装する
+func create{{.Name}}(req *create{{.Name}}Request) (*{{.LowerName}}Response, error) {
+	return &{{.LowerName}}Response{Name: req.Name}, nil
+}
+`))
+
+// 同じパッケージのハンドラで共通の処理
+// ハンドラごとに生成すると宣言が重複するため、別のファイルとしてパッケージに1つだけ生成する。
+var handlerHelperTemplate = template.Must(template.New("handler_helper").Parse(`package {{.Package}}
+
+import (
+	"net/http"
+
+	"github.com/megur0/simple-server/server"
+)
+
+type errorResponse struct {
+	Message string ` + "`json:\"message\"`" + `
+}
+
+// リクエストのBind、ロジックの実行、レスポンスの設定を行う。
+func handle[REQ, RES any](w http.ResponseWriter, r *http.Request, successStatusCode int, logic func(*REQ) (RES, error)) {
+	req := new(REQ)
+	if err := server.Bind(r, req); err != nil {
+		server.SetResponseAsJson(w, r, http.StatusBadRequest, errorResponse{Message: err.Error()})
+		return
+	}
+
+	res, err := logic(req)
+	if err != nil {
+		server.SetResponseAsJson(w, r, http.StatusInternalServerError, errorResponse{Message: "internal server error"})
+		return
+	}
+
+	server.SetResponseAsJson(w, r, successStatusCode, res)
+}
+`))
+
+var handlerTestTemplate = template.Must(template.New("handler_test").Parse(`package {{.Package}}
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// go test -v -count=1 -timeout 60s -run ^Test{{.Name}}Handler$ .
+func Test{{.Name}}Handler(t *testing.T) {
+	t.Run("成功：POST {{.Path}}", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "{{.Path}}", strings.NewReader(` + "`" + `{"name":"test"}` + "`" + `))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		create{{.Name}}Handler(res, req)
+
+		if res.Code != http.StatusCreated {
+			t.Fatalf("unexpected status: %d, body: %s", res.Code, res.Body.String())
+		}
+	})
+
+	t.Run("失敗：不正なJSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "{{.Path}}", strings.NewReader(` + "`" + `{"name":` + "`" + `))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		create{{.Name}}Handler(res, req)
+
+		if res.Code != http.StatusBadRequest {
+			t.Fatalf("unexpected status: %d, body: %s", res.Code, res.Body.String())
+		}
+	})
+}
+`))
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestNewHandler$ ./cmd/simpleserver
+func TestNewHandler(t *testing.T) {
+	t.Run("成功：ハンドラとテストの雛形を生成", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := run([]string{"new", "handler", "-dir", dir, "-pkg", "api", "user"}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		src, err := os.ReadFile(filepath.Join(dir, "user_handler.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"package api", "func RegisterUserRoutes(", `server.Get("/users/:id"`} {
+			if !strings.Contains(string(src), want) {
+				t.Errorf("generated code should contain %q", want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "user_handler_test.go")); err != nil {
+			t.Error("test file should be generated:", err)
+		}
+	})
+
+	t.Run("失敗：既にファイルが存在", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := run([]string{"new", "handler", "-dir", dir, "user"}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if err := run([]string{"new", "handler", "-dir", dir, "user"}); err == nil {
+			t.Error("should be error")
+		}
+	})
+
+	t.Run("失敗：不正な名前", func(t *testing.T) {
+		if err := run([]string{"new", "handler", "-dir", t.TempDir(), "user-name"}); err == nil {
+			t.Error("should be error")
+		}
+	})
+}
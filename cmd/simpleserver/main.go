@@ -0,0 +1,77 @@
+// simpleserverはserverパッケージを使ったコードの雛形を生成するコマンド
+//
+//	go run github.com/megur0/simple-server/cmd/simpleserver new handler user
+//	go run github.com/megur0/simple-server/cmd/simpleserver new project github.com/you/app
+//	go run github.com/megur0/simple-server/cmd/simpleserver dev
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `usage:
+  simpleserver new handler [-dir dir] [-pkg package] <name>
+  simpleserver new project [-dir dir] <module>
+  simpleserver dev [-dir dir] [-ext .go,.html] [-interval 500ms] [-- args...]
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) >= 1 && args[0] == "dev" {
+		fs := flag.NewFlagSet("dev", flag.ContinueOnError)
+		dir := fs.String("dir", ".", "directory to watch and build")
+		ext := fs.String("ext", ".go,.html,.tmpl", "comma separated extensions to watch")
+		interval := fs.Duration("interval", 500*time.Millisecond, "polling interval")
+		stopTimeout := fs.Duration("stop-timeout", 10*time.Second, "time to wait for graceful shutdown on restart")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return runDev(devConfig{
+			dir:         *dir,
+			exts:        strings.Split(*ext, ","),
+			interval:    *interval,
+			stopTimeout: *stopTimeout,
+			args:        fs.Args(),
+		})
+	}
+
+	if len(args) < 2 || args[0] != "new" {
+		return fmt.Errorf("%s", usage)
+	}
+
+	switch args[1] {
+	case "handler":
+		fs := flag.NewFlagSet("new handler", flag.ContinueOnError)
+		dir := fs.String("dir", ".", "output directory")
+		pkg := fs.String("pkg", "", "package name (default: package of the existing files or name of the output directory)")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%s", usage)
+		}
+		return newHandler(*dir, *pkg, fs.Arg(0))
+	case "project":
+		fs := flag.NewFlagSet("new project", flag.ContinueOnError)
+		dir := fs.String("dir", ".", "parent directory of the project")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%s", usage)
+		}
+		return newProject(*dir, fs.Arg(0))
+	default:
+		return fmt.Errorf("%s", usage)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+type projectData struct {
+	Module string
+}
+
+// 指定されたモジュール名でディレクトリを作成し、起動可能なプロジェクトの雛形を生成する。
+// main.go、go.mod、サンプルのリソース(example)のハンドラが生成される。
+func newProject(dir string, module string) error {
+	name := filepath.Base(module)
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid project name: %s", module)
+	}
+
+	projectDir := filepath.Join(dir, name)
+	if _, err := os.Stat(projectDir); err == nil {
+		return fmt.Errorf("directory already exists: %s", projectDir)
+	}
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		return err
+	}
+
+	data := projectData{Module: module}
+	gomod := filepath.Join(projectDir, "go.mod")
+	if err := executeToFile(gomod, goModTemplate, data); err != nil {
+		return err
+	}
+	fmt.Println("created:", gomod)
+
+	mainGo := filepath.Join(projectDir, "main.go")
+	if err := writeTemplate(mainGo, mainTemplate, data); err != nil {
+		return err
+	}
+	fmt.Println("created:", mainGo)
+
+	if err := newHandler(projectDir, "main", "example"); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nnext:\n  cd %s\n  go mod tidy\n  go run .\n", projectDir)
+	return nil
+}
+
+// gofmtの対象外のファイルをテンプレートから生成する。
+func executeToFile(path string, tmpl *template.Template, data any) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return tmpl.Execute(f, data)
+}
+
+var goModTemplate = template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.23
+`))
+
+var mainTemplate = template.Must(template.New("main").Parse(`package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/megur0/simple-server/server"
+)
+
+// 設定は環境変数から読み込む。
+type config struct {
+	Host            string
+	Port            int
+	ShutdownTimeout time.Duration
+}
+
+func loadConfig() (config, error) {
+	c := config{
+		Host:            getEnv("HOST", "0.0.0.0"),
+		Port:            8080,
+		ShutdownTimeout: 8 * time.Second,
+	}
+	if v := os.Getenv("PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return c, err
+		}
+		c.Port = port
+	}
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return c, err
+		}
+		c.ShutdownTimeout = d
+	}
+	return c, nil
+}
+
+func getEnv(key string, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
+// アクセスログを出力する共通ミドルウェア
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("[INFO] %s %s %s", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+func main() {
+	c, err := loadConfig()
+	if err != nil {
+		log.Fatalf("failed to load config: %s", err)
+	}
+	server.ShutdownTimeoutSecond = c.ShutdownTimeout
+
+	server.SetCommonMiddleware(accessLog)
+
+	// ヘルスチェック
+	server.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		server.SetResponse(w, r, server.ContentTypePlainText, http.StatusOK, []byte("ok"))
+	})
+
+	RegisterExampleRoutes()
+
+	server.StartServer(context.Background(), c.Host, c.Port)
+}
+`))
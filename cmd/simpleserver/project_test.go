@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestNewProject$ ./cmd/simpleserver
+func TestNewProject(t *testing.T) {
+	t.Run("成功：プロジェクトの雛形を生成", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := run([]string{"new", "project", "-dir", dir, "example.com/app"}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		for _, f := range []string{"go.mod", "main.go", "example_handler.go", "example_handler_test.go", "handler_helper.go"} {
+			if _, err := os.Stat(filepath.Join(dir, "app", f)); err != nil {
+				t.Errorf("%s should be generated: %s", f, err)
+			}
+		}
+	})
+
+	t.Run("成功：ハンドラを追加したプロジェクトをビルドできる", func(t *testing.T) {
+		goCmd, err := exec.LookPath("go")
+		if err != nil {
+			t.Skip("go command is not found")
+		}
+		dir := t.TempDir()
+		if err := run([]string{"new", "project", "-dir", dir, "example.com/app"}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		projectDir := filepath.Join(dir, "app")
+		if err := run([]string{"new", "handler", "-dir", projectDir, "user"}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		// 公開前の変更を検証するため、このリポジトリのserverパッケージを参照させる。
+		root, err := filepath.Abs("../..")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.OpenFile(filepath.Join(projectDir, "go.mod"), os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.WriteString("\nrequire github.com/megur0/simple-server v0.0.0\n\nreplace github.com/megur0/simple-server => " + root + "\n")
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(projectDir, "go.sum"), sum, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+			cmd := exec.Command(goCmd, args...)
+			cmd.Dir = projectDir
+			cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("go %s failed: %s\n%s", args[0], err, out)
+			}
+		}
+	})
+
+	t.Run("失敗：既にディレクトリが存在", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "app"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := run([]string{"new", "project", "-dir", dir, "app"}); err == nil {
+			t.Error("should be error")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// lambdaはserverパッケージのルーティングとミドルウェアをAWS Lambda上で動かすためのアダプター
+//
+// API GatewayのHTTP API、およびLambda Function URLのイベント(ペイロード形式 2.0)をhttp.Requestへ変換し、
+// ハンドラのレスポンスをイベントのレスポンスへ変換する。
+//
+//	func main() {
+//		server.Get("/hello", hello)
+//		lambda.Start(server.HTTPHandler())
+//	}
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// API Gateway HTTP API / Lambda Function URLのリクエストイベント(ペイロード形式 2.0)
+type Request struct {
+	Version               string            `json:"version"`
+	RawPath               string            `json:"rawPath"`
+	RawQueryString        string            `json:"rawQueryString"`
+	Cookies               []string          `json:"cookies,omitempty"`
+	Headers               map[string]string `json:"headers"`
+	QueryStringParameters map[string]string `json:"queryStringParameters,omitempty"`
+	RequestContext        RequestContext    `json:"requestContext"`
+	Body                  string            `json:"body,omitempty"`
+	IsBase64Encoded       bool              `json:"isBase64Encoded"`
+}
+
+type RequestContext struct {
+	DomainName string      `json:"domainName"`
+	RequestID  string      `json:"requestId"`
+	HTTP       HTTPContext `json:"http"`
+}
+
+type HTTPContext struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Protocol string `json:"protocol"`
+	SourceIP string `json:"sourceIp"`
+}
+
+// API Gateway HTTP API / Lambda Function URLのレスポンス(ペイロード形式 2.0)
+type Response struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// イベントをhttp.Requestへ変換してhを実行し、結果をレスポンスへ変換する。
+func Handle(c context.Context, h http.Handler, event Request) (Response, error) {
+	r, err := toHTTPRequest(c, event)
+	if err != nil {
+		return Response{}, err
+	}
+	w := newResponseWriter()
+	h.ServeHTTP(w, r)
+	return w.toResponse(), nil
+}
+
+func toHTTPRequest(c context.Context, event Request) (*http.Request, error) {
+	body := []byte(event.Body)
+	if event.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(event.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &url.URL{
+		Scheme:   "https",
+		Host:     event.RequestContext.DomainName,
+		Path:     event.RequestContext.HTTP.Path,
+		RawQuery: event.RawQueryString,
+	}
+	if event.RawPath != "" {
+		// RawPathはエスケープされているため、再度エスケープされないようにRawPathとして設定する。
+		path, err := url.PathUnescape(event.RawPath)
+		if err != nil {
+			return nil, err
+		}
+		u.Path = path
+		u.RawPath = event.RawPath
+	}
+
+	r, err := http.NewRequestWithContext(c, event.RequestContext.HTTP.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range event.Headers {
+		// ペイロード形式 2.0では同じ名前のヘッダーはカンマ区切りで結合されている。
+		r.Header.Set(k, v)
+	}
+	if len(event.Cookies) > 0 {
+		r.Header.Set("Cookie", strings.Join(event.Cookies, "; "))
+	}
+	if host := r.Header.Get("Host"); host != "" {
+		r.Host = host
+	}
+	r.RemoteAddr = event.RequestContext.HTTP.SourceIP
+	r.RequestURI = u.RequestURI()
+	if event.RequestContext.HTTP.Protocol != "" {
+		r.Proto = event.RequestContext.HTTP.Protocol
+	}
+	return r, nil
+}
+
+// レスポンスを記録するhttp.ResponseWriter
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseWriter() *responseWriter {
+	return &responseWriter{header: http.Header{}}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) toResponse() Response {
+	res := Response{
+		StatusCode: w.status,
+		Headers:    map[string]string{},
+	}
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	for k, v := range w.header {
+		if k == "Set-Cookie" {
+			res.Cookies = v
+			continue
+		}
+		res.Headers[k] = strings.Join(v, ",")
+	}
+
+	// テキストとして扱えない場合はbase64でエンコードする。
+	body := w.body.Bytes()
+	if utf8.Valid(body) {
+		res.Body = string(body)
+	} else {
+		res.Body = base64.StdEncoding.EncodeToString(body)
+		res.IsBase64Encoded = true
+	}
+	return res
+}
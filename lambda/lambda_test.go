@@ -0,0 +1,118 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/simple-server/server"
+	"github.com/megur0/testutil"
+)
+
+func setupRoutes() {
+	server.Post("/lambda/:id", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID    string `param:"id"`
+			Query string `query:"q"`
+			Name  string `json:"name"`
+		}
+		if err := server.Bind(r, &req); err != nil {
+			server.SetResponseAsJson(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		server.SetResponseAsJson(w, r, http.StatusCreated, map[string]string{
+			"id":     req.ID,
+			"query":  req.Query,
+			"name":   req.Name,
+			"cookie": r.Header.Get("Cookie"),
+		})
+	})
+	server.Get("/lambda-binary", func(w http.ResponseWriter, r *http.Request) {
+		server.SetResponse(w, r, "application/octet-stream", http.StatusOK, []byte{0xff, 0xfe})
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestHandle$ ./lambda
+func TestHandle(t *testing.T) {
+	setupRoutes()
+	h := server.HTTPHandler()
+
+	t.Run("成功：イベントの変換", func(t *testing.T) {
+		res, err := Handle(context.Background(), h, Request{
+			Version:         "2.0",
+			RawPath:         "/lambda/123",
+			RawQueryString:  "q=search",
+			Cookies:         []string{"a=1", "b=2"},
+			Headers:         map[string]string{"content-type": "application/json"},
+			RequestContext:  RequestContext{DomainName: "example.com", HTTP: HTTPContext{Method: http.MethodPost, Path: "/lambda/123"}},
+			Body:            base64.StdEncoding.EncodeToString([]byte(`{"name":"test"}`)),
+			IsBase64Encoded: true,
+		})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		testutil.AssertEqual(t, res.StatusCode, http.StatusCreated)
+		testutil.AssertEqual(t, res.Headers["Content-Type"], "application/json")
+		testutil.AssertEqual(t, strings.Join(res.Cookies, ";"), "session=abc")
+		testutil.AssertJsonExact(t, res.Body, `{"id":"123","query":"search","name":"test","cookie":"a=1; b=2"}`, nil)
+	})
+
+	t.Run("成功：エスケープされたパスは再度エスケープされない", func(t *testing.T) {
+		res, err := Handle(context.Background(), h, Request{
+			RawPath:        "/lambda/%E3%81%82%2Fb",
+			RequestContext: RequestContext{HTTP: HTTPContext{Method: http.MethodPost, Path: "/lambda/あ/b"}},
+		})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		testutil.AssertEqual(t, res.StatusCode, http.StatusCreated)
+		testutil.AssertJsonExact(t, res.Body, `{"id":"あ/b","query":"","name":"","cookie":""}`, nil)
+	})
+
+	t.Run("成功：バイナリのレスポンスはbase64になる", func(t *testing.T) {
+		res, err := Handle(context.Background(), h, Request{
+			RawPath:        "/lambda-binary",
+			RequestContext: RequestContext{HTTP: HTTPContext{Method: http.MethodGet}},
+		})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		testutil.AssertEqual(t, res.IsBase64Encoded, true)
+		testutil.AssertEqual(t, res.Body, base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe}))
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRuntime$ ./lambda
+func TestRuntime(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.SetResponse(w, r, server.ContentTypePlainText, http.StatusOK, []byte(r.URL.Path))
+	})
+
+	var got Response
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2018-06-01/runtime/invocation/next":
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", "req-1")
+			json.NewEncoder(w).Encode(Request{RawPath: "/hello", RequestContext: RequestContext{HTTP: HTTPContext{Method: http.MethodGet}}})
+		case "/2018-06-01/runtime/invocation/req-1/response":
+			json.NewDecoder(r.Body).Decode(&got)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	rt := &runtime{baseURL: api.URL + "/2018-06-01/runtime", client: api.Client()}
+	if err := rt.next(context.Background(), h); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	testutil.AssertEqual(t, got.StatusCode, http.StatusOK)
+	testutil.AssertEqual(t, got.Body, "/hello")
+}
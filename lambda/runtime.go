@@ -0,0 +1,83 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Lambdaのランタイムとしてイベントを待ち受け、hで処理する。
+// 環境変数AWS_LAMBDA_RUNTIME_APIで指定されたRuntime APIを利用するため、
+// AWS SDKのランタイムライブラリは不要。
+// この関数は戻らず、Runtime APIとの通信に失敗した場合はプロセスを終了する。
+func Start(h http.Handler) {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		log.Fatal("AWS_LAMBDA_RUNTIME_API is not set; not running on AWS Lambda")
+	}
+	rt := &runtime{baseURL: "http://" + api + "/2018-06-01/runtime", client: &http.Client{}}
+	for {
+		if err := rt.next(context.Background(), h); err != nil {
+			log.Fatalf("lambda runtime error: %s", err)
+		}
+	}
+}
+
+type runtime struct {
+	baseURL string
+	client  *http.Client
+}
+
+// 次のイベントを取得して処理し、結果をRuntime APIへ返す。
+func (rt *runtime) next(c context.Context, h http.Handler) error {
+	res, err := rt.client.Get(rt.baseURL + "/invocation/next")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from invocation/next: %d", res.StatusCode)
+	}
+	requestID := res.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+	// イベントの処理時間の上限をcontextに反映する。
+	if ms, err := strconv.ParseInt(res.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+		var cancel context.CancelFunc
+		c, cancel = context.WithDeadline(c, time.UnixMilli(ms))
+		defer cancel()
+	}
+
+	var event Request
+	if err := json.NewDecoder(res.Body).Decode(&event); err != nil {
+		return rt.post(requestID, "error", map[string]string{"errorMessage": err.Error(), "errorType": "InvalidEvent"})
+	}
+	response, err := Handle(c, h, event)
+	if err != nil {
+		return rt.post(requestID, "error", map[string]string{"errorMessage": err.Error(), "errorType": "InvalidEvent"})
+	}
+	return rt.post(requestID, "response", response)
+}
+
+func (rt *runtime) post(requestID string, kind string, body any) error {
+	jsn, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	res, err := rt.client.Post(rt.baseURL+"/invocation/"+requestID+"/"+kind, "application/json", bytes.NewReader(jsn))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status from invocation/%s: %d", kind, res.StatusCode)
+	}
+	return nil
+}
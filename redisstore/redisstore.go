@@ -0,0 +1,193 @@
+// RedisをKVストアとして利用するserver.Storeの実装
+// 外部のライブラリに依存せず、必要なコマンドのみをRESPで送信する。
+//
+//	store := redisstore.New(redisstore.Config{Addr: "localhost:6379"})
+//	defer store.Close()
+//	server.SetCommonAfterMiddleware(server.StoreRateLimitMiddleware(store, 100, time.Minute, clientIP))
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/megur0/simple-server/server"
+)
+
+var _ server.Store = (*Store)(nil)
+
+// Redisへの接続の設定
+type Config struct {
+	// "host:port"
+	Addr     string
+	Password string
+	DB       int
+	// 再利用のために保持する接続の最大数。0の場合は10
+	MaxIdle int
+	// 接続のタイムアウト。0の場合は5秒
+	DialTimeout time.Duration
+}
+
+// Redisを利用したserver.Store
+// 複数のgoroutineから同時に利用できる。
+type Store struct {
+	conf Config
+	mu   sync.Mutex
+	idle []*conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func New(conf Config) *Store {
+	if conf.MaxIdle == 0 {
+		conf.MaxIdle = 10
+	}
+	if conf.DialTimeout == 0 {
+		conf.DialTimeout = 5 * time.Second
+	}
+	return &Store{conf: conf}
+}
+
+// 保持している接続をすべて閉じる。
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cn := range s.idle {
+		cn.Close()
+	}
+	s.idle = nil
+	return nil
+}
+
+func (s *Store) getConn(c context.Context) (*conn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		cn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return cn, nil
+	}
+	s.mu.Unlock()
+
+	d := net.Dialer{Timeout: s.conf.DialTimeout}
+	nc, err := d.DialContext(c, "tcp", s.conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if s.conf.Password != "" {
+		if _, err := cn.do(c, "AUTH", s.conf.Password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if s.conf.DB != 0 {
+		if _, err := cn.do(c, "SELECT", strconv.Itoa(s.conf.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (s *Store) putConn(cn *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.idle) >= s.conf.MaxIdle {
+		cn.Close()
+		return
+	}
+	s.idle = append(s.idle, cn)
+}
+
+func (cn *conn) do(c context.Context, args ...string) (any, error) {
+	// contextに期限が無い場合はゼロ値となり、期限なしとなる。
+	deadline, _ := c.Deadline()
+	cn.SetDeadline(deadline)
+	if err := writeCommand(cn.w, args...); err != nil {
+		return nil, err
+	}
+	return readReply(cn.r)
+}
+
+// コマンドを実行して応答を返す。
+// 通信のエラーの場合は接続を破棄し、Redisのエラー(RedisError)の場合は接続を再利用する。
+func (s *Store) Do(c context.Context, args ...string) (any, error) {
+	cn, err := s.getConn(c)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(c, args...)
+	var redisErr *RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		cn.Close()
+		return nil, err
+	}
+	s.putConn(cn)
+	return reply, err
+}
+
+func (s *Store) Get(c context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.Do(c, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	return b, b != nil, nil
+}
+
+func (s *Store) Set(c context.Context, key string, val []byte, ttl time.Duration) error {
+	_, err := s.Do(c, setArgs(key, val, ttl)...)
+	return err
+}
+
+func (s *Store) SetNX(c context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	reply, err := s.Do(c, append(setArgs(key, val, ttl), "NX")...)
+	if err != nil {
+		return false, err
+	}
+	// 保存した場合は"OK"、キーが存在した場合はnull
+	return reply == "OK", nil
+}
+
+func setArgs(key string, val []byte, ttl time.Duration) []string {
+	args := []string{"SET", key, string(val)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	return args
+}
+
+func (s *Store) Delete(c context.Context, key string) error {
+	_, err := s.Do(c, "DEL", key)
+	return err
+}
+
+// 加算と期限の設定をアトミックに行うスクリプト
+const incrByScript = `local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if v == tonumber(ARGV[1]) and tonumber(ARGV[2]) > 0 then redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return v`
+
+func (s *Store) IncrBy(c context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	reply, err := s.Do(c, "EVAL", incrByScript, "1", key, strconv.FormatInt(n, 10), strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return 0, err
+	}
+	v, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	return v, nil
+}
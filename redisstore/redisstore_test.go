@@ -0,0 +1,151 @@
+package redisstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// テスト用のRedisサーバー
+// Storeが利用するコマンドのみを、期限を無視して処理する。
+type fakeRedis struct {
+	mu       sync.Mutex
+	data     map[string]string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeRedis{data: map[string]string{}}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r, w := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range req.([]any) {
+			args = append(args, string(a.([]byte)))
+		}
+		w.WriteString(f.exec(args))
+		w.Flush()
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, strings.Join(args, " "))
+	switch args[0] {
+	case "AUTH":
+		if args[1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+	case "SET":
+		if args[len(args)-1] == "NX" {
+			if _, ok := f.data[args[1]]; ok {
+				return "$-1\r\n"
+			}
+		}
+		f.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		delete(f.data, args[1])
+		return ":1\r\n"
+	case "EVAL":
+		cur, _ := strconv.ParseInt(f.data[args[3]], 10, 64)
+		n, _ := strconv.ParseInt(args[4], 10, 64)
+		f.data[args[3]] = strconv.FormatInt(cur+n, 10)
+		return ":" + strconv.FormatInt(cur+n, 10) + "\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStore$ ./redisstore
+func TestStore(t *testing.T) {
+	f, addr := startFakeRedis(t)
+	c := context.Background()
+	s := New(Config{Addr: addr, Password: "secret", DB: 2})
+	defer s.Close()
+
+	testutil.AssertEqual(t, s.Set(c, "a", []byte("hello\r\nworld"), time.Minute), nil)
+	v, ok, err := s.Get(c, "a")
+	testutil.AssertEqual(t, err, nil)
+	testutil.AssertEqual(t, ok, true)
+	testutil.AssertEqual(t, string(v), "hello\r\nworld")
+
+	_, ok, _ = s.Get(c, "none")
+	testutil.AssertEqual(t, ok, false)
+
+	set, _ := s.SetNX(c, "a", []byte("x"), 0)
+	testutil.AssertEqual(t, set, false)
+	set, _ = s.SetNX(c, "b", []byte("x"), 0)
+	testutil.AssertEqual(t, set, true)
+
+	n, _ := s.IncrBy(c, "counter", 2, time.Second)
+	testutil.AssertEqual(t, n, int64(2))
+	n, _ = s.IncrBy(c, "counter", 3, time.Second)
+	testutil.AssertEqual(t, n, int64(5))
+
+	testutil.AssertEqual(t, s.Delete(c, "a"), nil)
+	_, ok, _ = s.Get(c, "a")
+	testutil.AssertEqual(t, ok, false)
+
+	// 接続は再利用され、認証とDBの選択は最初の1回のみ
+	f.mu.Lock()
+	testutil.AssertEqual(t, f.commands[0], "AUTH secret")
+	testutil.AssertEqual(t, f.commands[1], "SELECT 2")
+	testutil.AssertEqual(t, f.commands[2], "SET a hello\r\nworld PX 60000")
+	testutil.AssertEqual(t, strings.Count(strings.Join(f.commands, "\n"), "AUTH"), 1)
+	f.mu.Unlock()
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStoreError$ ./redisstore
+func TestStoreError(t *testing.T) {
+	_, addr := startFakeRedis(t)
+	s := New(Config{Addr: addr, Password: "wrong"})
+	defer s.Close()
+
+	_, _, err := s.Get(context.Background(), "a")
+	var redisErr *RedisError
+	if !errors.As(err, &redisErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testutil.AssertEqual(t, redisErr.Message, "WRONGPASS invalid password")
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 登録済みのルートを別のパスでも受け付けるようにする。
+// ハンドラ、ミドルウェア、Routeで追加した設定はpatternのルートと共有され、
+// r.Pattern、RoutePatternはaliasesではなくpatternとなる。(ログやメトリクスは同じルートとして集計される)
+// patternを登録したすべてのメソッド(Anyを含む)が対象となるため、ルートを登録した後に呼び出す。
+// aliasesのパスパラメータの名前と順序は、patternと同じである必要がある。
+// patternのルートが無い場合、aliasesが既存のルートと重複する場合はpanicとなる。
+//
+//	server.Get("/healthz", health)
+//	server.Alias("/healthz", "/health", "/status")
+//	server.Alias("/users/:id", "/members/:id")
+func Alias(pattern string, aliases ...string) {
+	mustNotStarted("Alias")
+	var methods []string
+	for key := range patternIndex {
+		if method, p, _ := strings.Cut(key, " "); p == pattern {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		panic(fmt.Sprintf(PanicAliasNotFound, pattern))
+	}
+	// panicのメッセージが実行ごとに変わらないようにする。
+	slices.Sort(methods)
+
+	for _, alias := range aliases {
+		for _, method := range methods {
+			ru := patternIndex[method+" "+pattern]
+			if _, names := parsePattern(alias); !slices.Equal(names, ru.pathParamNames) {
+				panic(fmt.Sprintf(PanicAliasParams, alias, pattern))
+			}
+			insertRoute(method, alias, ru)
+			ru.aliases = append(ru.aliases, alias)
+		}
+	}
+}
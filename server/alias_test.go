@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestAlias$ ./server
+func TestAlias(t *testing.T) {
+	resetSetting()
+
+	mark := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Mark", "1")
+			next.ServeHTTP(w, r)
+		})
+	}
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		var vals []string
+		for _, name := range matchedRoute(r).pathParamNames {
+			vals = append(vals, getPathParamVal(r, name))
+		}
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Method+" "+RoutePattern(r)+" "+strings.Join(vals, ",")))
+	}
+	Get("/healthz", echo, mark)
+	Get("/users/:id", echo)
+	Post("/users/:id", echo)
+	Any("/files/*path", echo)
+	Alias("/healthz", "/health", "/status")
+	Alias("/users/:id", "/members/:id")
+	Alias("/files/*path", "/assets/*path")
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		want   string
+	}{
+		{"成功：別のパスで同じルートになる", http.MethodGet, "/health", "GET /healthz "},
+		{"成功：複数のパスを指定できる", http.MethodGet, "/status", "GET /healthz "},
+		{"成功：元のパス", http.MethodGet, "/healthz", "GET /healthz "},
+		{"成功：パスパラメータ", http.MethodGet, "/members/1", "GET /users/:id 1"},
+		{"成功：すべてのメソッドが対象", http.MethodPost, "/members/2", "POST /users/:id 2"},
+		{"成功：Anyのルート", http.MethodDelete, "/assets/a/b", "DELETE /files/*path a/b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), tc.want)
+		})
+	}
+
+	t.Run("成功：ミドルウェアを共有する", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		testutil.AssertEqual(t, w.Header().Get("X-Mark"), "1")
+	})
+
+	t.Run("成功：Routesに別のパスが含まれる", func(t *testing.T) {
+		var got []string
+		for _, ri := range Routes() {
+			got = append(got, fmt.Sprintf("%s %s [%s]", ri.Method, ri.Pattern, strings.Join(ri.Aliases, ",")))
+		}
+		testutil.AssertEqual(t, strings.Join(got, "\n"), strings.Join([]string{
+			"* /files/*path [/assets/*path]",
+			"GET /healthz [/health,/status]",
+			"GET /users/:id [/members/:id]",
+			"POST /users/:id [/members/:id]",
+		}, "\n"))
+	})
+
+	for _, tc := range []struct {
+		name    string
+		pattern string
+		alias   string
+		want    string
+	}{
+		{"失敗：ルートが無い", "/none", "/other", fmt.Sprintf(PanicAliasNotFound, "/none")},
+		{"失敗：パスパラメータが異なる", "/users/:id", "/members/:name/:id", fmt.Sprintf(PanicAliasParams, "/members/:name/:id", "/users/:id")},
+		{"失敗：既存のルートと重複する", "/healthz", "/status", fmt.Sprintf(PanicSameRoot, "/status")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(tc.want))
+			}()
+			Alias(tc.pattern, tc.alias)
+		})
+	}
+}
@@ -7,26 +7,44 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // リクエストデータを構造体へBindする。
 // 構造体以外が指定された場合はpanicとなる。
-// 構造体のタグには、"json", "query", "param", "form"を指定可能。
+// 構造体のタグには、"json", "query", "param", "form", "file", "header"を指定可能。
+// "header"はリクエストヘッダー(例: `header:"X-Request-Id"`)の値を、クエリーなどと同様に変換してbindする。
+// "query", "param", "form", "header"のスライスのフィールドは、",comma"オプション(例: `query:"ids,comma"`)で
+// カンマ区切りの値(例: ?ids=1,2,3)を要素に分割する。
 // タグがないフィールドが存在する場合はpanicとなる。
-// "multipart/form-data"はサポートしていない。
+// "multipart/form-data"の場合は、テキストのフィールドを"form"タグ、
+// ファイルを"file"タグ(*multipart.FileHeaderまたは[]*multipart.FileHeaderのフィールド)でbindする。
+// (メモリに保持するサイズはSetMultipartMaxMemoryで設定する)
 //
 // 本関数は値のバインドのみを行い、必須フィールドのチェックは含まれない。
 // 対象のフィールドが含まれない場合は何もセットしない。
 // その場合は構造体はデフォルト値のままになる。
 func Bind[S any](r *http.Request, s *S) error {
-	// "multipart/form-data"はサポートしていない。
+	bindStats.binds.Add(1)
+	err := bind(r, s)
+	if err != nil {
+		bindStats.errors.Add(1)
+	}
+	return err
+}
+
+// Bindの処理
+// sは構造体のポインタであり、型が実行時に決まる場合(BindDebugHandler)にも利用する。
+func bind(r *http.Request, s any) error {
 	// 指定されていない場合はチェックしない。
 	contentType := r.Header.Get("Content-Type")
-	if contentType != "" && !isFormRequest(r) && !strings.HasPrefix(contentType, ContentTypeJSON) {
+	if contentType != "" && !isFormRequest(r) && !isMultipartRequest(r) && !strings.HasPrefix(contentType, ContentTypeJSON) {
 		return errors.New("Content-Type is not supported:" + contentType)
 	}
 
@@ -35,13 +53,34 @@ func Bind[S any](r *http.Request, s *S) error {
 	if rt.Kind() != reflect.Struct {
 		panic("bind arg must be pointer to struct")
 	}
+	registerPIIType(rt)
+
+	isMultipartRequest := isMultipartRequest(r)
+	if isMultipartRequest {
+		// ファイルを含むボディ全体を文字列として読み込まないように、ParseMultipartFormでパースする。
+		// パースした結果はr.MultipartFormに保持されるため、再度Bindを行うこともできる。
+		if err := parseMultipartForm(r); err != nil {
+			return wrapByErrBind(&ErrRequestFormParse{
+				Err: err,
+			})
+		}
+		return bindFields(r, rv, rt, true)
+	}
 
-	body := IoReaderToString(r.Body)
+	body, err := readBody(r)
+	bindStats.bytesBuffered.Add(uint64(len(body)))
 	// 後続で再度読み取りできるように再度書き込む
 	r.Body = io.NopCloser(bytes.NewBuffer([]byte(body)))
+	if err != nil {
+		return wrapByErrBind(err)
+	}
 
 	// リクエストボディ -> 構造体へのbind
 	if body != "" && !isFormRequest(r) {
+		// 深いネストや大量の要素によってUnmarshalでCPUを消費しないように、事前に制限を確認する。
+		if err := checkJsonLimits(body, jsonLimits); err != nil {
+			return wrapByErrBind(err)
+		}
 		// Unmarshalによる変換の際は、
 		// ・json側に余分なフィールドがあってもエラーにならない。
 		// ・json側に存在しない構造体のフィールドは何も上書きされない。
@@ -50,7 +89,7 @@ func Bind[S any](r *http.Request, s *S) error {
 			jsonUnmarshalErrSyntaxErr := &json.SyntaxError{}
 			if errors.As(err, &jsonUnmarshalErrSyntaxErr) {
 				return wrapByErrBind(&ErrRequestJsonSyntaxError{
-					Json: body,
+					Json: scrubPIIJson(body),
 					Err:  err,
 				})
 			}
@@ -68,7 +107,7 @@ func Bind[S any](r *http.Request, s *S) error {
 			// どのフィールドのエラーなのか、という情報も返すことが理想ではあったが
 			// json.Unmarshalは、個別に定義した型のUnmarshalJSONを実行してエラーが発生した場合にそのerrorをそのまま返すため、難しかった。
 			return wrapByErrBind(&ErrRequestJsonSomethingInvalid{
-				Json: body,
+				Json: scrubPIIJson(body),
 				Err:  err,
 			})
 		}
@@ -83,19 +122,20 @@ func Bind[S any](r *http.Request, s *S) error {
 		}
 	}
 
-	// パラメータ、クエリー、フォーム -> 構造体へのbind
-	for i := range rt.NumField() {
-		j := rt.Field(i).Tag.Get("json")
-		if j != "" { // jsonの場合は既にbind済みのためここでは何もしない。
-			continue
-		}
+	return bindFields(r, rv, rt, isFormRequest)
+}
 
-		var fieldName string
-		var fieldValue *string
-		p := rt.Field(i).Tag.Get("param")
-		if p != "" {
-			fieldName = p
-			val := getPathParamVal(r, p)
+// パラメータ、クエリー、フォーム、ヘッダーの値を構造体のフィールドへセットする。
+// isFormRequestがtrueの場合、フォームの値はパース済みのr.Formから取得する。
+func bindFields(r *http.Request, rv reflect.Value, rt reflect.Type, isFormRequest bool) error {
+	// パラメータ、クエリー、フォーム -> 構造体へのbind
+	// クエリーは対象のフィールドがある場合のみ、1回だけパースする。
+	var query url.Values
+	for _, f := range getBindFields(rt) {
+		var values []string
+		switch f.source {
+		case bindSourceParam:
+			val := getPathParamVal(r, f.name)
 
 			// 空の場合はセットを行わない。
 			// 例えば/friend/:idといったパスに対してマッチするのは
@@ -103,40 +143,57 @@ func Bind[S any](r *http.Request, s *S) error {
 			// ここでもし空文字が取得される場合はそもそもパス指定の中にパスパラメータが
 			// 含まれていないケースとなる。
 			if val != "" {
-				fieldValue = &val
+				values = []string{val}
 			}
-		} else {
-			q := rt.Field(i).Tag.Get("query")
-			if q != "" {
-				fieldName = q
-				val, ok := r.URL.Query()[fieldName]
-				if ok {
-					fieldValue = &val[0]
-				}
-			} else {
-				f := rt.Field(i).Tag.Get("form")
-				if f != "" {
-					if !isFormRequest {
-						panic("form tag is only available in form request")
-					}
-					fieldName = f
-					val, ok := r.Form[fieldName]
-					if ok {
-						fieldValue = &val[0]
-					}
+		case bindSourceQuery:
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[f.name]
+		case bindSourceForm:
+			if !isFormRequest {
+				panic("form tag is only available in form request")
+			}
+			values = r.Form[f.name]
+		case bindSourceHeader:
+			// ヘッダー名の大文字・小文字は区別しない。
+			values = r.Header.Values(f.name)
+		case bindSourceFile:
+			if r.MultipartForm == nil {
+				panic("file tag is only available in multipart request")
+			}
+			// ファイルは文字列からの変換を行わずにそのままセットする。
+			if files := r.MultipartForm.File[f.name]; len(files) > 0 {
+				if f.multiple {
+					rv.Field(f.index).Set(reflect.ValueOf(files))
 				} else {
-					panic("binded struct should have at least one tag, which is json or param or query")
+					rv.Field(f.index).Set(reflect.ValueOf(files[0]))
 				}
 			}
+			continue
 		}
-		if fieldValue == nil {
+		if len(values) == 0 {
 			// リクエストに含まれていない場合はsetStrToStructFieldは実行しない。
 			// この場合は構造体はゼロバリューのままとなる。
 			continue
 		}
-		if err := setStrToStructField(rv.Field(i), *fieldValue); err != nil {
+		if f.comma {
+			values = splitCommaValues(values)
+		}
+		var err error
+		if f.multiple {
+			// 同じ名前の値(例: ?id=1&id=2)をすべてスライスへセットする。
+			err = setStrsToSliceField(rv.Field(f.index), values)
+		} else {
+			err = setStrToStructField(rv.Field(f.index), values[0])
+		}
+		if err != nil {
+			if f.pii {
+				// エラーのメッセージに値が含まれないようにする。
+				err = errPIIFieldFormat
+			}
 			return wrapByErrBind(&ErrRequestFieldFormat{
-				Field: fieldName,
+				Field: f.name,
 				Err:   err,
 			})
 		}
@@ -145,6 +202,158 @@ func Bind[S any](r *http.Request, s *S) error {
 	return nil
 }
 
+type bindSource int
+
+const (
+	bindSourceParam bindSource = iota
+	bindSourceQuery
+	bindSourceForm
+	bindSourceFile
+	bindSourceHeader
+)
+
+// jsonタグ以外でbindを行うフィールドの情報
+type bindField struct {
+	// 構造体のフィールドのインデックス
+	index int
+	// パラメータ名(タグに指定された値)
+	name   string
+	source bindSource
+	// 個人情報のフィールドか(`pii:"true"`)
+	pii bool
+	// 複数の値を受け取るフィールドか(スライス、[]*multipart.FileHeader)
+	multiple bool
+	// カンマ区切りの値を分割するか(",comma"オプション)
+	comma bool
+}
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// 個人情報のフィールドの変換に失敗した場合のエラー
+var errPIIFieldFormat = errors.New("invalid format (value omitted)")
+
+// 構造体の型ごとのbindFieldのキャッシュ
+// キーはreflect.Type、バリューは[]bindField
+// タグの解析をリクエストごとに行わず、パラメータ名の文字列も型ごとに1つを共有する。
+var bindFieldCache sync.Map
+
+func getBindFields(rt reflect.Type) []bindField {
+	if fields, ok := bindFieldCache.Load(rt); ok {
+		bindStats.cacheHits.Add(1)
+		return fields.([]bindField)
+	}
+	bindStats.cacheMisses.Add(1)
+
+	fields := []bindField{}
+	for i := range rt.NumField() {
+		tag := rt.Field(i).Tag
+		pii := tag.Get("pii") == "true"
+		if tag.Get("json") != "" { // jsonの場合はjson.Unmarshalでbindするため対象外
+			continue
+		}
+		if p := tag.Get("param"); p != "" {
+			fields = append(fields, newBindField(rt.Field(i), i, p, bindSourceParam, pii))
+		} else if q := tag.Get("query"); q != "" {
+			fields = append(fields, newBindField(rt.Field(i), i, q, bindSourceQuery, pii))
+		} else if f := tag.Get("form"); f != "" {
+			fields = append(fields, newBindField(rt.Field(i), i, f, bindSourceForm, pii))
+		} else if h := tag.Get("header"); h != "" {
+			fields = append(fields, newBindField(rt.Field(i), i, h, bindSourceHeader, pii))
+		} else if f := tag.Get("file"); f != "" {
+			ft := rt.Field(i).Type
+			if ft != fileHeaderType && ft != fileHeaderSliceType {
+				panic(fmt.Sprintf("file tag field must be *multipart.FileHeader or []*multipart.FileHeader: %s", rt.Field(i).Name))
+			}
+			fields = append(fields, bindField{index: i, name: f, source: bindSourceFile, multiple: ft == fileHeaderSliceType})
+		} else {
+			panic("binded struct should have at least one tag, which is json or param or query")
+		}
+	}
+	bindFieldCache.Store(rt, fields)
+	return fields
+}
+
+// "param", "query", "form", "header"のタグのbindFieldを作成する。
+// タグの値は"名前,オプション"の形式で、オプションには"comma"を指定できる。
+// 不明なオプションや、スライス以外のフィールドへのcommaの指定はpanicとなる。
+func newBindField(sf reflect.StructField, index int, tagValue string, source bindSource, pii bool) bindField {
+	name, opts, _ := strings.Cut(tagValue, ",")
+	f := bindField{index: index, name: name, source: source, pii: pii}
+	// パスパラメータは1つの値のため、スライスとして受け取るにはcommaを指定する。
+	f.multiple = source != bindSourceParam && isMultipleValueType(sf.Type)
+	switch opts {
+	case "":
+	case "comma":
+		if !isMultipleValueType(sf.Type) {
+			panic(fmt.Sprintf("comma option is only available for slice field: %s", sf.Name))
+		}
+		f.multiple = true
+		f.comma = true
+	default:
+		panic(fmt.Sprintf("unknown bind tag option %q: %s", opts, sf.Name))
+	}
+	return f
+}
+
+// カンマ区切りの値を分割する。空文字の値は要素を持たない。
+func splitCommaValues(values []string) []string {
+	var split []string
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		split = append(split, strings.Split(v, ",")...)
+	}
+	return split
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// 複数の値をスライスとして受け取る型かを返す。
+// UnmarshalTextなどを実装しているスライスの型(例: net.IP)は1つの値から変換するため対象外とする。
+func isMultipleValueType(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(textUnmarshalerType) && !pt.Implements(jsonUnmarshalerType)
+}
+
+// スライスのフィールドへ、各文字列を要素の型へ変換してセットする。
+// 変換に失敗した場合はフィールドを変更しない。
+func setStrsToSliceField(rv reflect.Value, strs []string) error {
+	slice := reflect.MakeSlice(rv.Type(), len(strs), len(strs))
+	for i, str := range strs {
+		if err := setStrToStructField(slice.Index(i), str); err != nil {
+			return err
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
+
+// リクエストボディを読み込む。
+// クライアントの中断などで、受信したボディがContent-Lengthより短い場合はErrRequestBodyIncompleteを返す。
+// (途中までのJSONをシンタックスエラーとして扱わないため)
+// それ以外の読み込みのエラー(BodyLimitMiddlewareの*http.MaxBytesErrorなど)はそのまま返す。
+func readBody(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(r.Body)
+	if errors.Is(err, io.ErrUnexpectedEOF) || (err == nil && r.ContentLength > 0 && int64(buf.Len()) < r.ContentLength) {
+		return buf.String(), &ErrRequestBodyIncomplete{ContentLength: r.ContentLength, Received: int64(buf.Len()), Err: err}
+	}
+	return buf.String(), err
+}
+
 func isFormRequest(r *http.Request) bool {
 	contentType := r.Header.Get("Content-Type")
 	return strings.HasPrefix(contentType, ContentTypeFormURLEnc)
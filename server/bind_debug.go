@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ルートのハンドラがBindするリクエストの構造体を設定する。
+// 構造体の値またはポインタを渡す。BindDebugHandlerなどで利用される。
+// 構造体以外を渡した場合はpanicとなる。
+//
+//	server.Post("/friend/:number", addFriend).Request(addFriendRequest{})
+func (rt *Route) Request(v any) *Route {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("request type must be struct, got %T", v))
+	}
+	rt.ru.requestType = t
+	return rt
+}
+
+// BindDebugHandlerへのリクエスト
+type bindDebugRequest struct {
+	// 対象のルートのメソッド。省略した場合はGET
+	Method string `json:"method"`
+	// リクエストのパス。クエリーを含めることができる。(例: /friend/1?page=2)
+	Path        string `json:"path"`
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+	// リクエストヘッダー("header"タグのフィールドの確認用)
+	Headers map[string]string `json:"headers"`
+}
+
+// BindDebugHandlerのレスポンス
+type bindDebugResponse struct {
+	// マッチしたルートの登録時のパス
+	Route string `json:"route"`
+	// Bindした結果の構造体
+	Bound any `json:"bound"`
+	// Bindのエラー
+	Error string `json:"error,omitempty"`
+	// ErrBindがラップしているエラーの型(例: *server.ErrRequestFieldFormat)
+	ErrorType string `json:"errorType,omitempty"`
+	// エラーとなったフィールド(ErrRequestFieldFormatの場合)
+	Field string `json:"field,omitempty"`
+}
+
+// サンプルのリクエストを、ルートのハンドラと同じようにBindした結果を返すハンドラ
+// クライアントの開発者が、連携時の問題(値がどのようにBindされるか、どのエラーになるか)を確認するためのもので、
+// 開発用であり、本番環境では登録しないこと。
+// 対象のルートにはRoute.Requestでリクエストの構造体を設定しておく必要がある。
+//
+//	server.Post("/_debug/bind", server.BindDebugHandler)
+//
+// リクエスト、レスポンスの例
+//
+//	{"method":"POST","path":"/friend/1?page=2","contentType":"application/json","body":"{\"name\":\"a\"}"}
+//	{"route":"/friend/:number","bound":{"Number":1,"Page":2,"name":"a"}}
+func BindDebugHandler(w http.ResponseWriter, r *http.Request) {
+	var req bindDebugRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SetResponseAsJson(w, r, http.StatusBadRequest, map[string]string{"message": "invalid request: " + err.Error()})
+		return
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	sample, err := http.NewRequestWithContext(r.Context(), req.Method, req.Path, strings.NewReader(req.Body))
+	if err != nil || !strings.HasPrefix(req.Path, "/") {
+		SetResponseAsJson(w, r, http.StatusBadRequest, map[string]string{"message": "invalid path: " + req.Path})
+		return
+	}
+	for k, v := range req.Headers {
+		sample.Header.Set(k, v)
+	}
+	if req.ContentType != "" {
+		sample.Header.Set("Content-Type", req.ContentType)
+	}
+
+	var ps pathParamValues
+	ru := lookupRoute(sample.Method, routingPath(sample), &ps)
+	if ru == nil {
+		SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": "route not found"})
+		return
+	}
+	if ru.requestType == nil {
+		SetResponseAsJson(w, r, http.StatusBadRequest, map[string]string{"message": "request type is not set for the route " + ru.pattern})
+		return
+	}
+	if ps.n > 0 {
+		sample = withPathParam(sample, ru, &ps)
+	}
+
+	bound := reflect.New(ru.requestType).Interface()
+	res := bindDebugResponse{Route: ru.pattern, Bound: bound}
+	if err := bind(sample, bound); err != nil {
+		res.Error = err.Error()
+		if inner := errors.Unwrap(err); inner != nil {
+			res.ErrorType = fmt.Sprintf("%T", inner)
+		} else {
+			res.ErrorType = fmt.Sprintf("%T", err)
+		}
+		var fieldErr *ErrRequestFieldFormat
+		if errors.As(err, &fieldErr) {
+			res.Field = fieldErr.Field
+		}
+	}
+	SetResponseAsJson(w, r, http.StatusOK, res)
+}
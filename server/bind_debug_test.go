@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+type bindDebugFriendRequest struct {
+	Number int    `param:"number"`
+	Page   int    `query:"page"`
+	Name   string `json:"name"`
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindDebugHandler$ ./server
+func TestBindDebugHandler(t *testing.T) {
+	resetSetting()
+	Post("/friend/:number", func(w http.ResponseWriter, r *http.Request) {}).Request(&bindDebugFriendRequest{})
+	Get("/friends", func(w http.ResponseWriter, r *http.Request) {})
+	Post("/_debug/bind", BindDebugHandler)
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/_debug/bind", strings.NewReader(body)))
+		return res
+	}
+
+	t.Run("成功：Bindした値を返す", func(t *testing.T) {
+		res := serve(`{"method":"POST","path":"/friend/1?page=2","contentType":"application/json","body":"{\"name\":\"a\"}"}`)
+		testutil.AssertEqual(t, res.Code, http.StatusOK)
+		testutil.AssertEqual(t, res.Body.String(), `{"route":"/friend/:number","bound":{"Number":1,"Page":2,"name":"a"}}`)
+	})
+
+	t.Run("成功：Bindのエラーを返す", func(t *testing.T) {
+		res := serve(`{"method":"POST","path":"/friend/abc","body":""}`)
+		testutil.AssertEqual(t, res.Code, http.StatusOK)
+		testutil.AssertEqual(t, res.Body.String(), `{"route":"/friend/:number","bound":{"Number":0,"Page":0,"name":""},"error":"bind error:field number: strconv.Atoi: parsing \"abc\": invalid syntax","errorType":"*server.ErrRequestFieldFormat","field":"number"}`)
+
+		res = serve(`{"method":"POST","path":"/friend/1","body":"{\"name\":"}`)
+		if !strings.Contains(res.Body.String(), `"errorType":"*server.ErrRequestJsonSyntaxError"`) {
+			t.Errorf("unexpected response: %s", res.Body.String())
+		}
+	})
+
+	t.Run("成功：リクエストヘッダーをBindする", func(t *testing.T) {
+		type traceRequest struct {
+			TraceID string `header:"X-Trace-Id"`
+		}
+		Get("/trace", func(w http.ResponseWriter, r *http.Request) {}).Request(&traceRequest{})
+		res := serve(`{"path":"/trace","headers":{"x-trace-id":"abc"}}`)
+		testutil.AssertEqual(t, res.Body.String(), `{"route":"/trace","bound":{"TraceID":"abc"}}`)
+	})
+
+	t.Run("失敗：ルートが無い場合は404、リクエストの型が無い場合は400", func(t *testing.T) {
+		testutil.AssertEqual(t, serve(`{"method":"GET","path":"/unknown"}`).Code, http.StatusNotFound)
+		testutil.AssertEqual(t, serve(`{"path":"/friends"}`).Code, http.StatusBadRequest)
+		testutil.AssertEqual(t, serve(`{"path":"friends"}`).Code, http.StatusBadRequest)
+	})
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/google/uuid"
@@ -417,7 +421,7 @@ func TestBind(t *testing.T) {
 
 	t.Run("成功: パスパラメータ(int)", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test/123", nil)
-		ctx := context.WithValue(req.Context(), contextKey{Key: "pathParam"}, pathParamTable{"id": "123"})
+		ctx := context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("id", "123"))
 		req = req.WithContext(ctx)
 
 		var result struct {
@@ -433,7 +437,7 @@ func TestBind(t *testing.T) {
 
 	t.Run("成功: パスパラメータ(UUID)", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test/0976b7cd-988b-45a7-a48a-af527c1ed9e3", nil)
-		ctx := context.WithValue(req.Context(), contextKey{Key: "pathParam"}, pathParamTable{"id": "0976b7cd-988b-45a7-a48a-af527c1ed9e3"})
+		ctx := context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("id", "0976b7cd-988b-45a7-a48a-af527c1ed9e3"))
 		req = req.WithContext(ctx)
 
 		var result struct {
@@ -449,7 +453,7 @@ func TestBind(t *testing.T) {
 
 	t.Run("成功: パスパラメータ(独自型でUnmarshalTextを実装)", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test/0976b7cd-988b-45a7-a48a-af527c1ed9e3", nil)
-		ctx := context.WithValue(req.Context(), contextKey{Key: "pathParam"}, pathParamTable{"id": "0976b7cd-988b-45a7-a48a-af527c1ed9e3"})
+		ctx := context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("id", "0976b7cd-988b-45a7-a48a-af527c1ed9e3"))
 		req = req.WithContext(ctx)
 
 		var result struct {
@@ -465,7 +469,7 @@ func TestBind(t *testing.T) {
 
 	t.Run("成功: パスパラメータ(独自型でUnmarshalを実装)", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test/\"0976b7cd-988b-45a7-a48a-af527c1ed9e3\"", nil)
-		ctx := context.WithValue(req.Context(), contextKey{Key: "pathParam"}, pathParamTable{"id": "\"0976b7cd-988b-45a7-a48a-af527c1ed9e3\""})
+		ctx := context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("id", "\"0976b7cd-988b-45a7-a48a-af527c1ed9e3\""))
 		req = req.WithContext(ctx)
 
 		var result struct {
@@ -548,7 +552,7 @@ func TestBind(t *testing.T) {
 
 	t.Run("失敗: 不正なパスパラメータ", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/test/invalid", nil)
-		ctx := context.WithValue(req.Context(), contextKey{Key: "pathParam"}, pathParamTable{"id": "invalid"})
+		ctx := context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("id", "invalid"))
 		req = req.WithContext(ctx)
 
 		var result struct {
@@ -566,6 +570,12 @@ func TestBind(t *testing.T) {
 	})
 }
 
+func newTestPathParamTable(name string, value string) *pathParamTable {
+	t := &pathParamTable{}
+	t.set(name, value)
+	return t
+}
+
 func getFormData(m map[string]string) string {
 	formData := url.Values{}
 	for key, value := range m {
@@ -573,3 +583,264 @@ func getFormData(m map[string]string) string {
 	}
 	return formData.Encode()
 }
+
+// go test -v -count=1 -timeout 60s -run ^TestBindSlice$ ./server
+func TestBindSlice(t *testing.T) {
+	type listRequest struct {
+		IDs    []int     `query:"id"`
+		Tags   []*string `query:"tag"`
+		Names  []string  `query:"name"`
+		Single int       `query:"single"`
+		IP     net.IP    `query:"ip"`
+	}
+
+	t.Run("成功：同じ名前のクエリーをスライスへbindする", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?id=1&id=2&id=3&tag=a&tag=b&single=1&single=2&ip=192.0.2.1", nil)
+		var result listRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, fmt.Sprint(result.IDs), "[1 2 3]")
+		testutil.AssertEqual(t, *result.Tags[0]+","+*result.Tags[1], "a,b")
+		// 含まれない場合は何もセットされない
+		testutil.AssertEqual(t, result.Names == nil, true)
+		// スライス以外のフィールドは最初の値
+		testutil.AssertEqual(t, result.Single, 1)
+		// UnmarshalTextを実装しているスライスの型は1つの値から変換する
+		testutil.AssertEqual(t, result.IP.String(), "192.0.2.1")
+	})
+
+	t.Run("成功：フォームの値をスライスへbindする", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id=4&id=5"))
+		req.Header.Set("Content-Type", ContentTypeFormURLEnc)
+		var result struct {
+			IDs []int64 `form:"id"`
+		}
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, fmt.Sprint(result.IDs), "[4 5]")
+	})
+
+	t.Run("失敗：要素の変換に失敗した場合はエラー", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?id=1&id=x", nil)
+		var result listRequest
+		var formatErr *ErrRequestFieldFormat
+		testutil.AssertEqual(t, errors.As(Bind(req, &result), &formatErr), true)
+		testutil.AssertEqual(t, formatErr.Field, "id")
+		testutil.AssertEqual(t, result.IDs == nil, true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindComma$ ./server
+func TestBindComma(t *testing.T) {
+	type listRequest struct {
+		IDs  []int    `query:"ids,comma"`
+		Tags []string `query:"tag,comma"`
+		Sort []string `query:"sort"`
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		ids    string
+		tags   string
+		sort   string
+	}{
+		{"成功：カンマ区切りの値を分割する", "/?ids=1,2,3&tag=a", "[1 2 3]", "[a]", "[]"},
+		{"成功：同じ名前の値も合わせて分割する", "/?ids=1,2&ids=3&tag=a,b", "[1 2 3]", "[a b]", "[]"},
+		{"成功：空文字の場合は何もセットされない", "/?ids=&tag=", "[]", "[]", "[]"},
+		{"成功：commaを指定しない場合は分割しない", "/?sort=name,asc", "[]", "[]", "[name,asc]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result listRequest
+			testutil.AssertEqual(t, Bind(httptest.NewRequest(http.MethodGet, tt.target, nil), &result), nil)
+			testutil.AssertEqual(t, fmt.Sprint(result.IDs), tt.ids)
+			testutil.AssertEqual(t, fmt.Sprint(result.Tags), tt.tags)
+			testutil.AssertEqual(t, fmt.Sprint(result.Sort), tt.sort)
+		})
+	}
+
+	t.Run("成功：パスパラメータ", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/1,2", nil)
+		req = req.WithContext(context.WithValue(req.Context(), pathParamContextKey, newTestPathParamTable("ids", "1,2")))
+		var result struct {
+			IDs []uint `param:"ids,comma"`
+		}
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, fmt.Sprint(result.IDs), "[1 2]")
+	})
+
+	t.Run("失敗：要素の変換に失敗した場合はエラー", func(t *testing.T) {
+		var result listRequest
+		var formatErr *ErrRequestFieldFormat
+		testutil.AssertEqual(t, errors.As(Bind(httptest.NewRequest(http.MethodGet, "/?ids=1,x", nil), &result), &formatErr), true)
+		testutil.AssertEqual(t, formatErr.Field, "ids")
+	})
+
+	t.Run("失敗：スライス以外のフィールドはpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any("comma option is only available for slice field: ID"))
+		}()
+		var result struct {
+			ID int `query:"id,comma"`
+		}
+		Bind(httptest.NewRequest(http.MethodGet, "/", nil), &result)
+	})
+
+	t.Run("失敗：不明なオプションはpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(`unknown bind tag option "space": IDs`))
+		}()
+		var result struct {
+			IDs []int `query:"ids,space"`
+		}
+		Bind(httptest.NewRequest(http.MethodGet, "/", nil), &result)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindHeader$ ./server
+func TestBindHeader(t *testing.T) {
+	type headerRequest struct {
+		RequestID uuid.UUID `header:"x-request-id"`
+		Token     string    `header:"Authorization"`
+		Locale    *string   `header:"Accept-Language"`
+		Retry     int       `header:"X-Retry-Count"`
+		Tags      []string  `header:"X-Tag"`
+		Flags     []string  `header:"X-Flags,comma"`
+		Page      int       `query:"page"`
+	}
+
+	t.Run("成功：リクエストヘッダーをbindする", func(t *testing.T) {
+		id := uuid.New()
+		req := httptest.NewRequest(http.MethodGet, "/?page=2", nil)
+		// タグのヘッダー名の大文字・小文字は区別しない
+		req.Header.Set("X-Request-Id", id.String())
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-Retry-Count", "3")
+		req.Header.Add("X-Tag", "a")
+		req.Header.Add("X-Tag", "b")
+		req.Header.Set("X-Flags", "x,y")
+
+		var result headerRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, result.RequestID, id)
+		testutil.AssertEqual(t, result.Token, "Bearer token")
+		// ヘッダーが無い場合は何もセットされない
+		testutil.AssertEqual(t, result.Locale, (*string)(nil))
+		testutil.AssertEqual(t, result.Retry, 3)
+		testutil.AssertEqual(t, fmt.Sprint(result.Tags), "[a b]")
+		testutil.AssertEqual(t, fmt.Sprint(result.Flags), "[x y]")
+		testutil.AssertEqual(t, result.Page, 2)
+	})
+
+	t.Run("失敗：値の形式が不正", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Retry-Count", "many")
+		var result headerRequest
+		var formatErr *ErrRequestFieldFormat
+		testutil.AssertEqual(t, errors.As(Bind(req, &result), &formatErr), true)
+		testutil.AssertEqual(t, formatErr.Field, "X-Retry-Count")
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindStats$ ./server
+func TestBindStats(t *testing.T) {
+	before := GetBindStats()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"field":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	var result struct {
+		Field string `json:"field"`
+	}
+	if err := Bind(req, &result); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"field":`))
+	req.Header.Set("Content-Type", "application/json")
+	if err := Bind(req, &result); err == nil {
+		t.Fatal("should be error")
+	}
+
+	after := GetBindStats()
+	testutil.AssertEqual(t, after.Binds-before.Binds, uint64(2))
+	testutil.AssertEqual(t, after.Errors-before.Errors, uint64(1))
+	testutil.AssertEqual(t, after.BytesBuffered-before.BytesBuffered, uint64(len(`{"field":"test"}`)+len(`{"field":`)))
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindFieldCache$ ./server
+func TestBindFieldCache(t *testing.T) {
+	type cacheTestRequest struct {
+		Field1 string `json:"field1"`
+		Field2 int    `query:"field2"`
+		Field3 string `form:"field3"`
+		Field4 string `param:"field4"`
+	}
+	before := GetBindStats()
+
+	fields := getBindFields(reflect.TypeOf(cacheTestRequest{}))
+	testutil.AssertEqual(t, len(fields), 3)
+	testutil.AssertEqual(t, fields[0], bindField{index: 1, name: "field2", source: bindSourceQuery})
+	testutil.AssertEqual(t, fields[1], bindField{index: 2, name: "field3", source: bindSourceForm})
+	testutil.AssertEqual(t, fields[2], bindField{index: 3, name: "field4", source: bindSourceParam})
+
+	getBindFields(reflect.TypeOf(cacheTestRequest{}))
+	after := GetBindStats()
+	testutil.AssertEqual(t, after.CacheMisses-before.CacheMisses, uint64(1))
+	testutil.AssertEqual(t, after.CacheHits-before.CacheHits, uint64(1))
+}
+
+// go test -count=1 -run ^$ -bench ^BenchmarkBind$ -benchmem ./server
+func BenchmarkBind(b *testing.B) {
+	type benchRequest struct {
+		Field1 string `json:"field1"`
+		Field2 int    `query:"field2"`
+		Field3 string `query:"field3"`
+	}
+	body := `{"field1":"test"}`
+
+	b.ReportAllocs()
+	for range b.N {
+		req := httptest.NewRequest(http.MethodPost, "/?field2=1&field3=test", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		var result benchRequest
+		if err := Bind(req, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindIncompleteBody$ ./server
+func TestBindIncompleteBody(t *testing.T) {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	t.Run("失敗：Content-Lengthより短い場合はErrRequestBodyIncomplete", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":`))
+		r.Header.Set("Content-Type", ContentTypeJSON)
+		r.ContentLength = 20
+		err := Bind(r, &request{})
+		var incomplete *ErrRequestBodyIncomplete
+		testutil.AssertEqual(t, errors.As(err, &incomplete), true)
+		testutil.AssertEqual(t, err.Error(), "bind error:request body incomplete: received 8 of 20 bytes")
+	})
+
+	t.Run("失敗：読み込みが途中で終了した場合はErrRequestBodyIncomplete", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader(`{"name":`), iotest.ErrReader(io.ErrUnexpectedEOF)))
+		r.Header.Set("Content-Type", ContentTypeJSON)
+		err := Bind(r, &request{})
+		var incomplete *ErrRequestBodyIncomplete
+		testutil.AssertEqual(t, errors.As(err, &incomplete), true)
+		testutil.AssertEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+	})
+
+	t.Run("失敗：サイズの制限を超えた場合は*http.MaxBytesError", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"abcdefghij"}`))
+		r.Header.Set("Content-Type", ContentTypeJSON)
+		r.ContentLength = -1
+		r.Body = http.MaxBytesReader(w, r.Body, 5)
+		err := Bind(r, &request{})
+		var maxBytes *http.MaxBytesError
+		testutil.AssertEqual(t, errors.As(err, &maxBytes), true)
+	})
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerMiddlewareの設定
+type CircuitBreakerConfig struct {
+	// 連続した失敗(5xxのレスポンス、panic)がこの回数に達した場合にopenにする。0の場合は5
+	FailureThreshold int
+	// openを継続する時間。経過後はhalf-openとなり、1件のリクエストのみ試行する。0の場合は30秒
+	OpenTimeout time.Duration
+}
+
+// サーキットブレーカーの状態
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// ルートごとのサーキットブレーカー
+type breaker struct {
+	state breakerState
+	// 連続した失敗の回数
+	failures int
+	openedAt time.Time
+	// half-openで試行中のリクエストがあるか
+	probing bool
+}
+
+type circuitBreaker struct {
+	conf     CircuitBreakerConfig
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// ルートごとのサーキットブレーカーのミドルウェア
+// ルートの連続した失敗(5xxのレスポンス、panic)がFailureThresholdに達した場合にopenにし、
+// OpenTimeoutの間はハンドラを実行せずに503とRetry-Afterヘッダーを返す。
+// OpenTimeoutの経過後はhalf-openとなり、1件のリクエストのみ試行して、成功した場合はclosedに戻し、失敗した場合は再度openにする。
+// 障害の発生しているルートへのリクエストを早期に拒否し、依存先の回復を妨げないようにする。
+// 状態はGetLimiterStatsのcircuit_breakerで確認できる。
+// ルートごとに状態を持つため、SetCommonAfterMiddlewareまたはルートのミドルウェアとして設定する。
+// FailureThreshold、OpenTimeoutが負の場合はpanicとなる。
+//
+//	server.SetCommonAfterMiddleware(server.CircuitBreakerMiddleware(server.CircuitBreakerConfig{FailureThreshold: 10}))
+func CircuitBreakerMiddleware(conf CircuitBreakerConfig) Middleware {
+	if conf.FailureThreshold < 0 || conf.OpenTimeout < 0 {
+		panic(fmt.Sprintf("circuit breaker config must not be negative, got threshold=%d timeout=%s", conf.FailureThreshold, conf.OpenTimeout))
+	}
+	if conf.FailureThreshold == 0 {
+		conf.FailureThreshold = 5
+	}
+	if conf.OpenTimeout == 0 {
+		conf.OpenTimeout = 30 * time.Second
+	}
+	cb := &circuitBreaker{conf: conf, breakers: map[string]*breaker{}}
+	stat := registerLimiter("circuit_breaker", func() map[string]int64 { return cb.state(time.Now()) })
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ru := requestRoute(r)
+			if ru == nil {
+				// ルートの無いリクエストは対象外
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Route.NoObservabilityのルートも区別するため、routeLabelではなくパターンで区別する。
+			label := r.Method + " " + ru.pattern
+			ok, retryAfter := cb.allow(label, time.Now())
+			if !ok {
+				stat.reject(r)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				SetResponseAsJson(w, r, http.StatusServiceUnavailable, map[string]string{"message": "circuit open"})
+				return
+			}
+			rec := newResponseRecorder(w)
+			completed := false
+			defer func() {
+				// panicの場合は失敗とする。(recoverはrecoverHandlerで行う)
+				cb.done(label, completed && rec.statusCode() < 500, time.Now())
+			}()
+			next.ServeHTTP(rec, r)
+			completed = true
+		})
+	}
+}
+
+// リクエストを処理できるかどうかを返す。
+// 処理できない場合は、次に試行できるまでの時間を返す。
+func (cb *circuitBreaker) allow(label string, now time.Time) (bool, time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	b, ok := cb.breakers[label]
+	if !ok {
+		b = &breaker{}
+		cb.breakers[label] = b
+	}
+	switch b.state {
+	case breakerOpen:
+		if elapsed := now.Sub(b.openedAt); elapsed < cb.conf.OpenTimeout {
+			return false, cb.conf.OpenTimeout - elapsed
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true, 0
+	case breakerHalfOpen:
+		if b.probing {
+			return false, time.Second
+		}
+		b.probing = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// リクエストの結果を記録する。
+func (cb *circuitBreaker) done(label string, success bool, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	b := cb.breakers[label]
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if success {
+			b.state = breakerClosed
+			b.failures = 0
+		} else {
+			b.state = breakerOpen
+			b.openedAt = now
+		}
+		return
+	}
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= cb.conf.FailureThreshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// 状態ごとのルートの数を返す。
+// OpenTimeoutが経過したopenのルートは、次のリクエストで試行するためhalf_openとして数える。
+func (cb *circuitBreaker) state(now time.Time) map[string]int64 {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	counts := map[string]int64{"closed": 0, "open": 0, "half_open": 0}
+	for _, b := range cb.breakers {
+		switch {
+		case b.state == breakerHalfOpen, b.state == breakerOpen && now.Sub(b.openedAt) >= cb.conf.OpenTimeout:
+			counts["half_open"]++
+		case b.state == breakerOpen:
+			counts["open"]++
+		default:
+			counts["closed"]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestCircuitBreakerMiddleware$ ./server
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonAfterMiddleware(CircuitBreakerMiddleware(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 100 * time.Millisecond}))
+	fail := true
+	Get("/payments", func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			SetResponseAsJson(w, r, http.StatusBadGateway, nil)
+			return
+		}
+		SetResponseAsJson(w, r, http.StatusOK, nil)
+	})
+	Get("/items", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	stats := func() LimiterStats {
+		return GetLimiterStats()[0]
+	}
+
+	t.Run("失敗：連続した失敗が閾値に達した場合はopenとなり503を返す", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusBadGateway)
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusBadGateway)
+		w := serve("/payments")
+		testutil.AssertEqual(t, w.Code, http.StatusServiceUnavailable)
+		testutil.AssertEqual(t, w.Header().Get("Retry-After"), "1")
+		// 他のルートには影響しない。
+		testutil.AssertEqual(t, serve("/items").Code, http.StatusOK)
+		testutil.AssertEqual(t, stats().State["open"], int64(1))
+		testutil.AssertEqual(t, stats().State["closed"], int64(1))
+		testutil.AssertEqual(t, stats().Rejected["GET /payments"], uint64(1))
+	})
+
+	t.Run("失敗：half-openの試行が失敗した場合は再度openとなる", func(t *testing.T) {
+		time.Sleep(150 * time.Millisecond)
+		testutil.AssertEqual(t, stats().State["half_open"], int64(1))
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusBadGateway)
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusServiceUnavailable)
+	})
+
+	t.Run("成功：half-openの試行が成功した場合はclosedに戻る", func(t *testing.T) {
+		time.Sleep(150 * time.Millisecond)
+		fail = false
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusOK)
+		testutil.AssertEqual(t, serve("/payments").Code, http.StatusOK)
+		testutil.AssertEqual(t, stats().State["closed"], int64(2))
+	})
+
+	t.Run("成功：Route.NoObservabilityのルートもルートごとに区別する", func(t *testing.T) {
+		Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			SetResponseAsJson(w, r, http.StatusServiceUnavailable, nil)
+		}).NoObservability()
+		Get("/metrics", func(w http.ResponseWriter, r *http.Request) {}).NoObservability()
+		serve("/healthz")
+		serve("/healthz")
+		testutil.AssertEqual(t, serve("/healthz").Code, http.StatusServiceUnavailable)
+		testutil.AssertEqual(t, serve("/healthz").Header().Get("Retry-After") != "", true)
+		testutil.AssertEqual(t, serve("/metrics").Code, http.StatusOK)
+	})
+
+	t.Run("成功：half-openの試行中は他のリクエストを拒否する", func(t *testing.T) {
+		cb := &circuitBreaker{conf: CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second}, breakers: map[string]*breaker{}}
+		now := time.Now()
+		cb.allow("r", now)
+		cb.done("r", false, now)
+		ok, _ := cb.allow("r", now.Add(time.Second))
+		testutil.AssertEqual(t, ok, true)
+		ok, _ = cb.allow("r", now.Add(time.Second))
+		testutil.AssertEqual(t, ok, false)
+	})
+
+	t.Run("失敗：設定が負の場合はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		CircuitBreakerMiddleware(CircuitBreakerConfig{FailureThreshold: -1})
+	})
+}
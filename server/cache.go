@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCacheMiddlewareの設定
+type ResponseCacheConfig struct {
+	// レスポンスを新しいものとして返す期間
+	TTL time.Duration
+	// TTLの経過後に、古いレスポンスを返しつつバックグラウンドで更新する期間
+	// 0の場合は、TTLの経過後は通常のキャッシュミスとなる。
+	StaleTTL time.Duration
+	// TTLに加えるばらつきの割合(0〜1)。0の場合は0.1
+	// 同時に保存されたキャッシュが一斉に期限切れになることを防ぐ。
+	Jitter float64
+	// 保存するレスポンスの最大数。0の場合は1000
+	MaxEntries int
+	// キャッシュのキー。nilの場合はメソッドとリクエストURI(パスとクエリー)
+	// nilの場合、AuthorizationまたはCookieを持つリクエストはキャッシュを利用しない。
+	// 指定する場合は、利用者ごとに異なるレスポンスを共有しないようにキーに利用者を含める。
+	Key func(r *http.Request) string
+}
+
+// キャッシュしたレスポンス
+type cacheEntry struct {
+	status     int
+	header     http.Header
+	body       []byte
+	storedAt   time.Time
+	freshUntil time.Time
+	staleUntil time.Time
+	// Varyのヘッダーを含めないキー
+	baseKey string
+	// バックグラウンドで更新中か
+	refreshing bool
+}
+
+// 同じキーのキャッシュミスを1回のハンドラの実行にまとめるための情報
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+	// entryを保存したキー
+	key string
+}
+
+type responseCache struct {
+	conf ResponseCacheConfig
+	// 認証情報を持つリクエストはキャッシュを利用しないか
+	skipCredentials bool
+	mu              sync.Mutex
+	entries         map[string]*cacheEntry
+	calls           map[string]*cacheCall
+	// Varyのヘッダーを含めないキーごとの、レスポンスのVaryのヘッダー
+	varies map[string][]string
+}
+
+// GET、HEADのレスポンスをメモリにキャッシュするミドルウェア
+// ・TTLの期間内はハンドラを実行せずにキャッシュしたレスポンスを返す。
+// ・TTLの経過後、StaleTTLの期間内は古いレスポンスを返しつつ、バックグラウンドで1回だけハンドラを実行して更新する。(stale-while-revalidate)
+// ・キャッシュが無い場合に同時に来た同じキーのリクエストは、1回のハンドラの実行結果を共有する。
+// これにより、キャッシュの期限切れのタイミングでハンドラへリクエストが集中することを防ぐ。
+//
+// キャッシュするのはステータスが200で、Set-Cookieが無く、
+// Cache-Controlにno-store、no-cache、privateが含まれず、Varyが"*"でないレスポンスのみ。
+// Varyを持つレスポンスは、Varyのヘッダーの値ごとに別のレスポンスとしてキャッシュする。
+// レスポンスにはX-Cache(HIT、STALE、MISS)とAgeヘッダーを付与する。
+//
+// バックグラウンドの更新ではルーティングから再度実行するため、SetCommonMiddlewareで設定する。
+// TTLが0以下の場合はpanicとなる。
+func ResponseCacheMiddleware(conf ResponseCacheConfig) Middleware {
+	if conf.TTL <= 0 {
+		panic(fmt.Sprintf("cache TTL must be positive, got %s", conf.TTL))
+	}
+	if conf.Jitter == 0 {
+		conf.Jitter = 0.1
+	}
+	if conf.MaxEntries == 0 {
+		conf.MaxEntries = 1000
+	}
+	skipCredentials := conf.Key == nil
+	if conf.Key == nil {
+		conf.Key = func(r *http.Request) string { return r.Method + " " + r.URL.RequestURI() }
+	}
+	// ミドルウェアのチェーンはリクエストごとに構築されるため、キャッシュはここで1つだけ生成する。
+	c := &responseCache{
+		conf:            conf,
+		skipCredentials: skipCredentials,
+		entries:         map[string]*cacheEntry{},
+		calls:           map[string]*cacheCall{},
+		varies:          map[string][]string{},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.serveHTTP(next, w, r)
+		})
+	}
+}
+
+func (c *responseCache) serveHTTP(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if c.skipCredentials && (r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "") {
+		next.ServeHTTP(w, r)
+		return
+	}
+	baseKey := c.conf.Key(r)
+	now := time.Now()
+
+	c.mu.Lock()
+	key := c.variantKey(baseKey, r)
+	if e := c.entries[key]; e != nil {
+		if now.Before(e.freshUntil) {
+			c.mu.Unlock()
+			writeCacheEntry(w, e, "HIT", now)
+			return
+		}
+		if now.Before(e.staleUntil) {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(next, e, r.Clone(context.WithoutCancel(r.Context())))
+			}
+			c.mu.Unlock()
+			writeCacheEntry(w, e, "STALE", now)
+			return
+		}
+	}
+	if call := c.calls[key]; call != nil {
+		// 同じキーのハンドラの実行を待つ。
+		c.mu.Unlock()
+		<-call.done
+		c.mu.Lock()
+		// Varyのヘッダーの値が異なる場合は、共有せずにハンドラを実行する。
+		shared := call.entry != nil && call.key == c.variantKey(baseKey, r)
+		c.mu.Unlock()
+		if shared {
+			writeCacheEntry(w, call.entry, "HIT", time.Now())
+			return
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	w.Header().Set("X-Cache", "MISS")
+	cw := &cacheCaptureWriter{ResponseWriter: w}
+	next.ServeHTTP(cw, r)
+	call.entry, call.key = c.store(baseKey, r, cw)
+}
+
+// Varyのヘッダーを含めないキーに、レスポンスのVaryのヘッダーのリクエストの値を加えたキーを返す。
+// c.muをロックして呼び出す。
+func (c *responseCache) variantKey(baseKey string, r *http.Request) string {
+	names := c.varies[baseKey]
+	if len(names) == 0 {
+		return baseKey
+	}
+	var b strings.Builder
+	b.WriteString(baseKey)
+	for _, name := range names {
+		b.WriteString("\x00")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// バックグラウンドでハンドラを実行してキャッシュを更新する。
+func (c *responseCache) refresh(next http.Handler, e *cacheEntry, r *http.Request) {
+	defer func() {
+		if rv := recover(); rv != nil {
+			l.Error(r.Context(), fmt.Sprintf("panic in cache refresh %s: %v", e.baseKey, rv))
+		}
+		// 更新できなかった場合は、次のリクエストで再度更新する。
+		c.mu.Lock()
+		e.refreshing = false
+		c.mu.Unlock()
+	}()
+	cw := &cacheCaptureWriter{ResponseWriter: newDiscardResponseWriter()}
+	next.ServeHTTP(cw, r)
+	c.store(e.baseKey, r, cw)
+}
+
+// キャッシュできるレスポンスの場合は保存して、保存したキーとともに返す。
+func (c *responseCache) store(baseKey string, r *http.Request, cw *cacheCaptureWriter) (*cacheEntry, string) {
+	if !cw.cacheable() {
+		return nil, ""
+	}
+	var names []string
+	for _, v := range cw.header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	now := time.Now()
+	// TTLに±Jitterの割合のばらつきを加える。
+	ttl := time.Duration(float64(c.conf.TTL) * (1 + c.conf.Jitter*(2*rand.Float64()-1)))
+	e := &cacheEntry{
+		status:     cw.status,
+		header:     cw.header,
+		body:       cw.body.Bytes(),
+		storedAt:   now,
+		freshUntil: now.Add(ttl),
+		staleUntil: now.Add(ttl + c.conf.StaleTTL),
+		baseKey:    baseKey,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(names) > 0 {
+		c.varies[baseKey] = names
+	} else {
+		delete(c.varies, baseKey)
+	}
+	key := c.variantKey(baseKey, r)
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.conf.MaxEntries {
+		for k, old := range c.entries {
+			if now.After(old.staleUntil) {
+				delete(c.entries, k)
+				delete(c.varies, old.baseKey)
+			}
+		}
+		if len(c.entries) >= c.conf.MaxEntries {
+			return e, key
+		}
+	}
+	c.entries[key] = e
+	return e, key
+}
+
+func writeCacheEntry(w http.ResponseWriter, e *cacheEntry, state string, now time.Time) {
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = v
+	}
+	h.Set("X-Cache", state)
+	h.Set("Age", strconv.Itoa(int(now.Sub(e.storedAt).Seconds())))
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// クライアントへ書き込みながら、レスポンスを保存するhttp.ResponseWriter
+type cacheCaptureWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *cacheCaptureWriter) WriteHeader(statusCode int) {
+	if w.status == 0 && statusCode >= 200 {
+		w.status = statusCode
+		w.header = w.Header().Clone()
+		w.header.Del("X-Cache")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cacheCaptureWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheCaptureWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *cacheCaptureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *cacheCaptureWriter) cacheable() bool {
+	if w.status != http.StatusOK || w.header.Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, v := range w.header.Values("Vary") {
+		if strings.Contains(v, "*") {
+			return false
+		}
+	}
+	cc := strings.ToLower(w.header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "no-cache") && !strings.Contains(cc, "private")
+}
+
+// 書き込みを破棄するhttp.ResponseWriter
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func newDiscardResponseWriter() *discardResponseWriter {
+	return &discardResponseWriter{header: http.Header{}}
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(statusCode int)  {}
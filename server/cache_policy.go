@@ -0,0 +1,167 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Controlヘッダーの設定
+// メソッドは設定を追加したコピーを返すため、ゼロ値からつなげて組み立てる。
+//
+//	p := server.CachePolicy{}.Public().MaxAge(time.Minute).StaleWhileRevalidate(10 * time.Minute)
+//	server.Get("/articles", h).Cache(p)
+type CachePolicy struct {
+	public, private, noCache, noStore, mustRevalidate, immutable bool
+	maxAge, sMaxAge, staleWhileRevalidate, staleIfError          *time.Duration
+}
+
+// 共有キャッシュ(CDN、プロキシ)への保存を許可する。
+func (p CachePolicy) Public() CachePolicy {
+	p.public, p.private = true, false
+	return p
+}
+
+// ブラウザなどの個人のキャッシュのみに保存を許可する。
+func (p CachePolicy) Private() CachePolicy {
+	p.private, p.public = true, false
+	return p
+}
+
+// 保存は許可するが、利用する前に必ずオリジンへ確認させる。
+func (p CachePolicy) NoCache() CachePolicy {
+	p.noCache = true
+	return p
+}
+
+// キャッシュへの保存を禁止する。
+func (p CachePolicy) NoStore() CachePolicy {
+	p.noStore = true
+	return p
+}
+
+// 有効期限切れの後は、オリジンへの確認無しで利用させない。
+func (p CachePolicy) MustRevalidate() CachePolicy {
+	p.mustRevalidate = true
+	return p
+}
+
+// 有効期間内はコンテンツが変わらないことを示す。
+func (p CachePolicy) Immutable() CachePolicy {
+	p.immutable = true
+	return p
+}
+
+// キャッシュの有効期間
+func (p CachePolicy) MaxAge(d time.Duration) CachePolicy {
+	p.maxAge = &d
+	return p
+}
+
+// 共有キャッシュでの有効期間
+func (p CachePolicy) SMaxAge(d time.Duration) CachePolicy {
+	p.sMaxAge = &d
+	return p
+}
+
+// 有効期限切れの後、バックグラウンドで再検証する間に古いレスポンスを返してよい期間
+func (p CachePolicy) StaleWhileRevalidate(d time.Duration) CachePolicy {
+	p.staleWhileRevalidate = &d
+	return p
+}
+
+// オリジンがエラーの場合に古いレスポンスを返してよい期間
+func (p CachePolicy) StaleIfError(d time.Duration) CachePolicy {
+	p.staleIfError = &d
+	return p
+}
+
+// Cache-Controlヘッダーの値を返す。(例: "public, max-age=60, stale-while-revalidate=600")
+func (p CachePolicy) String() string {
+	var directives []string
+	for _, d := range []struct {
+		on   bool
+		name string
+	}{
+		{p.public, "public"},
+		{p.private, "private"},
+		{p.noCache, "no-cache"},
+		{p.noStore, "no-store"},
+		{p.mustRevalidate, "must-revalidate"},
+		{p.immutable, "immutable"},
+	} {
+		if d.on {
+			directives = append(directives, d.name)
+		}
+	}
+	for _, d := range []struct {
+		val  *time.Duration
+		name string
+	}{
+		{p.maxAge, "max-age"},
+		{p.sMaxAge, "s-maxage"},
+		{p.staleWhileRevalidate, "stale-while-revalidate"},
+		{p.staleIfError, "stale-if-error"},
+	} {
+		if d.val != nil {
+			directives = append(directives, d.name+"="+strconv.FormatInt(int64(d.val.Seconds()), 10))
+		}
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Cache-Controlヘッダーを付与するミドルウェアを返す。
+// 成功(2xx)とリダイレクト(3xx)のレスポンスのみが対象で、
+// ハンドラが独自にCache-Controlを設定した場合はそちらが優先される。
+func (p CachePolicy) Middleware() Middleware {
+	value := p.String()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+// ルートのレスポンスにCache-Controlヘッダーを付与する。
+// ルートのミドルウェアの最後に、CachePolicy.Middlewareを追加する。
+func (rt *Route) Cache(p CachePolicy) *Route {
+	rt.ru.middleware = append(rt.ru.middleware, p.Middleware())
+	return rt
+}
+
+// レスポンスの書き込み開始時にCache-Controlヘッダーを付与するhttp.ResponseWriter
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader && statusCode >= 200 {
+		w.wroteHeader = true
+		if statusCode < 400 && w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestCachePolicy$ ./server
+func TestCachePolicy(t *testing.T) {
+	t.Run("成功：ヘッダーの値", func(t *testing.T) {
+		testutil.AssertEqual(t, CachePolicy{}.Public().MaxAge(time.Minute).StaleWhileRevalidate(10*time.Minute).String(), "public, max-age=60, stale-while-revalidate=600")
+		testutil.AssertEqual(t, CachePolicy{}.Public().Private().MaxAge(0).String(), "private, max-age=0")
+		testutil.AssertEqual(t, CachePolicy{}.NoStore().String(), "no-store")
+		testutil.AssertEqual(t, CachePolicy{}.Public().Immutable().MaxAge(365*24*time.Hour).SMaxAge(time.Hour).StaleIfError(time.Hour).String(), "public, immutable, max-age=31536000, s-maxage=3600, stale-if-error=3600")
+	})
+
+	t.Run("成功：ルートのレスポンスに付与される", func(t *testing.T) {
+		resetSetting()
+		p := CachePolicy{}.Public().MaxAge(time.Minute)
+		Get("/articles", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, nil)
+		}).Cache(p)
+		Get("/custom", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+		}).Cache(p)
+		Get("/error", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusInternalServerError, nil)
+		}).Cache(p)
+
+		for path, expect := range map[string]string{
+			"/articles": "public, max-age=60",
+			"/custom":   "no-store",
+			"/error":    "",
+		} {
+			res := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+			testutil.AssertEqual(t, res.Header().Get("Cache-Control"), expect)
+		}
+	})
+}
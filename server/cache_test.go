@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestResponseCacheMiddleware$ ./server
+func TestResponseCacheMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonMiddleware(ResponseCacheMiddleware(ResponseCacheConfig{TTL: 100 * time.Millisecond, StaleTTL: time.Second, Jitter: 0.0001}))
+	var calls atomic.Int64
+	release := make(chan struct{})
+	Get("/count/:name", func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if getPathParamVal(r, "name") == "slow" {
+			<-release
+		}
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(strconv.FormatInt(n, 10)))
+	})
+	Get("/private", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "private")
+	})
+
+	Get("/vary", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		AddVary(w, "Accept-Encoding")
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("encoding:"+r.Header.Get("Accept-Encoding")))
+	})
+	Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Header.Get("Authorization")))
+	})
+
+	serve := func(path string, header ...string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("成功：TTLの期間内はキャッシュを返し、期限切れの後は古いレスポンスを返しつつ更新する", func(t *testing.T) {
+		calls.Store(0)
+		res := serve("/count/a")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "MISS")
+		testutil.AssertEqual(t, res.Body.String(), "1")
+		res = serve("/count/a")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "HIT")
+		testutil.AssertEqual(t, res.Body.String(), "1")
+
+		time.Sleep(150 * time.Millisecond)
+		res = serve("/count/a")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "STALE")
+		testutil.AssertEqual(t, res.Body.String(), "1")
+
+		// バックグラウンドで更新される。
+		deadline := time.Now().Add(time.Second)
+		for serve("/count/a").Body.String() != "2" {
+			if time.Now().After(deadline) {
+				t.Fatal("cache should be refreshed in background")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		testutil.AssertEqual(t, calls.Load(), int64(2))
+	})
+
+	t.Run("成功：同時のキャッシュミスは1回のハンドラの実行にまとめられる", func(t *testing.T) {
+		calls.Store(0)
+		var wg sync.WaitGroup
+		bodies := make([]string, 5)
+		for i := range bodies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bodies[i] = serve("/count/slow").Body.String()
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		testutil.AssertEqual(t, calls.Load(), int64(1))
+		for _, b := range bodies {
+			testutil.AssertEqual(t, b, "1")
+		}
+	})
+
+	t.Run("成功：キャッシュできないレスポンスは毎回実行される", func(t *testing.T) {
+		calls.Store(0)
+		serve("/private")
+		serve("/private")
+		testutil.AssertEqual(t, calls.Load(), int64(2))
+	})
+	t.Run("成功：Varyを持つレスポンスはVaryのヘッダーの値ごとにキャッシュされる", func(t *testing.T) {
+		calls.Store(0)
+		res := serve("/vary", "Accept-Encoding", "gzip")
+		testutil.AssertEqual(t, res.Body.String(), "encoding:gzip")
+		res = serve("/vary")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "MISS")
+		testutil.AssertEqual(t, res.Body.String(), "encoding:")
+		res = serve("/vary", "Accept-Encoding", "gzip")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "HIT")
+		testutil.AssertEqual(t, res.Body.String(), "encoding:gzip")
+		res = serve("/vary")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "HIT")
+		testutil.AssertEqual(t, res.Body.String(), "encoding:")
+		testutil.AssertEqual(t, calls.Load(), int64(2))
+	})
+
+	t.Run("成功：認証情報を持つリクエストはキャッシュを利用しない", func(t *testing.T) {
+		calls.Store(0)
+		testutil.AssertEqual(t, serve("/me", "Authorization", "Bearer a").Body.String(), "Bearer a")
+		res := serve("/me", "Authorization", "Bearer b")
+		testutil.AssertEqual(t, res.Header().Get("X-Cache"), "")
+		testutil.AssertEqual(t, res.Body.String(), "Bearer b")
+		testutil.AssertEqual(t, serve("/me", "Cookie", "session=a").Body.String(), "")
+		testutil.AssertEqual(t, calls.Load(), int64(3))
+	})
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ルートのレスポンスをCompressMiddlewareで圧縮しないようにする。
+// 独自の形式でストリーミングを行うルートや、既に圧縮されたデータを返すルートに設定する。
+func (rt *Route) NoCompress() *Route {
+	rt.ru.noCompress = true
+	return rt
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// レスポンスをgzipで圧縮するミドルウェア
+// リクエストのAccept-Encodingがgzipを受け付ける場合のみ圧縮する。
+// 以下の場合は圧縮せずにそのまま返す。
+// ・Route.NoCompressが設定されたルート
+// ・Server-Sent Events(Content-Typeがtext/event-stream)
+// ・WebSocketなどのプロトコルのアップグレード
+// ・ハンドラが既にContent-Encodingを設定している場合、ボディの無いステータス(204、304など)
+// ハンドラがFlushした場合は、圧縮済みのデータをその時点でクライアントへ送信する。(バッファリングしない)
+// Route.NoCompressを参照するため、SetCommonAfterMiddlewareまたはルートのミドルウェアとして設定する。
+func CompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) || isUpgradeRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressResponseWriter{ResponseWriter: w, r: r}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Accept-Encodingがgzipを受け付けるか
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+			if name != "gzip" && name != "*" {
+				continue
+			}
+			// q=0は受け付けないことを示す。
+			q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !found {
+				return true
+			}
+			if f, err := strconv.ParseFloat(q, 64); err == nil && f > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isUpgradeRequest(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" || strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// 圧縮の要否をレスポンスの書き込み開始時に判断するhttp.ResponseWriter
+type compressResponseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *compressResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	// 1xxのレスポンスは最終的なレスポンスではないため判断しない。
+	if statusCode >= 100 && statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.wroteHeader = true
+	if w.shouldCompress(statusCode) {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		AddVary(w, "Accept-Encoding")
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *compressResponseWriter) shouldCompress(statusCode int) bool {
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		return false
+	}
+	if ru := matchedRoute(w.r); ru != nil && ru.noCompress {
+		return false
+	}
+	return true
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		// net/httpと同様にContent-Typeを判定してから書き込む。
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
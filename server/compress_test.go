@@ -0,0 +1,95 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestCompressMiddleware$ ./server
+func TestCompressMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonAfterMiddleware(CompressMiddleware)
+	body := strings.Repeat("hello ", 100)
+	Get("/text", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(body))
+	})
+	Get("/raw", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(body))
+	}).NoCompress()
+	Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		http.NewResponseController(w).Flush()
+	})
+	Get("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+		http.NewResponseController(w).Flush()
+	})
+
+	serve := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+	gunzip := func(t *testing.T, res *httptest.ResponseRecorder) string {
+		t.Helper()
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	t.Run("成功：gzipを受け付ける場合は圧縮する", func(t *testing.T) {
+		res := serve("/text", "deflate, gzip")
+		testutil.AssertEqual(t, res.Header().Get("Content-Encoding"), "gzip")
+		testutil.AssertEqual(t, res.Header().Get("Vary"), "Accept-Encoding")
+		testutil.AssertEqual(t, gunzip(t, res), body)
+	})
+
+	t.Run("成功：gzipを受け付けない場合は圧縮しない", func(t *testing.T) {
+		for _, ae := range []string{"", "br", "gzip;q=0"} {
+			res := serve("/text", ae)
+			testutil.AssertEqual(t, res.Header().Get("Content-Encoding"), "")
+			testutil.AssertEqual(t, res.Body.String(), body)
+		}
+	})
+
+	t.Run("成功：NoCompressのルート、SSE、アップグレードは圧縮しない", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/raw", "gzip").Body.String(), body)
+
+		res := serve("/events", "gzip")
+		testutil.AssertEqual(t, res.Header().Get("Content-Encoding"), "")
+		testutil.AssertEqual(t, res.Body.String(), "data: 1\n\n")
+		testutil.AssertEqual(t, res.Flushed, true)
+
+		req := httptest.NewRequest(http.MethodGet, "/text", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		res = httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		testutil.AssertEqual(t, res.Body.String(), body)
+	})
+
+	t.Run("成功：Flushした時点で圧縮済みのデータが送信される", func(t *testing.T) {
+		res := serve("/stream", "gzip")
+		testutil.AssertEqual(t, res.Flushed, true)
+		testutil.AssertEqual(t, res.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+		testutil.AssertEqual(t, gunzip(t, res), body)
+	})
+}
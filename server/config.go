@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// StartServerFromConfigで利用するサーバーの設定
+// ゼロ値の項目は未設定として扱い、net/httpのデフォルトの動作となる。
+type Config struct {
+	Host string
+	// 0の場合は空いているポートが利用される。
+	Port int
+
+	// 両方を指定した場合はTLSで起動する。
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// http.Serverの各タイムアウト
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// Graceful shutdown時のタイムアウト
+	// 0の場合はShutdownTimeoutSecondが利用される。
+	ShutdownTimeout time.Duration
+
+	// シャットダウンの信号を受け取ってからGraceful shutdownを開始するまでの待機時間
+	// 0の場合はShutdownDelayが利用される。
+	ShutdownDelay time.Duration
+
+	// リクエストヘッダーの最大サイズ
+	// 0の場合はhttp.DefaultMaxHeaderBytesが利用される。
+	MaxHeaderBytes int
+}
+
+// 相互に依存する項目を含めて設定値をチェックする。
+// 問題がある場合は、すべての問題をまとめたErrInvalidConfigを返す。
+func (conf Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if conf.Port < 0 || conf.Port > 65535 {
+		invalid("Port must be between 0 and 65535, got %d", conf.Port)
+	}
+
+	if (conf.TLSCertFile == "") != (conf.TLSKeyFile == "") {
+		invalid("TLSCertFile and TLSKeyFile must be set together")
+	}
+	// エラーの順番が実行ごとに変わらないように、マップではなくスライスで項目の順に確認する。
+	for _, f := range []struct {
+		name string
+		path string
+	}{
+		{"TLSCertFile", conf.TLSCertFile},
+		{"TLSKeyFile", conf.TLSKeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if err := checkReadableFile(f.path); err != nil {
+			invalid("%s %q is not readable: %s", f.name, f.path, err)
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"ReadHeaderTimeout", conf.ReadHeaderTimeout},
+		{"ReadTimeout", conf.ReadTimeout},
+		{"WriteTimeout", conf.WriteTimeout},
+		{"IdleTimeout", conf.IdleTimeout},
+		{"ShutdownTimeout", conf.ShutdownTimeout},
+		{"ShutdownDelay", conf.ShutdownDelay},
+	} {
+		if f.d < 0 {
+			invalid("%s must not be negative, got %s", f.name, f.d)
+		}
+	}
+	// ヘッダーの読み込みはリクエスト全体の読み込みに含まれるため、
+	// ReadTimeoutより長いReadHeaderTimeoutは意味を持たない。
+	if conf.ReadHeaderTimeout > 0 && conf.ReadTimeout > 0 && conf.ReadHeaderTimeout > conf.ReadTimeout {
+		invalid("ReadHeaderTimeout (%s) must not exceed ReadTimeout (%s)", conf.ReadHeaderTimeout, conf.ReadTimeout)
+	}
+
+	if conf.MaxHeaderBytes < 0 {
+		invalid("MaxHeaderBytes must not be negative, got %d", conf.MaxHeaderBytes)
+	}
+
+	if len(errs) > 0 {
+		return &ErrInvalidConfig{Errs: errs}
+	}
+	return nil
+}
+
+// ファイルを開いて読み込めることを確認する。
+func checkReadableFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("is a directory")
+	}
+	return nil
+}
+
+// 設定をもとにサーバーを起動する。
+// ポートをバインドする前に設定をチェックし、問題がある場合はErrInvalidConfigを返す。
+// それ以外はStartServerと同様で、シャットダウンが完了するまでブロックする。
+func StartServerFromConfig(c context.Context, conf Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", conf.Host, conf.Port),
+		Handler:           HTTPHandler(),
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		ReadTimeout:       conf.ReadTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
+	}
+	sc := serveConfig{
+		certFile:        conf.TLSCertFile,
+		keyFile:         conf.TLSKeyFile,
+		shutdownTimeout: conf.ShutdownTimeout,
+		shutdownDelay:   conf.ShutdownDelay,
+	}
+	if sc.shutdownTimeout == 0 {
+		sc.shutdownTimeout = ShutdownTimeoutSecond
+	}
+	if sc.shutdownDelay == 0 {
+		sc.shutdownDelay = ShutdownDelay
+	}
+	serve(c, srv, sc)
+	return nil
+}
+
+type ErrInvalidConfig struct {
+	Errs []error
+}
+
+func (e *ErrInvalidConfig) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid config:\n\t%s", strings.Join(msgs, "\n\t"))
+}
+
+func (e *ErrInvalidConfig) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestConfigValidate$ ./server
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(certFile, []byte("dummy"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("成功：ゼロ値", func(t *testing.T) {
+		if err := (Config{}).Validate(); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+
+	t.Run("成功：すべて指定", func(t *testing.T) {
+		err := Config{
+			Host:              "localhost",
+			Port:              8080,
+			TLSCertFile:       certFile,
+			TLSKeyFile:        certFile,
+			ReadHeaderTimeout: time.Second,
+			ReadTimeout:       5 * time.Second,
+			WriteTimeout:      5 * time.Second,
+			IdleTimeout:       time.Minute,
+			ShutdownTimeout:   10 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+		}.Validate()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+
+	t.Run("失敗：すべての問題がまとめて返る", func(t *testing.T) {
+		err := Config{
+			Port:              70000,
+			TLSCertFile:       filepath.Join(dir, "not-exist.pem"),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Second,
+			WriteTimeout:      -time.Second,
+			IdleTimeout:       -time.Second,
+			MaxHeaderBytes:    -1,
+		}.Validate()
+
+		var configErr *ErrInvalidConfig
+		if !errors.As(err, &configErr) {
+			t.Fatalf("unexpected error type: %v", err)
+		}
+		// 項目の順に返る。
+		expect := []string{"Port", "TLSCertFile and TLSKeyFile", "TLSCertFile", "WriteTimeout", "IdleTimeout", "ReadHeaderTimeout", "MaxHeaderBytes"}
+		testutil.AssertEqual(t, len(configErr.Errs), len(expect))
+		for i, name := range expect {
+			testutil.AssertEqual(t, strings.HasPrefix(configErr.Errs[i].Error(), name+" "), true)
+		}
+	})
+
+	t.Run("失敗：ディレクトリは読み込めない", func(t *testing.T) {
+		err := Config{TLSCertFile: dir, TLSKeyFile: certFile}.Validate()
+		var configErr *ErrInvalidConfig
+		if !errors.As(err, &configErr) {
+			t.Fatalf("unexpected error type: %v", err)
+		}
+		testutil.AssertEqual(t, len(configErr.Errs), 1)
+		testutil.AssertEqual(t, strings.HasSuffix(configErr.Errs[0].Error(), "is not readable: is a directory"), true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStartServerFromConfig$ ./server
+func TestStartServerFromConfig(t *testing.T) {
+	t.Run("失敗：不正な設定の場合は起動せずにエラーを返す", func(t *testing.T) {
+		err := StartServerFromConfig(context.Background(), Config{Port: -1})
+		var configErr *ErrInvalidConfig
+		if !errors.As(err, &configErr) {
+			t.Fatalf("unexpected error type: %v", err)
+		}
+	})
+
+	t.Run("成功：起動とシャットダウン", func(t *testing.T) {
+		resetSetting()
+		go StartServerFromConfig(context.Background(), Config{Host: "127.0.0.1", Port: 8088, ReadHeaderTimeout: time.Second})
+		time.Sleep(time.Millisecond * 100)
+		Shutdown()
+	})
+
+	t.Run("成功：ShutdownDelayが0の場合はグローバルのShutdownDelayを利用する", func(t *testing.T) {
+		defer func() { ShutdownDelay = 0 }()
+		ShutdownDelay = time.Millisecond * 300
+		resetSetting()
+		go StartServerFromConfig(context.Background(), Config{Host: "127.0.0.1", Port: 8088, ReadHeaderTimeout: time.Second})
+		time.Sleep(time.Millisecond * 100)
+
+		done := make(chan struct{})
+		go func() {
+			Shutdown()
+			close(done)
+		}()
+		time.Sleep(time.Millisecond * 100)
+		select {
+		case <-done:
+			t.Fatal("server should wait for the delay")
+		default:
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second * 2):
+			t.Fatal("server should shutdown after the delay")
+		}
+	})
+}
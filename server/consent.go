@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 同意が必要な規約(利用規約、プライバシーポリシーなど)
+type ConsentRequirement struct {
+	// 規約の種類(例: "tos", "privacy")
+	Document string `json:"document"`
+	// 同意が必要な最新のバージョン
+	Version string `json:"version"`
+	// 同意を行うためのURL
+	AcceptURL string `json:"acceptUrl"`
+}
+
+// Principalがまだ同意していない規約を返す。
+// すべて同意済みの場合は空のスライスを返す。
+type ConsentResolver func(r *http.Request, p *Principal) ([]ConsentRequirement, error)
+
+// 同意が必要な場合に返すレスポンス
+type consentRequiredResponse struct {
+	Message  string               `json:"message"`
+	Required []ConsentRequirement `json:"required"`
+}
+
+// 認証されたユーザーが最新の規約に同意しているかをチェックするミドルウェア
+// AuthMiddlewareなどでPrincipalがセットされた後に実行する必要がある。
+// 同意していない規約がある場合は、statusCode(403または451)で規約と同意のためのURLを返す。
+//
+//	{"message":"consent required","required":[{"document":"tos","version":"2024-04","acceptUrl":"/consent/tos"}]}
+//
+// Principalがセットされていない場合は401、resolveがエラーを返した場合は500を返す。
+// statusCodeが403、451以外の場合はpanicとなる。
+func ConsentMiddleware(statusCode int, resolve ConsentResolver) Middleware {
+	if statusCode != http.StatusForbidden && statusCode != http.StatusUnavailableForLegalReasons {
+		panic(fmt.Sprintf("consent status code must be 403 or 451, got %d", statusCode))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFrom(r)
+			if !ok {
+				SetResponseAsJson(w, r, http.StatusUnauthorized, map[string]string{"message": "unauthorized"})
+				return
+			}
+			required, err := resolve(r, p)
+			if err != nil {
+				l.Error(r.Context(), fmt.Sprintf("failed to resolve consent: %s", err))
+				SetResponse(w, r, internalServerErrorContentType, http.StatusInternalServerError, internalServerErrorResponseFor(r))
+				return
+			}
+			if len(required) > 0 {
+				SetResponseAsJson(w, r, statusCode, consentRequiredResponse{Message: "consent required", Required: required})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestConsentMiddleware$ ./server
+func TestConsentMiddleware(t *testing.T) {
+	resetSetting()
+	// user1は同意済み、errorはresolverのエラー、それ以外は未同意
+	resolve := func(r *http.Request, p *Principal) ([]ConsentRequirement, error) {
+		switch p.ID {
+		case "user1":
+			return nil, nil
+		case "error":
+			return nil, errors.New("db error")
+		}
+		return []ConsentRequirement{{Document: "tos", Version: "2024-04", AcceptURL: "/consent/tos"}}, nil
+	}
+	Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, nil))
+	}, AuthMiddleware(testAuthenticate), ConsentMiddleware(http.StatusUnavailableForLegalReasons, resolve))
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("成功：同意済みの場合は後続の処理が実行される", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("user1").Code, http.StatusOK)
+	})
+
+	t.Run("失敗：未同意の場合は同意が必要な規約を返す", func(t *testing.T) {
+		res := serve("user2")
+		testutil.AssertEqual(t, res.Code, http.StatusUnavailableForLegalReasons)
+		testutil.AssertEqual(t, res.Body.String(), `{"message":"consent required","required":[{"document":"tos","version":"2024-04","acceptUrl":"/consent/tos"}]}`)
+	})
+
+	t.Run("失敗：resolverのエラーは500", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("error").Code, http.StatusInternalServerError)
+	})
+
+	t.Run("失敗：403、451以外のステータスはpanic", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("should panic")
+			}
+		}()
+		ConsentMiddleware(http.StatusBadRequest, resolve)
+	})
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"cmp"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ルートのレスポンスの型を設定する。
+// 値またはポインタを渡す。DocsHandlerで利用される。
+func (rt *Route) Response(v any) *Route {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	rt.ru.responseType = t
+	return rt
+}
+
+// ルートの説明を設定する。DocsHandlerで利用される。
+func (rt *Route) Description(s string) *Route {
+	rt.ru.description = s
+	return rt
+}
+
+// ドキュメントに出力するルートの情報
+type docRoute struct {
+	Method      string
+	Pattern     string
+	Description string
+	Request     *docType
+	Response    *docType
+	Examples    []docExample
+}
+
+// ドキュメントに出力する型の情報
+type docType struct {
+	Name   string
+	Fields []docField
+}
+
+type docField struct {
+	Name string
+	// 値の取得元(Bindのタグのjson、param、query、form、header、file)
+	In   string
+	Type string
+}
+
+func newDocType(t reflect.Type) *docType {
+	if t == nil {
+		return nil
+	}
+	dt := &docType{Name: t.String()}
+	if t.Kind() != reflect.Struct {
+		return dt
+	}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field := docField{Name: f.Name, Type: f.Type.String()}
+		for _, key := range []string{"json", "param", "query", "form", "header", "file"} {
+			if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" {
+				field.Name, field.In = name, key
+				break
+			}
+		}
+		if field.Name == "-" {
+			continue
+		}
+		dt.Fields = append(dt.Fields, field)
+	}
+	return dt
+}
+
+// 登録されているルートの情報をパス、メソッドの順に並べて返す。
+func docRoutes() []docRoute {
+	routes := make([]docRoute, 0, len(patternIndex))
+	for key, ru := range patternIndex {
+		if ru.notFound {
+			continue
+		}
+		method, _, _ := strings.Cut(key, " ")
+		routes = append(routes, docRoute{
+			Method:      method,
+			Pattern:     ru.pattern,
+			Description: ru.description,
+			Request:     newDocType(ru.requestType),
+			Response:    newDocType(ru.responseType),
+			Examples:    newDocExamples(ru.examples),
+		})
+	}
+	slices.SortFunc(routes, func(a, b docRoute) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+section { border-top: 1px solid #ccc; padding: 0.5em 0; }
+.method { font-weight: bold; display: inline-block; min-width: 5em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ddd; padding: 0.2em 0.6em; text-align: left; }
+pre { background: #f6f8fa; padding: 0.5em; }
+</style>
+</head>
+<body>
+<h1>API</h1>
+{{range .}}<section>
+<h2><span class="method">{{.Method}}</span> <code>{{.Pattern}}</code></h2>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{with .Request}}<h3>Request <code>{{.Name}}</code></h3>{{template "fields" .}}{{end}}
+{{with .Response}}<h3>Response <code>{{.Name}}</code></h3>{{template "fields" .}}{{end}}
+{{range .Examples}}<h3>Example: {{.Name}}</h3>
+{{if .Request}}<pre>{{.Request}}</pre>{{end}}
+<p>{{.StatusCode}}</p>
+<pre>{{.Response}}</pre>
+{{end}}</section>
+{{end}}</body>
+</html>
+{{define "fields"}}{{if .Fields}}<table>
+<tr><th>name</th><th>in</th><th>type</th></tr>
+{{range .Fields}}<tr><td>{{.Name}}</td><td>{{.In}}</td><td><code>{{.Type}}</code></td></tr>
+{{end}}</table>{{end}}{{end}}`))
+
+// 登録されているルートの情報(メソッド、パス、説明、リクエスト・レスポンスの型、例)をHTMLで返すハンドラ
+// 説明と型、例は、Route.Description、Route.Request、Route.Response、Route.Exampleで設定する。
+// OpenAPIやSwagger UIを用意せずに、簡易的なAPIのドキュメントを提供する。
+//
+//	server.Get("/docs", server.DocsHandler)
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	var buf strings.Builder
+	if err := docsTemplate.Execute(&buf, docRoutes()); err != nil {
+		panic(err)
+	}
+	SetResponse(w, r, ContentTypeHTMLWithCharset, http.StatusOK, []byte(buf.String()))
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestDocsHandler$ ./server
+func TestDocsHandler(t *testing.T) {
+	resetSetting()
+	Post("/friend/:number", func(w http.ResponseWriter, r *http.Request) {}).
+		Request(bindDebugFriendRequest{}).
+		Response(&getFriendResponse{}).
+		Description("<友達>を追加する")
+	Get("/friends", func(w http.ResponseWriter, r *http.Request) {})
+	Get("/docs", DocsHandler)
+
+	routes := docRoutes()
+	testutil.AssertEqual(t, len(routes), 3)
+	testutil.AssertEqual(t, routes[0].Pattern, "/docs")
+	testutil.AssertEqual(t, routes[1].Pattern, "/friend/:number")
+	testutil.AssertEqual(t, routes[1].Request.Fields[0], docField{Name: "number", In: "param", Type: "int"})
+	testutil.AssertEqual(t, routes[1].Request.Fields[2], docField{Name: "name", In: "json", Type: "string"})
+	testutil.AssertEqual(t, routes[1].Response.Name, "server.getFriendResponse")
+
+	t.Run("成功：headerとfileのタグも取得元として出力される", func(t *testing.T) {
+		type uploadRequest struct {
+			RequestID string                  `header:"X-Request-Id"`
+			Avatar    *multipart.FileHeader   `file:"avatar"`
+			Photos    []*multipart.FileHeader `file:"photos"`
+		}
+		dt := newDocType(reflect.TypeOf(uploadRequest{}))
+		testutil.AssertEqual(t, len(dt.Fields), 3)
+		testutil.AssertEqual(t, dt.Fields[0], docField{Name: "X-Request-Id", In: "header", Type: "string"})
+		testutil.AssertEqual(t, dt.Fields[1], docField{Name: "avatar", In: "file", Type: "*multipart.FileHeader"})
+		testutil.AssertEqual(t, dt.Fields[2], docField{Name: "photos", In: "file", Type: "[]*multipart.FileHeader"})
+	})
+
+	res := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	testutil.AssertEqual(t, res.Code, http.StatusOK)
+	testutil.AssertEqual(t, res.Header().Get("Content-Type"), ContentTypeHTMLWithCharset)
+	body := res.Body.String()
+	for _, s := range []string{"<code>/friend/:number</code>", "&lt;友達&gt;を追加する", "<code>server.bindDebugFriendRequest</code>", "<code>/friends</code>"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("%q not found in docs", s)
+		}
+	}
+}
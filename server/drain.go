@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// DrainHandlerで確認用のトークンを指定するヘッダー
+const DrainTokenHeader = "X-Drain-Token"
+
+// DrainHandlerからシャットダウンを要求するチャネル
+// リクエストのgoroutineから送信するため、サーバーの起動ごとに生成せず、closeもしない。
+var drainRequests = make(chan string, 1)
+
+// リモートからシャットダウン(drain)を開始するための管理用のハンドラを返す。
+// ローリングリスタートなどで、OSのシグナル以外からシャットダウンを開始するために利用する。
+// AuthMiddlewareなどでPrincipalがセットされている必要があり、セットされていない場合は401を返す。
+// さらにDrainTokenHeaderの値がtokenと一致しない場合は403を返す。
+// 受け付けた場合は202を返し、OSのシグナルを受け取った場合と同様にシャットダウン(ShutdownDelayの待機を含む)を開始する。
+// 既にシャットダウン中の場合は409を返す。
+// tokenが空の場合はpanicとなる。
+//
+//	server.Post("/admin/drain", server.DrainHandler(os.Getenv("DRAIN_TOKEN")), server.AuthMiddleware(adminOnly))
+func DrainHandler(token string) Handler {
+	if token == "" {
+		panic("drain token must not be empty")
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFrom(r)
+		if !ok {
+			SetResponseAsJson(w, r, http.StatusUnauthorized, map[string]string{"message": "unauthorized"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(DrainTokenHeader)), []byte(token)) != 1 {
+			l.Warn(r.Context(), fmt.Sprintf("drain denied: actor=%s", p.Actor().ID))
+			SetResponseAsJson(w, r, http.StatusForbidden, map[string]string{"message": "invalid drain token"})
+			return
+		}
+		if !IsReady() {
+			SetResponseAsJson(w, r, http.StatusConflict, map[string]string{"status": "draining"})
+			return
+		}
+		// シャットダウンはこのリクエストの完了を待って行われる(graceful shutdown)。
+		select {
+		case drainRequests <- "drain requested by " + p.Actor().ID:
+		default:
+			SetResponseAsJson(w, r, http.StatusConflict, map[string]string{"status": "draining"})
+			return
+		}
+		l.Info(r.Context(), fmt.Sprintf("drain accepted: actor=%s", p.Actor().ID))
+		SetResponseAsJson(w, r, http.StatusAccepted, map[string]string{"status": "draining"})
+	}
+}
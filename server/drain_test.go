@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestDrainHandler$ ./server
+func TestDrainHandler(t *testing.T) {
+	resetSetting()
+	Post("/admin/drain", DrainHandler("secret"), AuthMiddleware(testAuthenticate))
+
+	done := make(chan struct{})
+	go func() {
+		StartServer(context.Background(), "127.0.0.1", 8095)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 100)
+
+	// 未使用の接続が残るとシャットダウンが遅れるため、接続は再利用しない。
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	request := func(auth, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:8095/admin/drain", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		if token != "" {
+			req.Header.Set(DrainTokenHeader, token)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("失敗：認証されていない場合は401", func(t *testing.T) {
+		testutil.AssertEqual(t, request("", "secret"), http.StatusUnauthorized)
+	})
+
+	t.Run("失敗：トークンが一致しない場合は403", func(t *testing.T) {
+		testutil.AssertEqual(t, request("admin", "wrong"), http.StatusForbidden)
+		testutil.AssertEqual(t, request("admin", ""), http.StatusForbidden)
+		testutil.AssertEqual(t, IsReady(), true)
+	})
+
+	t.Run("成功：シャットダウンが開始される", func(t *testing.T) {
+		testutil.AssertEqual(t, request("admin", "secret"), http.StatusAccepted)
+		select {
+		case <-done:
+		case <-time.After(time.Second * 2):
+			t.Fatal("server should shutdown")
+		}
+		testutil.AssertEqual(t, IsReady(), false)
+	})
+
+	t.Run("失敗：シャットダウン中の場合は409", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+		r.Header.Set(DrainTokenHeader, "secret")
+		DrainHandler("secret")(w, SetPrincipal(r, &Principal{ID: "admin"}))
+		testutil.AssertEqual(t, w.Code, http.StatusConflict)
+	})
+
+	t.Run("成功：停止中に受け付けた要求は次の起動に影響しない", func(t *testing.T) {
+		ready.Store(true)
+		defer ready.Store(false)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+		r.Header.Set(DrainTokenHeader, "secret")
+		DrainHandler("secret")(w, SetPrincipal(r, &Principal{ID: "admin"}))
+		testutil.AssertEqual(t, w.Code, http.StatusAccepted)
+		// 要求が残っている間は409となる。
+		w = httptest.NewRecorder()
+		DrainHandler("secret")(w, SetPrincipal(r, &Principal{ID: "admin"}))
+		testutil.AssertEqual(t, w.Code, http.StatusConflict)
+
+		done := make(chan struct{})
+		go func() {
+			StartServer(context.Background(), "127.0.0.1", 8095)
+			close(done)
+		}()
+		time.Sleep(time.Millisecond * 100)
+		select {
+		case <-done:
+			t.Fatal("server should not shutdown by the stale drain request")
+		default:
+		}
+		Shutdown()
+		<-done
+	})
+
+	t.Run("失敗：トークンが空の場合はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		DrainHandler("")
+	})
+}
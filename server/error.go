@@ -13,7 +13,13 @@ Errがnilの場合はErr.Error()が実行時エラーとなる。
 */
 
 var (
-	PanicSameRoot = "there already route %s exists"
+	PanicSameRoot        = "there already route %s exists"
+	PanicInvalidMethod   = "invalid method %q"
+	PanicInvalidWildcard = "wildcard must be the last segment with a name: %s"
+	PanicInvalidPattern  = "invalid route pattern: %s"
+	PanicShadowedRoute   = "route %s is shadowed by %s"
+	PanicAliasNotFound   = "alias target route %s is not registered"
+	PanicAliasParams     = "alias %s must have the same path parameters as %s"
 )
 
 type ErrBind struct {
@@ -78,3 +84,30 @@ func (e *ErrRequestFormParse) Error() string {
 func (e *ErrRequestFormParse) Unwrap() error {
 	return e.Err
 }
+
+// リクエストのJSONが制限(SetJsonLimits)を超えた場合のエラー
+type ErrRequestJsonLimitExceeded struct {
+	// 超えた制限の名前(MaxDepth、MaxTokens、MaxNumberLength)
+	Limit string
+	Max   int
+}
+
+func (e *ErrRequestJsonLimitExceeded) Error() string {
+	return fmt.Sprintf("json exceeds %s (%d)", e.Limit, e.Max)
+}
+
+// 受信したリクエストボディがContent-Lengthより短い場合(クライアントの中断など)のエラー
+type ErrRequestBodyIncomplete struct {
+	ContentLength int64
+	Received      int64
+	// 読み込みのエラー(io.ErrUnexpectedEOF)。エラー無しで短い場合はnil
+	Err error
+}
+
+func (e *ErrRequestBodyIncomplete) Error() string {
+	return fmt.Sprintf("request body incomplete: received %d of %d bytes", e.Received, e.ContentLength)
+}
+
+func (e *ErrRequestBodyIncomplete) Unwrap() error {
+	return e.Err
+}
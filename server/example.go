@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ルートのリクエスト・レスポンスの例
+type routeExample struct {
+	name       string
+	request    any
+	statusCode int
+	response   any
+}
+
+// ルートにリクエスト・レスポンスの例を追加する。
+// 例はDocsHandlerで表示され、モックモード(SetMockMode)ではハンドラの代わりにレスポンスとして返される。
+// requestはリクエストが無い場合はnilを指定する。
+// nameが重複している場合はpanicとなる。
+//
+//	server.Post("/users", createUser).
+//		Example("created", createUserRequest{Name: "taro"}, http.StatusCreated, userResponse{ID: 1, Name: "taro"}).
+//		Example("invalid", createUserRequest{}, http.StatusBadRequest, errorResponse{Message: "name is required"})
+func (rt *Route) Example(name string, request any, statusCode int, response any) *Route {
+	for _, ex := range rt.ru.examples {
+		if ex.name == name {
+			panic("example " + name + " already exists in route " + rt.ru.pattern)
+		}
+	}
+	rt.ru.examples = append(rt.ru.examples, routeExample{name: name, request: request, statusCode: statusCode, response: response})
+	return rt
+}
+
+// モックモードで返す例を指定するヘッダー
+// 指定しない場合は最初に追加した例を返す。
+const MockExampleHeader = "X-Mock-Example"
+
+var mockMode = false
+
+// モックモードを設定する。
+// モックモードでは、例(Route.Example)が設定されているルートはハンドラを実行せずに例のレスポンスを返す。
+// ミドルウェアは通常通り実行される。
+// フロントエンドの開発などで、ハンドラの実装前にAPIを利用するために使う。
+func SetMockMode(b bool) {
+	mustNotStarted("SetMockMode")
+	mockMode = b
+}
+
+// モックモードで例のレスポンスを返すルートか
+func isMockRoute(ru *route) bool {
+	return mockMode && len(ru.examples) > 0
+}
+
+func serveExample(w http.ResponseWriter, r *http.Request, ru *route) {
+	ex := ru.examples[0]
+	if name := r.Header.Get(MockExampleHeader); name != "" {
+		found := false
+		for _, e := range ru.examples {
+			if e.name == name {
+				ex, found = e, true
+				break
+			}
+		}
+		if !found {
+			SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": "example " + name + " not found"})
+			return
+		}
+	}
+	SetResponseAsJson(w, r, ex.statusCode, ex.response)
+}
+
+// ドキュメントに出力する例
+type docExample struct {
+	Name       string
+	Request    string
+	StatusCode int
+	Response   string
+}
+
+func newDocExamples(examples []routeExample) []docExample {
+	docs := make([]docExample, len(examples))
+	for i, ex := range examples {
+		docs[i] = docExample{Name: ex.name, StatusCode: ex.statusCode, Response: indentedJson(ex.response)}
+		if ex.request != nil {
+			docs[i].Request = indentedJson(ex.request)
+		}
+	}
+	return docs
+}
+
+func indentedJson(v any) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
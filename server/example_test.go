@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteExample$ ./server
+func TestRouteExample(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	setup := func() {
+		resetSetting()
+		Post("/users", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("handler"))
+		}).
+			Example("created", user{Name: "taro"}, http.StatusCreated, user{ID: 1, Name: "taro"}).
+			Example("conflict", user{Name: "jiro"}, http.StatusConflict, map[string]string{"message": "already exists"})
+		Get("/docs", DocsHandler)
+	}
+
+	t.Run("成功：モックモードでは例のレスポンスを返す", func(t *testing.T) {
+		setup()
+		SetMockMode(true)
+		for _, tc := range []struct {
+			example string
+			status  int
+			body    string
+		}{
+			{"", http.StatusCreated, `{"id":1,"name":"taro"}`},
+			{"conflict", http.StatusConflict, `{"message":"already exists"}`},
+			{"unknown", http.StatusNotFound, `{"message":"example unknown not found"}`},
+		} {
+			r := httptest.NewRequest(http.MethodPost, "/users", nil)
+			r.Header.Set(MockExampleHeader, tc.example)
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, r)
+			testutil.AssertEqual(t, w.Code, tc.status)
+			testutil.AssertEqual(t, w.Body.String(), tc.body)
+		}
+	})
+
+	t.Run("成功：モックモードでない場合はハンドラを実行する", func(t *testing.T) {
+		setup()
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+		testutil.AssertEqual(t, w.Body.String(), "handler")
+	})
+
+	t.Run("成功：ドキュメントに例が表示される", func(t *testing.T) {
+		setup()
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+		body := w.Body.String()
+		for _, s := range []string{
+			"<h3>Example: created</h3>",
+			"<pre>{\n  &#34;id&#34;: 1,\n  &#34;name&#34;: &#34;taro&#34;\n}</pre>",
+			"<p>409</p>",
+		} {
+			if !strings.Contains(body, s) {
+				t.Errorf("body should contain %q: %s", s, body)
+			}
+		}
+	})
+
+	t.Run("失敗：名前の重複はpanic", func(t *testing.T) {
+		resetSetting()
+		rt := Get("/dup", func(w http.ResponseWriter, r *http.Request) {}).Example("a", nil, http.StatusOK, nil)
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		rt.Example("a", nil, http.StatusOK, nil)
+	})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/fcgi"
+)
+
+// FastCGIでサーバーを起動する。
+// nginxなどのWebサーバーからFastCGIで転送される環境向けで、ハンドラはStartServerと同じものが利用される。
+// networkは"tcp"または"unix"、addressは"127.0.0.1:9000"や"/run/app.sock"などを指定する。
+//
+// FastCGIにはgraceful shutdownの仕組みが無いため、シャットダウンの信号を受け取るとリスナーを閉じて終了する。
+// (処理中のリクエストの完了は待たない)
+// 起動時の処理、readiness、定期実行のジョブ、ShutdownDelayなどはStartServerと同様に行われる。
+func StartFastCGIServer(c context.Context, network string, address string) {
+	var listener net.Listener
+	runServer(c, ShutdownTimeoutSecond, ShutdownDelay, func() {
+		var err error
+		if listener, err = net.Listen(network, address); err != nil {
+			panic(fmt.Sprintf("somethig error happend on server start: %s", err))
+		}
+		go func() {
+			// リスナーが閉じられるとエラーが返るため、シャットダウン後のエラーは無視する。
+			if err := fcgi.Serve(listener, HTTPHandler()); err != nil && !isClosedListenerError(err) {
+				panic(fmt.Sprintf("somethig error happend on server start: %s", err))
+			}
+		}()
+	}, func(ctx context.Context) error {
+		return listener.Close()
+	})
+}
+
+// Webサーバーから子プロセスとして起動され、標準入力をFastCGIの接続として受け付ける場合に利用する。
+// エラーが発生するまで戻らない。
+func ServeFastCGIStdin() error {
+	return fcgi.Serve(nil, HTTPHandler())
+}
+
+func isClosedListenerError(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestStartFastCGIServer$ ./server
+func TestStartFastCGIServer(t *testing.T) {
+	resetSetting()
+	var hooked atomic.Bool
+	OnStartup(func(c context.Context) error {
+		hooked.Store(true)
+		return nil
+	})
+	go StartFastCGIServer(context.Background(), "tcp", "127.0.0.1:8090")
+	time.Sleep(time.Millisecond * 100)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8090")
+	if err != nil {
+		t.Fatal("should accept connection:", err)
+	}
+	conn.Close()
+
+	t.Run("成功：StartServerと同様に起動時の処理を実行し、readinessが成功になる", func(t *testing.T) {
+		testutil.AssertEqual(t, hooked.Load(), true)
+		testutil.AssertEqual(t, IsReady(), true)
+	})
+
+	t.Run("失敗：起動後の設定の変更はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any("route registration must be called before the server starts"))
+		}()
+		Get("/after", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	Shutdown()
+	testutil.AssertEqual(t, IsReady(), false)
+	if _, err := net.Dial("tcp", "127.0.0.1:8090"); err == nil {
+		t.Error("listener should be closed after shutdown")
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// GuardMiddlewareの設定(実験的な機能)
+type GuardConfig struct {
+	// ハンドラの処理時間の上限。0の場合は制限しない。
+	Timeout time.Duration
+	// リクエストの処理中に割り当てられたメモリの推定値がこれを超えた場合にWarnを出力する。0の場合は計測しない。
+	// 推定値はプロセス全体の割り当ての増加量のため、同時に処理している他のリクエストの分も含まれる。
+	AllocWarnBytes uint64
+}
+
+// 暴走したハンドラからプロセスを守るためのミドルウェア(実験的な機能)
+// 処理時間がTimeoutを超えた場合はcontextをキャンセルし、Warnを出力して503を返す。
+// (goroutineを強制的に停止することはできないため、ハンドラはcontextのキャンセルで処理を終了する必要がある)
+// 超過後のハンドラからの書き込みはhttp.ErrHandlerTimeoutとなり、既にレスポンスの書き込みを開始していた場合は503を返さない。
+// TimeoutMiddlewareと異なりレスポンスをバッファリングしないため、ストリーミングのレスポンスにも利用できる。
+//
+//	server.Get("/report", h).Guard(server.GuardConfig{Timeout: 5 * time.Second, AllocWarnBytes: 64 << 20})
+func GuardMiddleware(conf GuardConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if conf.AllocWarnBytes > 0 {
+				before := heapAllocBytes()
+				defer func() {
+					if allocated := heapAllocBytes() - before; allocated > conf.AllocWarnBytes {
+						l.Warn(r.Context(), fmt.Sprintf("guard: %s %s allocated about %d bytes (threshold %d)", r.Method, r.URL.Path, allocated, conf.AllocWarnBytes))
+					}
+				}()
+			}
+			if conf.Timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), conf.Timeout)
+			defer cancel()
+			gw := &guardResponseWriter{w: w, h: http.Header{}}
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+			go func() {
+				defer func() {
+					if rv := recover(); rv != nil {
+						panicChan <- rv
+					}
+				}()
+				next.ServeHTTP(gw, r.WithContext(ctx))
+				close(done)
+			}()
+			select {
+			case rv := <-panicChan:
+				// recoverHandlerで処理されるように、呼び出し元のgoroutineでpanicさせる。
+				panic(rv)
+			case <-done:
+			case <-ctx.Done():
+				gw.mu.Lock()
+				defer gw.mu.Unlock()
+				gw.timedOut = true
+				l.Warn(r.Context(), fmt.Sprintf("guard: %s %s exceeded the time budget %s", r.Method, r.URL.Path, conf.Timeout))
+				if !gw.wroteHeader {
+					SetResponseAsJson(w, r, http.StatusServiceUnavailable, map[string]string{"message": "handler time budget exceeded"})
+				}
+			}
+		})
+	}
+}
+
+// ルートにGuardMiddlewareを追加する。
+func (rt *Route) Guard(conf GuardConfig) *Route {
+	rt.ru.middleware = append(rt.ru.middleware, GuardMiddleware(conf))
+	return rt
+}
+
+// プロセスの起動時からのヒープの割り当ての累計(バイト)を返す。
+// runtime.ReadMemStatsと異なりstop-the-worldを伴わない。
+func heapAllocBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// 処理時間の超過後の書き込みを防ぐhttp.ResponseWriter
+// ハンドラは別のgoroutineで実行されるため、ヘッダーは書き込みの開始時に元のResponseWriterへコピーする。
+type guardResponseWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (gw *guardResponseWriter) Header() http.Header {
+	return gw.h
+}
+
+func (gw *guardResponseWriter) writeHeaderLocked(statusCode int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+	dst := gw.w.Header()
+	for k, v := range gw.h {
+		dst[k] = v
+	}
+	gw.w.WriteHeader(statusCode)
+}
+
+func (gw *guardResponseWriter) WriteHeader(statusCode int) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.timedOut {
+		return
+	}
+	gw.writeHeaderLocked(statusCode)
+}
+
+func (gw *guardResponseWriter) Write(b []byte) (int, error) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	gw.writeHeaderLocked(http.StatusOK)
+	return gw.w.Write(b)
+}
+
+func (gw *guardResponseWriter) Flush() {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.timedOut {
+		return
+	}
+	gw.writeHeaderLocked(http.StatusOK)
+	http.NewResponseController(gw.w).Flush()
+}
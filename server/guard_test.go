@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestGuardMiddleware$ ./server
+func TestGuardMiddleware(t *testing.T) {
+	resetSetting()
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+
+	written := make(chan error, 1)
+	Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// 超過後の書き込みはエラーとなる。
+		_, err := w.Write([]byte("late"))
+		written <- err
+	}).Guard(GuardConfig{Timeout: time.Millisecond * 50})
+	Get("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "1")
+		SetResponseAsJson(w, r, http.StatusCreated, nil)
+	}).Guard(GuardConfig{Timeout: time.Second})
+	Get("/alloc", func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 8<<20)
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, buf[:1])
+	}).Guard(GuardConfig{AllocWarnBytes: 1 << 20})
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy")
+	}).Guard(GuardConfig{Timeout: time.Second})
+
+	t.Run("成功：時間内に終了した場合はそのまま返す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusCreated)
+		testutil.AssertEqual(t, w.Header().Get("X-Handler"), "1")
+	})
+
+	t.Run("失敗：時間を超えた場合は503を返し、ログを出力する", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusServiceUnavailable)
+		testutil.AssertEqual(t, <-written, http.ErrHandlerTimeout)
+		testutil.AssertEqual(t, lg.contains("guard: GET /slow exceeded the time budget 50ms"), true)
+	})
+
+	t.Run("成功：割り当ての推定値が閾値を超えた場合はログを出力する", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/alloc", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, lg.contains("guard: GET /alloc allocated about"), true)
+	})
+
+	t.Run("失敗：ハンドラのpanicは500となる", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestGuardMiddlewarePathParam$ ./server
+func TestGuardMiddlewarePathParam(t *testing.T) {
+	resetSetting()
+
+	next := make(chan struct{})
+	read := make(chan string, 1)
+	Get("/slow/:id", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// 超過後に別のリクエストが処理されても、自身のパスパラメータを参照できる。
+		<-next
+		read <- getPathParamVal(r, "id")
+	}).Guard(GuardConfig{Timeout: time.Millisecond * 50})
+	Get("/fast/:id", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(getPathParamVal(r, "id")))
+	})
+
+	t.Run("成功：時間を超えたハンドラはリクエスト完了後も自身のパスパラメータを参照できる", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/1", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusServiceUnavailable)
+
+		w = httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast/2", nil))
+		testutil.AssertEqual(t, w.Body.String(), "2")
+
+		close(next)
+		testutil.AssertEqual(t, <-read, "1")
+	})
+}
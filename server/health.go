@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// サーバーがリクエストを受け付ける準備ができているか
+// 起動(リスナーの作成)後にtrueとなり、シャットダウンの信号を受け取るとfalseになる。
+var ready atomic.Bool
+
+// サーバーの起動時刻(UnixNano)。起動前は0。
+var startedAt atomic.Int64
+
+// 起動時の処理(OnStartup)
+var startupHooks = []func(c context.Context) error{}
+
+// 起動時の処理が失敗した場合のエラー
+var startupErr atomic.Pointer[error]
+
+// リスナーの作成後、readinessが成功になる前に実行する処理を登録する。
+// キャッシュの事前読み込みやマイグレーションの確認などに利用する。
+// 登録した順に実行され、エラーを返した場合は以降の処理を実行せずにErrorを出力する。
+// その場合、サーバーはリクエストの受け付けを継続するがreadinessは成功にならず、
+// StatusHandlerに"startup"のヘルスチェックの失敗として表示される。
+// サーバーの起動前に呼び出す必要がある。
+func OnStartup(hook func(c context.Context) error) {
+	mustNotStarted("OnStartup")
+	startupHooks = append(startupHooks, hook)
+}
+
+// 起動時の処理を実行する。
+func runStartupHooks(c context.Context) error {
+	startupErr.Store(nil)
+	for _, hook := range startupHooks {
+		if err := hook(c); err != nil {
+			err = fmt.Errorf("startup hook failed: %w", err)
+			startupErr.Store(&err)
+			return err
+		}
+	}
+	return nil
+}
+
+// サーバーがリクエストを受け付ける準備ができているかを返す。
+func IsReady() bool {
+	return ready.Load()
+}
+
+// readinessの状態を返すハンドラ
+// 準備ができている場合は200、そうでない場合(起動中、シャットダウン中)は503を返す。
+//
+//	server.Get("/readyz", server.ReadinessHandler)
+func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if !IsReady() {
+		SetResponseAsJson(w, r, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+		return
+	}
+	SetResponseAsJson(w, r, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// 依存するコンポーネント(DB、外部APIなど)のヘルスチェック
+type healthCheck struct {
+	name  string
+	check func(c context.Context) error
+}
+
+var healthChecks = []healthCheck{}
+
+// コンポーネントのヘルスチェックを登録する。StatusHandlerで実行される。
+// checkは正常な場合にnilを返す。
+// サーバーの起動前に呼び出す必要がある。nameが重複している場合はpanicとなる。
+//
+//	server.RegisterHealthCheck("db", func(c context.Context) error { return db.PingContext(c) })
+func RegisterHealthCheck(name string, check func(c context.Context) error) {
+	mustNotStarted("RegisterHealthCheck")
+	for _, h := range healthChecks {
+		if h.name == name {
+			panic(fmt.Sprintf("health check %s is already registered", name))
+		}
+	}
+	healthChecks = append(healthChecks, healthCheck{name: name, check: check})
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestShutdownDelay$ ./server
+func TestShutdownDelay(t *testing.T) {
+	defer func() { ShutdownDelay = 0 }()
+	ShutdownDelay = time.Millisecond * 300
+	resetSetting()
+	Get("/readyz", ReadinessHandler)
+
+	go StartServer(context.Background(), "127.0.0.1", 8093)
+	time.Sleep(time.Millisecond * 100)
+
+	res, err := http.Get("http://127.0.0.1:8093/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	testutil.AssertEqual(t, res.StatusCode, http.StatusOK)
+
+	// Shutdownはシャットダウンの完了まで待機するためgo routineで呼び出す。
+	done := make(chan struct{})
+	go func() {
+		Shutdown()
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 100)
+
+	// 待機中はreadinessが失敗するが、リクエストの受け付けは継続する。
+	testutil.AssertEqual(t, IsReady(), false)
+	res, err = http.Get("http://127.0.0.1:8093/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	testutil.AssertEqual(t, res.StatusCode, http.StatusServiceUnavailable)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 2):
+		t.Fatal("server should shutdown after the delay")
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestReadinessHandler$ ./server
+func TestReadinessHandler(t *testing.T) {
+	defer ready.Store(false)
+	for _, tc := range []struct {
+		ready  bool
+		status int
+	}{
+		{true, http.StatusOK},
+		{false, http.StatusServiceUnavailable},
+	} {
+		ready.Store(tc.ready)
+		w := httptest.NewRecorder()
+		ReadinessHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		testutil.AssertEqual(t, w.Code, tc.status)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestOnStartup$ ./server
+func TestOnStartup(t *testing.T) {
+	t.Run("成功：起動時の処理の完了後にreadinessが成功になる", func(t *testing.T) {
+		resetSetting()
+		var calls []string
+		OnStartup(func(c context.Context) error {
+			// 起動時の処理の実行中はreadinessが失敗する。
+			calls = append(calls, fmt.Sprintf("first ready=%v", IsReady()))
+			return nil
+		})
+		OnStartup(func(c context.Context) error {
+			calls = append(calls, "second")
+			return nil
+		})
+		go StartServer(context.Background(), "127.0.0.1", 8096)
+		time.Sleep(time.Millisecond * 100)
+		testutil.AssertEqual(t, IsReady(), true)
+		Shutdown()
+		testutil.AssertEqual(t, strings.Join(calls, ","), "first ready=false,second")
+	})
+
+	t.Run("失敗：起動時の処理が失敗した場合はreadinessが成功にならない", func(t *testing.T) {
+		resetSetting()
+		lg := &recordLogger{}
+		SetLogger(lg)
+		defer SetLogger(&defaultLogger{})
+		secondCalled := false
+		OnStartup(func(c context.Context) error {
+			return errors.New("migration is not applied")
+		})
+		OnStartup(func(c context.Context) error {
+			secondCalled = true
+			return nil
+		})
+		Get("/status", StatusHandler)
+		go StartServer(context.Background(), "127.0.0.1", 8096)
+		time.Sleep(time.Millisecond * 100)
+		defer Shutdown()
+
+		testutil.AssertEqual(t, IsReady(), false)
+		testutil.AssertEqual(t, secondCalled, false)
+		testutil.AssertEqual(t, lg.contains("startup hook failed: migration is not applied"), true)
+
+		// リクエストの受け付けは継続し、ステータスページに表示される。
+		res, err := http.Get("http://127.0.0.1:8096/status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		if !strings.Contains(string(body), `<td>startup</td><td class="ng">startup hook failed: migration is not applied</td>`) {
+			t.Errorf("unexpected body: %s", body)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package server
+
+import "net/http"
+
+/*
+
+[他のフレームワークからの移行について]
+Middlewareの型はfunc(http.Handler) http.Handlerであり、chiなどのnet/http互換のミドルウェアはそのまま利用できる。
+・server.Get("/path", handler, middleware.Logger)
+それ以外の形式のハンドラやミドルウェアは下記の関数で変換する。
+
+*/
+
+// http.Handlerを本パッケージのHandlerへ変換する。
+func WrapHandler(h http.Handler) Handler {
+	return h.ServeHTTP
+}
+
+// func(http.HandlerFunc) http.HandlerFunc 形式のミドルウェアをMiddlewareへ変換する。
+func WrapHandlerFuncMiddleware(m func(http.HandlerFunc) http.HandlerFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		return m(next.ServeHTTP)
+	}
+}
+
+// negroniなどの func(w, r, next) 形式のミドルウェアをMiddlewareへ変換する。
+func WrapNextMiddleware(m func(http.ResponseWriter, *http.Request, http.HandlerFunc)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m(w, r, next.ServeHTTP)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestInterop$ ./server
+func TestInterop(t *testing.T) {
+	resetSetting()
+	var order []string
+
+	handlerFuncMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handlerFuncMiddleware")
+			next(w, r)
+		}
+	}
+	nextMiddleware := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		order = append(order, "nextMiddleware")
+		next(w, r)
+	}
+	// net/http互換のミドルウェアはそのまま渡せる。
+	stdMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "stdMiddleware")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mux", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, nil))
+	})
+
+	Get("/mux", WrapHandler(mux), WrapHandlerFuncMiddleware(handlerFuncMiddleware), WrapNextMiddleware(nextMiddleware), stdMiddleware)
+
+	execRequest(t, http.MethodGet, "/mux", nil, nil, http.StatusOK, createResponse(true, nil))
+	expect := []string{"handlerFuncMiddleware", "nextMiddleware", "stdMiddleware", "handler"}
+	if !reflect.DeepEqual(order, expect) {
+		t.Fatalf("unexpected order: got %v, want %v", order, expect)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+// Bindで受け付けるリクエストのJSONの制限
+// 0の場合は制限しない。
+type JsonLimits struct {
+	// オブジェクト、配列のネストの深さ
+	MaxDepth int
+	// 値(オブジェクト、配列、キーを含む文字列、数値、true、false、null)の数
+	MaxTokens int
+	// 数値の桁数(符号、小数点、指数を含む)
+	MaxNumberLength int
+}
+
+// デフォルトの制限
+var DefaultJsonLimits = JsonLimits{
+	MaxDepth:        64,
+	MaxTokens:       100000,
+	MaxNumberLength: 128,
+}
+
+var jsonLimits = DefaultJsonLimits
+
+// Bindで受け付けるリクエストのJSONの制限を設定する。
+// 制限を超えた場合、BindはErrRequestJsonLimitExceededをラップしたErrBindを返す。
+// 深いネストや大量の要素を含む悪意のあるリクエストで、デコードにCPUを消費させないために利用する。
+func SetJsonLimits(lim JsonLimits) {
+	mustNotStarted("SetJsonLimits")
+	jsonLimits = lim
+}
+
+// JSONを走査し、制限を超えていないか確認する。
+// JSONの文法のチェックは行わない(後続のUnmarshalで行う)。
+func checkJsonLimits(body string, lim JsonLimits) error {
+	if lim == (JsonLimits{}) {
+		return nil
+	}
+	depth, tokens := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '"':
+			tokens++
+			for i++; i < len(body); i++ {
+				if body[i] == '\\' {
+					i++
+				} else if body[i] == '"' {
+					break
+				}
+			}
+		case c == '{' || c == '[':
+			tokens++
+			depth++
+			if lim.MaxDepth > 0 && depth > lim.MaxDepth {
+				return &ErrRequestJsonLimitExceeded{Limit: "MaxDepth", Max: lim.MaxDepth}
+			}
+		case c == '}' || c == ']':
+			depth--
+		case c == '-' || ('0' <= c && c <= '9'):
+			tokens++
+			start := i
+			for i+1 < len(body) && isJsonNumberByte(body[i+1]) {
+				i++
+			}
+			if lim.MaxNumberLength > 0 && i-start+1 > lim.MaxNumberLength {
+				return &ErrRequestJsonLimitExceeded{Limit: "MaxNumberLength", Max: lim.MaxNumberLength}
+			}
+		case c == 't' || c == 'f' || c == 'n':
+			tokens++
+			for i+1 < len(body) && 'a' <= body[i+1] && body[i+1] <= 'z' {
+				i++
+			}
+		}
+		if lim.MaxTokens > 0 && tokens > lim.MaxTokens {
+			return &ErrRequestJsonLimitExceeded{Limit: "MaxTokens", Max: lim.MaxTokens}
+		}
+	}
+	return nil
+}
+
+func isJsonNumberByte(c byte) bool {
+	return ('0' <= c && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestCheckJsonLimits$ ./server
+func TestCheckJsonLimits(t *testing.T) {
+	lim := JsonLimits{MaxDepth: 3, MaxTokens: 7, MaxNumberLength: 5}
+	for _, tc := range []struct {
+		name  string
+		json  string
+		limit string
+	}{
+		{"成功：制限内", `{"a":[1,{"b":true}]}`, ""},
+		{"成功：文字列の中の記号は数えない", `{"a":"[[[[{{{{\"12345678"}`, ""},
+		{"失敗：ネストが深い", `{"a":[[[1]]]}`, "MaxDepth"},
+		{"失敗：値が多い", `[1,2,3,4,5,6,7]`, "MaxTokens"},
+		{"失敗：数値の桁数が多い", `{"a":-1.2345}`, "MaxNumberLength"},
+		{"成功：数値の桁数が制限と同じ", `{"a":-1.23}`, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkJsonLimits(tc.json, lim)
+			if tc.limit == "" {
+				testutil.AssertEqual(t, err, nil)
+				return
+			}
+			var limitErr *ErrRequestJsonLimitExceeded
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutil.AssertEqual(t, limitErr.Limit, tc.limit)
+		})
+	}
+
+	t.Run("成功：ゼロ値は制限しない", func(t *testing.T) {
+		testutil.AssertEqual(t, checkJsonLimits(strings.Repeat("[", 1000), JsonLimits{}), nil)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindJsonLimits$ ./server
+func TestBindJsonLimits(t *testing.T) {
+	resetSetting()
+	type request struct {
+		Data any `json:"data"`
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"data":`+strings.Repeat("[", 100)+strings.Repeat("]", 100)+`}`))
+	r.Header.Set("Content-Type", ContentTypeJSON)
+	var req request
+	err := Bind(r, &req)
+	var bindErr *ErrBind
+	var limitErr *ErrRequestJsonLimitExceeded
+	testutil.AssertEqual(t, errors.As(err, &bindErr), true)
+	testutil.AssertEqual(t, errors.As(err, &limitErr), true)
+	testutil.AssertEqual(t, err.Error(), "bind error:json exceeds MaxDepth (64)")
+}
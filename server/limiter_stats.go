@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// リミッター(RateLimitMiddleware、StoreRateLimitMiddleware、LoadShedMiddleware、CircuitBreakerMiddleware)の状態
+// 運用者がリクエストが拒否されている理由を確認するために利用する。
+type LimiterStats struct {
+	// 種類と作成順の番号(例: "rate_limit#1")
+	Name string `json:"name"`
+	// 現在の状態(種類ごとに異なる)
+	// rate_limit: buckets(キーの数)、limited(トークンが1未満のキーの数)
+	// load_shed: in_flight(処理中のリクエスト数)、max_in_flight
+	// store_rate_limit: limit、window_seconds
+	// circuit_breaker: closed、open、half_open(それぞれの状態のルートの数)
+	State map[string]int64 `json:"state"`
+	// ルート("メソッド パターン")ごとの拒否したリクエスト数
+	// ルートが無いリクエスト、Route.NoObservabilityのルートは"-"となる。
+	Rejected map[string]uint64 `json:"rejected"`
+}
+
+// 状態を記録するリミッター
+type limiterStat struct {
+	name  string
+	state func() map[string]int64
+
+	mu       sync.Mutex
+	rejected map[string]uint64
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = []*limiterStat{}
+)
+
+// リミッターを登録する。ミドルウェアの作成時に呼び出す。
+func registerLimiter(kind string, state func() map[string]int64) *limiterStat {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	n := 1
+	for _, ls := range limiters {
+		if strings.HasPrefix(ls.name, kind+"#") {
+			n++
+		}
+	}
+	ls := &limiterStat{name: fmt.Sprintf("%s#%d", kind, n), state: state, rejected: map[string]uint64{}}
+	limiters = append(limiters, ls)
+	return ls
+}
+
+// 拒否したリクエストを記録する。
+func (ls *limiterStat) reject(r *http.Request) {
+	label := routeLabel(r)
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.rejected[label]++
+}
+
+// リクエストのルートを"メソッド パターン"で返す。
+// ルートが無い場合、Route.NoObservabilityのルートの場合は"-"を返す。
+func routeLabel(r *http.Request) string {
+	ru := requestRoute(r)
+	if ru == nil || ru.noObservability {
+		return "-"
+	}
+	return r.Method + " " + ru.pattern
+}
+
+// 作成されたリミッターの状態を作成順に返す。
+func GetLimiterStats() []LimiterStats {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	stats := make([]LimiterStats, len(limiters))
+	for i, ls := range limiters {
+		ls.mu.Lock()
+		rejected := make(map[string]uint64, len(ls.rejected))
+		for k, v := range ls.rejected {
+			rejected[k] = v
+		}
+		ls.mu.Unlock()
+		stats[i] = LimiterStats{Name: ls.name, State: ls.state(), Rejected: rejected}
+	}
+	return stats
+}
+
+// リミッターの状態(GetLimiterStats)をJSONで返す管理用のハンドラ
+// 必要に応じて認証のミドルウェアを設定する。
+//
+//	server.Get("/admin/limiters", server.LimiterStatsHandler, server.AuthMiddleware(adminOnly))
+func LimiterStatsHandler(w http.ResponseWriter, r *http.Request) {
+	SetResponseAsJson(w, r, http.StatusOK, GetLimiterStats())
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestLimiterStatsHandler$ ./server
+func TestLimiterStatsHandler(t *testing.T) {
+	resetSetting()
+	SetCommonMiddleware(LoadShedMiddleware(10))
+	key := func(r *http.Request) string { return "client" }
+	Get("/items/:id", func(w http.ResponseWriter, r *http.Request) {}, RateLimitMiddleware(0.001, 1, key))
+	Get("/orders", func(w http.ResponseWriter, r *http.Request) {}, StoreRateLimitMiddleware(NewMemoryStore(), 1, time.Minute, key))
+	Get("/admin/limiters", LimiterStatsHandler)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/3", "/orders", "/orders"} {
+		HTTPHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/limiters", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, w.Body.String(), `[`+
+		`{"name":"load_shed#1","state":{"in_flight":1,"max_in_flight":10},"rejected":{}},`+
+		`{"name":"rate_limit#1","state":{"buckets":1,"limited":1},"rejected":{"GET /items/:id":2}},`+
+		`{"name":"store_rate_limit#1","state":{"limit":1,"window_seconds":60},"rejected":{"GET /orders":1}}`+
+		`]`)
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteLabel$ ./server
+func TestRouteLabel(t *testing.T) {
+	resetSetting()
+	Get("/items/:id", func(w http.ResponseWriter, r *http.Request) {})
+	testutil.AssertEqual(t, routeLabel(httptest.NewRequest(http.MethodGet, "/items/1", nil)), "GET /items/:id")
+	testutil.AssertEqual(t, routeLabel(httptest.NewRequest(http.MethodGet, "/unknown", nil)), "-")
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ロケールの解決の設定
+type LocaleConfig struct {
+	// サポートするロケール(例: "ja", "en", "en-US")
+	// Accept-Languageの値はこの中から選ばれる。(大文字・小文字は区別しない)
+	// 完全に一致しない場合は言語の部分(例: "en-GB"に対する"en")で一致するものを選ぶ。
+	Supported []string
+	// テナントのデフォルトのロケールを返す。nilまたは空文字を返した場合は利用しない。
+	TenantDefault func(r *http.Request) string
+	// サーバーのデフォルトのロケール
+	Default string
+}
+
+var localeConfig *LocaleConfig
+
+// ロケールの解決の設定を行う。LocaleMiddleware、Locales、500エラーのレスポンスで利用される。
+// サーバーの起動前に呼び出す必要がある。
+func SetLocaleConfig(conf LocaleConfig) {
+	mustNotStarted("SetLocaleConfig")
+	localeConfig = &conf
+}
+
+// ロケールごとの500エラーのレスポンス
+var localizedInternalServerErrorResponses = map[string][]byte{}
+
+// 指定したロケールの500エラーのレスポンスを設定する。
+// panicが発生した場合、リクエストのロケールの候補(Locales)の先頭から順に、設定されているレスポンスを返す。
+// いずれも設定されていない場合はSetInternalServerErrorResponseのレスポンスを返す。
+// ContentTypeはSetInternalServerErrorResponseで設定したものとなる。
+func SetInternalServerErrorResponseForLocale(locale string, data []byte) {
+	mustNotStarted("SetInternalServerErrorResponseForLocale")
+	localizedInternalServerErrorResponses[strings.ToLower(locale)] = data
+}
+
+// 500エラーのレスポンスをロケールに応じて返す。
+func internalServerErrorResponseFor(r *http.Request) []byte {
+	if len(localizedInternalServerErrorResponses) > 0 {
+		for _, locale := range Locales(r) {
+			if data, ok := localizedInternalServerErrorResponses[strings.ToLower(locale)]; ok {
+				return data
+			}
+		}
+	}
+	return internalServerErrorResponse
+}
+
+// リクエストのロケールの候補を解決してcontextにセットするミドルウェア
+// 候補は、Accept-Languageで要求されたもの(品質値の順) -> テナントのデフォルト -> サーバーのデフォルトの順となる。
+// レスポンスにはContent-Language(先頭の候補)とVary: Accept-Languageを付与する。
+// 事前にSetLocaleConfigで設定しておく必要があり、設定していない場合はpanicとなる。
+//
+//	server.SetLocaleConfig(server.LocaleConfig{Supported: []string{"ja", "en"}, Default: "en"})
+//	server.SetCommonAfterMiddleware(server.LocaleMiddleware)
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if localeConfig == nil {
+			panic("locale config is not set. call SetLocaleConfig before using LocaleMiddleware")
+		}
+		locales := resolveLocales(r, localeConfig)
+		AddVary(w, "Accept-Language")
+		if len(locales) > 0 {
+			w.Header().Set("Content-Language", locales[0])
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{Key: "locales"}, locales)))
+	})
+}
+
+// リクエストのロケールの候補を優先順に返す。
+// LocaleMiddlewareで解決済みの場合はその値を返し、
+// そうでない場合はSetLocaleConfigの設定をもとに解決する。設定されていない場合はnilを返す。
+func Locales(r *http.Request) []string {
+	if locales, ok := r.Context().Value(contextKey{Key: "locales"}).([]string); ok {
+		return locales
+	}
+	if localeConfig == nil {
+		return nil
+	}
+	return resolveLocales(r, localeConfig)
+}
+
+// リクエストのロケール(候補の先頭)を返す。候補が無い場合は空文字を返す。
+func Locale(r *http.Request) string {
+	if locales := Locales(r); len(locales) > 0 {
+		return locales[0]
+	}
+	return ""
+}
+
+func resolveLocales(r *http.Request, conf *LocaleConfig) []string {
+	var locales []string
+	add := func(locale string) {
+		if locale != "" && !slices.ContainsFunc(locales, func(l string) bool { return strings.EqualFold(l, locale) }) {
+			locales = append(locales, locale)
+		}
+	}
+	for _, tag := range parseAcceptLanguage(r.Header.Get("Accept-Language")) {
+		add(matchLocale(tag, conf.Supported))
+	}
+	if conf.TenantDefault != nil {
+		add(conf.TenantDefault(r))
+	}
+	add(conf.Default)
+	return locales
+}
+
+// サポートするロケールから一致するものを返す。一致しない場合は空文字を返す。
+// supportedが空の場合はtagをそのまま返す。
+func matchLocale(tag string, supported []string) string {
+	if len(supported) == 0 {
+		return tag
+	}
+	for _, s := range supported {
+		if strings.EqualFold(s, tag) {
+			return s
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, s := range supported {
+		if strings.EqualFold(s, base) {
+			return s
+		}
+	}
+	return ""
+}
+
+// Accept-Languageの言語タグを品質値の高い順に返す。
+// 品質値が0のもの、"*"は除外する。
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int { return cmp.Compare(b.q, a.q) })
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
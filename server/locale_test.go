@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestLocaleMiddleware$ ./server
+func TestLocaleMiddleware(t *testing.T) {
+	resetSetting()
+	SetLocaleConfig(LocaleConfig{
+		Supported: []string{"ja", "en", "en-US", "fr"},
+		TenantDefault: func(r *http.Request) string {
+			return r.Header.Get("X-Tenant-Locale")
+		},
+		Default: "en",
+	})
+	SetCommonAfterMiddleware(LocaleMiddleware)
+	Get("/locales", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(strings.Join(Locales(r), ",")))
+	})
+
+	for _, tc := range []struct {
+		name           string
+		acceptLanguage string
+		tenant         string
+		expect         string
+	}{
+		{"成功：サーバーのデフォルト", "", "", "en"},
+		{"成功：品質値の順に並び、デフォルトが後ろに続く", "fr;q=0.5, ja, de;q=0.9", "", "ja,fr,en"},
+		{"成功：言語の部分で一致する", "en-GB", "", "en"},
+		{"成功：完全に一致するものを優先する", "EN-us", "", "en-US,en"},
+		{"成功：テナントのデフォルトがサーバーのデフォルトの前に入る", "de", "ja", "ja,en"},
+		{"成功：品質値が0のものは除外される", "ja;q=0, *", "", "en"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/locales", nil)
+			r.Header.Set("Accept-Language", tc.acceptLanguage)
+			r.Header.Set("X-Tenant-Locale", tc.tenant)
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, r)
+			testutil.AssertEqual(t, w.Body.String(), tc.expect)
+			testutil.AssertEqual(t, w.Header().Get("Content-Language"), strings.Split(tc.expect, ",")[0])
+			testutil.AssertEqual(t, w.Header().Get("Vary"), "Accept-Language")
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestInternalServerErrorResponseForLocale$ ./server
+func TestInternalServerErrorResponseForLocale(t *testing.T) {
+	resetSetting()
+	SetLocaleConfig(LocaleConfig{Supported: []string{"ja", "en"}})
+	SetInternalServerErrorResponseForLocale("ja", []byte(`{"message":"サーバーエラー"}`))
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy")
+	})
+
+	for _, tc := range []struct {
+		name           string
+		acceptLanguage string
+		expect         string
+	}{
+		{"成功：ロケールのレスポンスが返される", "en;q=0.5, ja", `{"message":"サーバーエラー"}`},
+		{"成功：設定されていないロケールはデフォルトのレスポンス", "en", `{"is_success":false,"data":{"message":"something error"}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/panic", nil)
+			r.Header.Set("Accept-Language", tc.acceptLanguage)
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, r)
+			testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+			testutil.AssertEqual(t, w.Body.String(), tc.expect)
+		})
+	}
+}
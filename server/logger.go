@@ -9,7 +9,10 @@ var (
 	l Logger = &defaultLogger{}
 )
 
+// ロガーを設定する。
+// サーバーの起動前に呼び出す必要がある。
 func SetLogger(lg Logger) {
+	mustNotStarted("SetLogger")
 	l = lg
 }
 
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+
+[スコープによるレスポンスのフィールドのマスクについて]
+SetResponseAsJsonでは、レスポンスの構造体のフィールドに"scope"タグが指定されている場合、
+リクエストのPrincipalがいずれかのスコープを持っていなければそのフィールドを出力しない。
+Principalがセットされていないリクエストでは、"scope"タグのフィールドはすべて出力されない。
+
+	type user struct {
+		ID    string `json:"id"`
+		Email string `json:"email" scope:"admin,support"`  // admin、supportのいずれかのスコープを持つ場合のみ出力
+		Phone string `json:"phone" scope:"admin,mask"`     // スコープを持たない場合は"***"として出力
+	}
+
+"scope"タグの最後に",mask"を指定した場合は、フィールドを削除せずに値を"***"に置き換える。
+"scope"タグを含まない型はそのままjsonへ変換される。
+json.Marshalerを実装した型は、その内部のフィールドを対象としない。
+
+*/
+
+// スコープを持たない場合に置き換える値
+const scopeMaskedValue = "***"
+
+// 型が"scope"タグを持つかどうか
+type scopeTagKind int
+
+const (
+	// "scope"タグを持たない
+	scopeTagNone scopeTagKind = iota
+	// interfaceのフィールドなどを含み、実際の値を確認するまで判断できない
+	scopeTagDynamic
+	// "scope"タグを(入れ子のフィールドを含めて)持つ
+	scopeTagStatic
+)
+
+// 型ごとのscopeTagKindのキャッシュ
+// キーはreflect.Type、バリューはscopeTagKind
+var scopeTagCache sync.Map
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func getScopeTagKind(rt reflect.Type) scopeTagKind {
+	if kind, ok := scopeTagCache.Load(rt); ok {
+		return kind.(scopeTagKind)
+	}
+	kind := scopeTagKindRec(rt, map[reflect.Type]bool{})
+	scopeTagCache.Store(rt, kind)
+	return kind
+}
+
+func scopeTagKindRec(rt reflect.Type, visiting map[reflect.Type]bool) scopeTagKind {
+	if visiting[rt] || rt.Implements(jsonMarshalerType) || reflect.PointerTo(rt).Implements(jsonMarshalerType) {
+		return scopeTagNone
+	}
+	visiting[rt] = true
+	switch rt.Kind() {
+	case reflect.Interface:
+		return scopeTagDynamic
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return scopeTagKindRec(rt.Elem(), visiting)
+	case reflect.Struct:
+		kind := scopeTagNone
+		for i := range rt.NumField() {
+			f := rt.Field(i)
+			if !f.IsExported() && !f.Anonymous {
+				continue
+			}
+			if f.Tag.Get("scope") != "" {
+				return scopeTagStatic
+			}
+			kind = max(kind, scopeTagKindRec(f.Type, visiting))
+			if kind == scopeTagStatic {
+				return kind
+			}
+		}
+		return kind
+	}
+	return scopeTagNone
+}
+
+// 値が"scope"タグを持つ型の値を含むかを返す。
+// scopeTagDynamicの型の場合は、interfaceの実際の値をたどって確認する。
+func containsScopedValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	switch getScopeTagKind(v.Type()) {
+	case scopeTagNone:
+		return false
+	case scopeTagStatic:
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && containsScopedValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if containsScopedValue(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if containsScopedValue(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if containsScopedValue(v.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Principalのスコープに応じてフィールドを削除、マスクした値を返す。
+// "scope"タグを含まない型の場合はdataをそのまま返す。
+func maskByScope(r *http.Request, data any) any {
+	if data == nil {
+		return data
+	}
+	v := reflect.ValueOf(data)
+	if !containsScopedValue(v) {
+		return data
+	}
+	var p *Principal
+	if r != nil {
+		p, _ = PrincipalFrom(r)
+	}
+	return maskValue(v, p)
+}
+
+func maskValue(v reflect.Value, p *Principal) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if getScopeTagKind(v.Type()) == scopeTagNone {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return maskValue(v.Elem(), p)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			out[i] = maskValue(v.Index(i), p)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[mapKeyString(iter.Key())] = maskValue(iter.Value(), p)
+		}
+		return out
+	case reflect.Struct:
+		out := maskedObject{}
+		appendMaskedFields(&out, v, p)
+		return out
+	}
+	return v.Interface()
+}
+
+func appendMaskedFields(out *maskedObject, v reflect.Value, p *Principal) {
+	rt := v.Type()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		fv := v.Field(i)
+		// 埋め込みの構造体は、jsonのタグが無い場合はフィールドを展開する(encoding/jsonと同様)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				ft, fv = ft.Elem(), fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				appendMaskedFields(out, fv, p)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyJsonValue(fv) {
+			continue
+		}
+		if scope := f.Tag.Get("scope"); scope != "" {
+			scopes := strings.Split(scope, ",")
+			mask := scopes[len(scopes)-1] == "mask"
+			if mask {
+				scopes = scopes[:len(scopes)-1]
+			}
+			if !hasAnyScope(p, scopes) {
+				if mask {
+					*out = append(*out, maskedField{name: name, val: scopeMaskedValue})
+				}
+				continue
+			}
+		}
+		*out = append(*out, maskedField{name: name, val: maskValue(fv, p)})
+	}
+}
+
+func hasAnyScope(p *Principal, scopes []string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range scopes {
+		if p.HasScope(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// encoding/jsonのomitemptyと同じ判定を行う。
+func isEmptyJsonValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func mapKeyString(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if b, err := k.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// フィールドの順序を保持したjsonのオブジェクト
+// mapでは出力の順序が変わってしまうため、構造体の定義順で出力するために利用する。
+type maskedObject []maskedField
+
+type maskedField struct {
+	name string
+	val  any
+}
+
+func (o maskedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(f.val)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+type maskedUser struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email" scope:"admin,support"`
+	Phone     string    `json:"phone" scope:"admin,mask"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type maskedUsers struct {
+	Users  []maskedUser          `json:"users"`
+	ByID   map[string]maskedUser `json:"byId"`
+	Hidden string                `json:"-"`
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestMaskByScope$ ./server
+func TestMaskByScope(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	u := maskedUser{ID: "1", Email: "a@example.com", Phone: "000", CreatedAt: createdAt}
+
+	serve := func(p *Principal, data any) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if p != nil {
+			req = SetPrincipal(req, p)
+		}
+		res := httptest.NewRecorder()
+		SetResponseAsJson(res, req, http.StatusOK, data)
+		return res.Body.String()
+	}
+
+	t.Run("成功：Principalが無い場合はscopeタグのフィールドが出力されない", func(t *testing.T) {
+		testutil.AssertEqual(t, serve(nil, u), `{"id":"1","phone":"***","createdAt":"2024-01-02T03:04:05Z"}`)
+	})
+
+	t.Run("成功：いずれかのスコープを持つ場合は出力される", func(t *testing.T) {
+		testutil.AssertEqual(t, serve(&Principal{Scopes: []string{"support"}}, &u), `{"id":"1","email":"a@example.com","phone":"***","createdAt":"2024-01-02T03:04:05Z"}`)
+		testutil.AssertEqual(t, serve(&Principal{Scopes: []string{"admin"}}, u), `{"id":"1","email":"a@example.com","phone":"000","createdAt":"2024-01-02T03:04:05Z"}`)
+	})
+
+	t.Run("成功：スライスやマップ内の構造体も対象となる", func(t *testing.T) {
+		data := createResponse(true, maskedUsers{Users: []maskedUser{u}, ByID: map[string]maskedUser{"1": u}, Hidden: "x"})
+		testutil.AssertEqual(t, serve(nil, data), `{"is_success":true,"data":{"users":[{"id":"1","phone":"***","createdAt":"2024-01-02T03:04:05Z"}],"byId":{"1":{"id":"1","phone":"***","createdAt":"2024-01-02T03:04:05Z"}}}}`)
+	})
+
+	t.Run("成功：scopeタグを含まない型はそのまま出力される", func(t *testing.T) {
+		testutil.AssertEqual(t, containsScopedValue(reflect.ValueOf(createResponse(true, friend{ID: "1"}))), false)
+		data := createResponse(true, friend{ID: "1", Name: "friend1"})
+		expect, _ := json.Marshal(data)
+		testutil.AssertEqual(t, serve(nil, data), string(expect))
+	})
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// メソッドの上書きを指定するヘッダー
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// メソッドの上書きを指定するフォームのフィールド
+const MethodOverrideFormField = "_method"
+
+// 上書きを許可するメソッド
+var methodOverrideTargets = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// POSTのリクエストのメソッドを、MethodOverrideHeaderまたはフォームの_methodフィールドで指定したメソッドに置き換えるミドルウェア
+// HTMLのフォームやPUT、DELETEを送信できないクライアントから、それらのルートを利用するために使う。
+// 上書きできるのはPUT、PATCH、DELETEのみで、それ以外の値は無視する。
+// ヘッダーとフォームの両方が指定された場合はヘッダーを優先する。
+// ルーティングの前に実行する必要があるため、SetCommonMiddlewareで設定する。
+//
+//	server.SetCommonMiddleware(server.MethodOverrideMiddleware)
+func MethodOverrideMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			method := r.Header.Get(MethodOverrideHeader)
+			if method == "" && isFormRequest(r) {
+				method = methodFromForm(r)
+			}
+			method = strings.ToUpper(method)
+			for _, m := range methodOverrideTargets {
+				if method == m {
+					r.Method = m
+					break
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// フォームの_methodフィールドの値を返す。
+// 後続のBindなどでボディを読み込めるように、読み込んだボディは戻しておく。
+func methodFromForm(r *http.Request) string {
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(MethodOverrideFormField)
+}
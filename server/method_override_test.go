@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestMethodOverrideMiddleware$ ./server
+func TestMethodOverrideMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonMiddleware(MethodOverrideMiddleware)
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Method+" "+IoReaderToString(r.Body)))
+	}
+	Post("/item", echo)
+	Put("/item", echo)
+	Patch("/item", echo)
+	Delete("/item", echo)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		header string
+		body   string
+		expect string
+	}{
+		{"成功：ヘッダーで上書きされる", http.MethodPost, "DELETE", "", "DELETE "},
+		{"成功：フォームの_methodで上書きされ、後続でボディを読み込める", http.MethodPost, "", "_method=put&name=a", "PUT _method=put&name=a"},
+		{"成功：PATCHのルート", http.MethodPost, "PATCH", "", "PATCH "},
+		{"成功：ヘッダーが優先される", http.MethodPost, "DELETE", "_method=PUT", "DELETE _method=PUT"},
+		{"成功：許可されていないメソッドは無視される", http.MethodPost, "GET", "", "POST "},
+		{"成功：POST以外は上書きされない", http.MethodPut, "DELETE", "", "PUT "},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/item", strings.NewReader(tc.body))
+			if tc.body != "" {
+				r.Header.Set("Content-Type", ContentTypeFormURLEnc)
+			}
+			if tc.header != "" {
+				r.Header.Set(MethodOverrideHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, r)
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), tc.expect)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// レスポンスのステータスコードとサイズを記録するhttp.ResponseWriter
+// ミドルウェアでハンドラの処理結果を参照するために利用する。
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (w *responseRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// ステータスコードを返す。何も書き込まれていない場合は200とみなす。
+func (w *responseRecorder) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// アクセスログを出力するミドルウェア
+// メソッド、パス、ステータスコード、レスポンスのバイト数、処理時間をInfoで出力する。
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsObservabilityDisabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		completed := false
+		defer func() {
+			// panicの場合もログを出力する。
+			// ステータスはこの後にrecoverHandlerが書き込むため、ここでは500とする。
+			// (recoverするとスタックトレースが失われるため、recoverはしない)
+			status := rec.statusCode()
+			if !completed {
+				status = http.StatusInternalServerError
+			}
+			l.Info(r.Context(), fmt.Sprintf("%s %s %d %dB %s", r.Method, scrubbedRequestURI(r), status, rec.bytes, time.Since(start)))
+		}()
+		next.ServeHTTP(rec, r)
+		completed = true
+	})
+}
+
+// セキュリティ関連のレスポンスヘッダーを付与するミドルウェア
+// TLSのリクエストの場合はStrict-Transport-Securityも付与する。
+func SecureHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if r.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 処理時間がdを超えた場合に503を返すミドルウェア
+// 超えた時点でリクエストのcontextはキャンセルされる。
+// http.TimeoutHandlerを利用しているため、レスポンスはバッファリングされる。
+// (ストリーミングのレスポンスには利用できない。その場合はRoute.Guardを利用する)
+// Flush、Hijackも利用できなくなるため、共通のミドルウェアではなくルートごとのミドルウェアとして指定する。
+//
+//	server.Get("/report", h, server.TimeoutMiddleware(5*time.Second))
+func TimeoutMiddleware(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, `{"message":"timeout"}`)
+	}
+}
+
+// リクエストボディのサイズをnバイトに制限するミドルウェア
+// 超えた場合はボディの読み込み時にエラー(*http.MaxBytesError)となる。
+func BodyLimitMiddleware(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				SetResponseAsJson(w, r, http.StatusRequestEntityTooLarge, map[string]string{"message": "request body too large"})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// リクエストの内容(ヘッダー、ボディ)をDebugで出力するミドルウェア
+// 開発用であり、本番環境では利用しないこと。
+// 個人情報(pii.goを参照)の値は置き換えて出力する。
+// Bindで登録された型も対象とするため、出力はハンドラの処理の後に行う。
+func DebugDumpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ハンドラで読み込まれる前にボディを取得し、再度読み込めるように戻す。
+		var body []byte
+		if r.Body != nil {
+			var err error
+			if body, err = io.ReadAll(r.Body); err != nil {
+				l.Warn(r.Context(), fmt.Sprintf("failed to dump request: %s", err))
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		dumpReq := r.Clone(r.Context())
+		defer func() {
+			dumpReq.URL.RawQuery = ""
+			dumpReq.RequestURI = scrubbedRequestURI(r)
+			for k := range dumpReq.Header {
+				if isPIIField(k) {
+					dumpReq.Header[k] = []string{piiMaskedValue}
+				}
+			}
+			dump, err := httputil.DumpRequest(dumpReq, false)
+			if err != nil {
+				l.Warn(r.Context(), fmt.Sprintf("failed to dump request: %s", err))
+				return
+			}
+			l.Debug(r.Context(), "request dump:\n"+string(dump)+scrubPIIBody(r.Header.Get("Content-Type"), string(body)))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 環境ごとの設定のまとまり
+// UsePresetで一度に設定する。
+type Preset struct {
+	// 共通のミドルウェアとして設定される。
+	Middleware []Middleware
+	// SetResponseAsJsonで整形したJSONを返すかどうか
+	PrettyJson bool
+}
+
+// 本番環境向けの設定
+// アクセスログ、セキュリティヘッダー、リクエストボディの制限(10MB)を設定する。
+// panicのリカバリーは常に有効なため含まれていない。
+// タイムアウトはストリーミングのレスポンスを妨げないように含めていない。
+// 必要なルートにRoute.GuardまたはTimeoutMiddlewareを個別に指定する。
+func ProductionPreset() Preset {
+	return Preset{
+		Middleware: []Middleware{
+			AccessLogMiddleware,
+			SecureHeadersMiddleware,
+			BodyLimitMiddleware(10 << 20),
+		},
+	}
+}
+
+// 開発環境向けの設定
+// アクセスログ、リクエストの内容の出力を設定し、JSONを整形して返す。
+func DevPreset() Preset {
+	return Preset{
+		Middleware: []Middleware{
+			AccessLogMiddleware,
+			DebugDumpMiddleware,
+		},
+		PrettyJson: true,
+	}
+}
+
+// Presetの内容を設定する。
+// 共通のミドルウェアはPresetのミドルウェアで置き換えられる。
+func UsePreset(p Preset) {
+	SetCommonMiddleware(p.Middleware...)
+	SetPrettyJson(p.PrettyJson)
+}
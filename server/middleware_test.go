@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// ログの内容を記録するLogger
+type recordLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordLogger) record(level string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, level+fmt.Sprint(args...))
+}
+
+func (l *recordLogger) Info(c context.Context, args ...any)  { l.record("[INFO]", args...) }
+func (l *recordLogger) Debug(c context.Context, args ...any) { l.record("[DEBUG]", args...) }
+func (l *recordLogger) Warn(c context.Context, args ...any)  { l.record("[WARN]", args...) }
+func (l *recordLogger) Error(c context.Context, args ...any) { l.record("[ERROR]", args...) }
+
+func (l *recordLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, log := range l.logs {
+		if strings.Contains(log, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestAccessLogMiddleware$ ./server
+func TestAccessLogMiddleware(t *testing.T) {
+	resetSetting()
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+	SetCommonMiddleware(AccessLogMiddleware)
+
+	Get("/created", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusCreated, []byte("created"))
+	})
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	})
+
+	t.Run("成功：ステータスとバイト数が出力される", func(t *testing.T) {
+		execRequest[any](t, http.MethodGet, "/created", nil, nil, http.StatusCreated, nil)
+		if !lg.contains("[INFO]GET /created 201 7B") {
+			t.Errorf("unexpected logs: %v", lg.logs)
+		}
+	})
+
+	t.Run("成功：panicの場合も出力される", func(t *testing.T) {
+		execRequest[any](t, http.MethodGet, "/panic", nil, nil, http.StatusInternalServerError, nil)
+		if !lg.contains("[INFO]GET /panic 500") {
+			t.Errorf("unexpected logs: %v", lg.logs)
+		}
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestSecureHeadersMiddleware$ ./server
+func TestSecureHeadersMiddleware(t *testing.T) {
+	res := httptest.NewRecorder()
+	SecureHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	testutil.AssertEqual(t, res.Header().Get("X-Content-Type-Options"), "nosniff")
+	testutil.AssertEqual(t, res.Header().Get("X-Frame-Options"), "DENY")
+	testutil.AssertEqual(t, res.Header().Get("Strict-Transport-Security"), "")
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestTimeoutMiddleware$ ./server
+func TestTimeoutMiddleware(t *testing.T) {
+	res := httptest.NewRecorder()
+	TimeoutMiddleware(10*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	testutil.AssertEqual(t, res.Code, http.StatusServiceUnavailable)
+
+	t.Run("成功：ルートごとにタイムアウトを設定できる", func(t *testing.T) {
+		resetSetting()
+		canceled := make(chan error, 1)
+		Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			canceled <- r.Context().Err()
+		}, TimeoutMiddleware(10*time.Millisecond))
+		Get("/fast", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("ok"))
+		})
+
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		testutil.AssertEqual(t, res.Code, http.StatusServiceUnavailable)
+		testutil.AssertEqual(t, <-canceled, context.DeadlineExceeded)
+
+		res = httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		testutil.AssertEqual(t, res.Code, http.StatusOK)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBodyLimitMiddleware$ ./server
+func TestBodyLimitMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonMiddleware(BodyLimitMiddleware(10))
+	Post("/comment", func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, &addCommentRequest{}, (*emptyDataResponse)(nil), http.StatusCreated, func(req *addCommentRequest) (any, error) {
+			return nil, nil
+		})
+	})
+
+	t.Run("成功：制限以内", func(t *testing.T) {
+		execRequest(t, http.MethodPost, "/comment", stringToIoReader(`{}`), nil, http.StatusCreated, createResponse(true, nil))
+	})
+
+	t.Run("失敗：Content-Lengthが制限を超える", func(t *testing.T) {
+		execRequest(t, http.MethodPost, "/comment", stringToIoReader(`{"comment":"too long comment"}`), nil, http.StatusRequestEntityTooLarge, &map[string]string{"message": "request body too large"})
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestUsePreset$ ./server
+func TestUsePreset(t *testing.T) {
+	resetSetting()
+	SetLogger(&discardLogger{})
+	defer SetLogger(&defaultLogger{})
+	UsePreset(DevPreset())
+	defer resetSetting()
+
+	Get("/json", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, map[string]int{"a": 1})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/json", nil)
+	res := httptest.NewRecorder()
+	http.HandlerFunc(recoverHandler).ServeHTTP(res, req)
+	testutil.AssertEqual(t, res.Body.String(), "{\n  \"a\": 1\n}")
+	testutil.AssertEqual(t, len(commonMiddleware), 2)
+
+	UsePreset(ProductionPreset())
+	testutil.AssertEqual(t, prettyJson, false)
+	testutil.AssertEqual(t, len(commonMiddleware), 3)
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// サーバーへマウントする前のルートの集まり
+// パッケージごとにルートを定義し、Mountでプレフィックスを指定してサーバーへ登録する。
+// Router自体はサーバーの設定を変更しないため、Mountを呼ぶまではどこで作成してもよい。
+//
+//	admin := server.NewRouter(adminAuth)
+//	admin.Get("/users", listUsers)
+//	admin.NotFound(adminNotFound)
+//	admin.Recover(adminInternalServerError)
+//	server.Mount("/admin", admin) // GET /admin/users
+type Router struct {
+	routes     []routerRoute
+	children   []routerChild
+	middleware []Middleware
+	notFound   Handler
+	recover    func(w http.ResponseWriter, r *http.Request, rv any)
+	mounted    bool
+}
+
+type routerRoute struct {
+	method string
+	ru     *route
+}
+
+type routerChild struct {
+	prefix string
+	router *Router
+}
+
+// ルーターを作成する。
+// middlewareはルーターのすべてのルートで、ルートごとのミドルウェアの前に実行される。
+func NewRouter(middleware ...Middleware) *Router {
+	return &Router{middleware: middleware}
+}
+
+// ルーターのミドルウェアを追加する。
+// Mountの前であれば、ルートを追加した後に呼んでもすべてのルートに適用される。
+func (rt *Router) Use(middleware ...Middleware) {
+	rt.middleware = append(rt.middleware, middleware...)
+}
+
+// ルーターのプレフィックス配下でルートにマッチしなかったリクエストのハンドラを設定する。
+// ハンドラの前にはルーターのミドルウェアが実行される。
+// 設定しない場合は、サーバーの共通の404(SetNoMethodResponse)となる。(NotFoundでプレフィックスに設定したハンドラがある場合はそちら)
+func (rt *Router) NotFound(hr Handler) {
+	rt.notFound = hr
+}
+
+// ルーターのルート(入れ子のルーター、Router.NotFoundのハンドラを含む)でpanicが発生した際にレスポンスを返す関数を設定する。
+// 共通の500エラーのレスポンス(SetInternalServerErrorResponse)より優先され、
+// 例えば/apiはJSON、/webはHTMLのエラーページを返すように、ルーターごとにレスポンスを変える場合に利用する。
+// Route.Recoverを設定したルート、Recoverを設定した入れ子のルーターのルートはそちらが優先される。
+// ルーティング処理の前に実行されるミドルウェア(SetCommonMiddleware)でのpanicは対象外となる。
+func (rt *Router) Recover(f func(w http.ResponseWriter, r *http.Request, rv any)) {
+	rt.recover = f
+}
+
+// ルートを追加する。
+// pathはルーター内のパスで、Mountの際にプレフィックスが付与される。(パスが"/"の場合はプレフィックスそのものとなる)
+// methodがHTTPのメソッドとして不正な場合はpanicになる。
+func (rt *Router) Handle(method string, path string, hr Handler, middleware ...Middleware) *Route {
+	if method != methodAny && !isValidMethod(method) {
+		panic(fmt.Sprintf(PanicInvalidMethod, method))
+	}
+	parsePattern(path)
+	ru := &route{
+		pattern:    path,
+		handler:    hr,
+		middleware: middleware,
+	}
+	rt.routes = append(rt.routes, routerRoute{method: method, ru: ru})
+	return &Route{ru: ru}
+}
+
+// GETメソッドのルートを追加する。
+func (rt *Router) Get(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodGet, path, hr, middleware...)
+}
+
+// POSTメソッドのルートを追加する。
+func (rt *Router) Post(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodPost, path, hr, middleware...)
+}
+
+// PUTメソッドのルートを追加する。
+func (rt *Router) Put(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodPut, path, hr, middleware...)
+}
+
+// PATCHメソッドのルートを追加する。
+func (rt *Router) Patch(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodPatch, path, hr, middleware...)
+}
+
+// DELETEメソッドのルートを追加する。
+func (rt *Router) Delete(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodDelete, path, hr, middleware...)
+}
+
+// HEADメソッドのルートを追加する。
+func (rt *Router) Head(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodHead, path, hr, middleware...)
+}
+
+// OPTIONSメソッドのルートを追加する。
+func (rt *Router) Options(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(http.MethodOptions, path, hr, middleware...)
+}
+
+// すべてのメソッドに対応するルートを追加する。
+func (rt *Router) Any(path string, hr Handler, middleware ...Middleware) *Route {
+	return rt.Handle(methodAny, path, hr, middleware...)
+}
+
+// 別のルーターをプレフィックスを指定して追加する。
+// subのルートには、このルーターのミドルウェア、subのミドルウェアの順にミドルウェアが実行される。
+func (rt *Router) Mount(prefix string, sub *Router) {
+	mustValidPrefix(prefix)
+	rt.children = append(rt.children, routerChild{prefix: prefix, router: sub})
+}
+
+// ルーターのルートをプレフィックスを付与してサーバーへ登録する。
+// プレフィックスは"/"で始まり、"/"で終わらない必要がある。(例: /admin)
+// 同じルーターを複数回マウントするとpanicとなる。
+func Mount(prefix string, rt *Router) {
+	mustNotStarted("Mount")
+	mustValidPrefix(prefix)
+	rt.mount(prefix, nil, nil)
+}
+
+func (rt *Router) mount(prefix string, parentMiddleware []Middleware, parentRecover func(w http.ResponseWriter, r *http.Request, rv any)) {
+	if rt.mounted {
+		panic("router is already mounted")
+	}
+	rt.mounted = true
+
+	middleware := append(append([]Middleware{}, parentMiddleware...), rt.middleware...)
+	rec := rt.recover
+	if rec == nil {
+		rec = parentRecover
+	}
+	for _, r := range rt.routes {
+		ru := r.ru
+		ru.pattern = joinPrefix(prefix, ru.pattern)
+		ru.middleware = append(append([]Middleware{}, middleware...), ru.middleware...)
+		if ru.recover == nil {
+			ru.recover = rec
+		}
+		registerRoute(r.method, ru)
+	}
+	for _, child := range rt.children {
+		child.router.mount(prefix+child.prefix, middleware, rec)
+	}
+	if rt.notFound != nil {
+		registerNotFound(prefix, rt.notFound, middleware).recover = rec
+	}
+}
+
+// プレフィックス配下でルートにマッチしなかったリクエストのハンドラを設定する。
+// 例えば/apiはJSON、/appはHTMLの404を返すように、プレフィックスごとにレスポンスを変える場合に利用する。
+// プレフィックスが重なる場合は、長いプレフィックスのハンドラが優先される。
+// プレフィックスに"/"を指定するとすべてのパスが対象となる。
+// どのプレフィックスにも該当しない場合は、共通の404(SetNoMethodResponse)となる。
+//
+//	server.NotFound("/api", func(w http.ResponseWriter, r *http.Request) {
+//		server.SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": "not found"})
+//	})
+func NotFound(prefix string, hr Handler, middleware ...Middleware) {
+	mustNotStarted("NotFound")
+	if prefix == "/" {
+		prefix = ""
+	} else {
+		mustValidPrefix(prefix)
+	}
+	registerNotFound(prefix, hr, middleware)
+}
+
+func registerNotFound(prefix string, hr Handler, middleware []Middleware) *route {
+	ru := &route{
+		pattern:    prefix + "/*path",
+		handler:    hr,
+		middleware: middleware,
+		notFound:   true,
+	}
+	registerRoute(methodAny, ru)
+	return ru
+}
+
+func joinPrefix(prefix string, path string) string {
+	if path == "/" {
+		return prefix
+	}
+	return prefix + path
+}
+
+func mustValidPrefix(prefix string) {
+	if !strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") {
+		panic(fmt.Sprintf(PanicInvalidPattern, prefix))
+	}
+	parsePattern(prefix)
+}
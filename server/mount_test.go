@@ -0,0 +1,214 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestMount$ ./server
+func TestMount(t *testing.T) {
+	resetSetting()
+
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Method+" "+r.Pattern))
+	}
+
+	admin := NewRouter(mark("admin"))
+	admin.Get("/", echo)
+	admin.Get("/users/:id", echo, mark("route")).Description("ユーザーの取得")
+	admin.Use(mark("admin2"))
+	admin.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusNotFound, []byte("admin not found: "+getPathParamVal(r, "path")))
+	})
+
+	reports := NewRouter(mark("reports"))
+	reports.Delete("/:reportID", echo)
+	admin.Mount("/reports", reports)
+
+	Mount("/admin", admin)
+	Get("/users/:id", echo)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+		order  string
+	}{
+		{"成功：プレフィックスそのもの", http.MethodGet, "/admin", http.StatusOK, "GET /admin", "admin,admin2"},
+		{"成功：ルーターのミドルウェアの後にルートのミドルウェア", http.MethodGet, "/admin/users/1", http.StatusOK, "GET /admin/users/:id", "admin,admin2,route"},
+		{"成功：入れ子のルーター", http.MethodDelete, "/admin/reports/1", http.StatusOK, "DELETE /admin/reports/:reportID", "admin,admin2,reports"},
+		{"成功：ルーターの404", http.MethodGet, "/admin/unknown/path", http.StatusNotFound, "admin not found: unknown/path", "admin,admin2"},
+		{"成功：メソッドが異なる場合もルーターの404", http.MethodPost, "/admin/users/1", http.StatusNotFound, "admin not found: users/1", "admin,admin2"},
+		{"成功：プレフィックス外は共通の404", http.MethodGet, "/unknown", http.StatusNotFound, string(noMethodResponse), ""},
+		{"成功：プレフィックス外のルートにはミドルウェアが適用されない", http.MethodGet, "/users/1", http.StatusOK, "GET /users/:id", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order = nil
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+			testutil.AssertEqual(t, strings.Join(order, ","), tt.order)
+		})
+	}
+
+	t.Run("成功：Routeの設定が引き継がれる", func(t *testing.T) {
+		testutil.AssertEqual(t, patternIndex[http.MethodGet+" /admin/users/:id"].description, "ユーザーの取得")
+	})
+
+	t.Run("失敗：同じルーターを再度マウントするとpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any("router is already mounted"))
+		}()
+		Mount("/admin2", admin)
+	})
+
+	for _, prefix := range []string{"admin", "/admin/", ""} {
+		t.Run("失敗：不正なプレフィックスはpanic("+prefix+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidPattern, prefix)))
+			}()
+			Mount(prefix, NewRouter())
+		})
+	}
+
+	t.Run("失敗：既存のルートと重複するとpanic", func(t *testing.T) {
+		dup := NewRouter()
+		dup.Get("/:id", echo)
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicSameRoot, "/users/:id")))
+		}()
+		Mount("/users", dup)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestNotFound$ ./server
+func TestNotFound(t *testing.T) {
+	resetSetting()
+
+	Get("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("users"))
+	})
+	NotFound("/api", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": "api not found"})
+	})
+	NotFound("/api/v2", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": "v2 not found"})
+	})
+	NotFound("/app", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypeHTMLWithCharset, http.StatusNotFound, []byte("<h1>not found</h1>"))
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"成功：ルートがある場合はルート", http.MethodGet, "/api/users", http.StatusOK, ContentTypePlainText, "users"},
+		{"成功：JSONの404", http.MethodGet, "/api/unknown", http.StatusNotFound, ContentTypeJSON, `{"message":"api not found"}`},
+		{"成功：メソッドが異なる場合も対象", http.MethodDelete, "/api/users", http.StatusNotFound, ContentTypeJSON, `{"message":"api not found"}`},
+		{"成功：長いプレフィックスが優先", http.MethodGet, "/api/v2/x", http.StatusNotFound, ContentTypeJSON, `{"message":"v2 not found"}`},
+		{"成功：HTMLの404", http.MethodGet, "/app/page", http.StatusNotFound, ContentTypeHTMLWithCharset, "<h1>not found</h1>"},
+		{"成功：プレフィックス外は共通の404", http.MethodGet, "/other", http.StatusNotFound, noMethodContentType, string(noMethodResponse)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Header().Get("Content-Type"), tt.contentType)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("成功：/はすべてのパスが対象", func(t *testing.T) {
+		NotFound("/", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusNotFound, []byte("root not found"))
+		})
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+		testutil.AssertEqual(t, w.Body.String(), "root not found")
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouterRecover$ ./server
+func TestRouterRecover(t *testing.T) {
+	resetSetting()
+
+	panicHandler := func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	}
+	api := NewRouter()
+	api.Get("/users", panicHandler)
+	api.Get("/payments", panicHandler).Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+		SetResponseAsJson(w, r, http.StatusInternalServerError, map[string]string{"code": "PAYMENT_UNKNOWN"})
+	})
+	api.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	})
+	api.Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+		SetResponseAsJson(w, r, http.StatusInternalServerError, map[string]string{"message": "api error"})
+	})
+	v2 := NewRouter()
+	v2.Get("/users", panicHandler)
+	api.Mount("/v2", v2)
+	legacy := NewRouter()
+	legacy.Get("/users", panicHandler)
+	legacy.Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+		SetResponseAsJson(w, r, http.StatusInternalServerError, map[string]string{"message": "legacy error"})
+	})
+	api.Mount("/legacy", legacy)
+	Mount("/api", api)
+
+	web := NewRouter()
+	web.Get("/page", panicHandler)
+	web.Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+		SetResponse(w, r, ContentTypeHTMLWithCharset, http.StatusInternalServerError, []byte("<h1>error</h1>"))
+	})
+	Mount("/web", web)
+	Get("/other", panicHandler)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+	}{
+		{"成功：ルーターのレスポンス(JSON)", "/api/users", ContentTypeJSON, `{"message":"api error"}`},
+		{"成功：ルーターのレスポンス(HTML)", "/web/page", ContentTypeHTMLWithCharset, "<h1>error</h1>"},
+		{"成功：Route.Recoverが優先", "/api/payments", ContentTypeJSON, `{"code":"PAYMENT_UNKNOWN"}`},
+		{"成功：入れ子のルーターに引き継がれる", "/api/v2/users", ContentTypeJSON, `{"message":"api error"}`},
+		{"成功：入れ子のルーターの設定が優先", "/api/legacy/users", ContentTypeJSON, `{"message":"legacy error"}`},
+		{"成功：ルーターの404のハンドラも対象", "/api/unknown", ContentTypeJSON, `{"message":"api error"}`},
+		{"成功：ルーター外は共通の500", "/other", internalServerErrorContentType, string(internalServerErrorResponse)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+			testutil.AssertEqual(t, w.Header().Get("Content-Type"), tt.contentType)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+}
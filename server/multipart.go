@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// multipart/form-dataのリクエストで、メモリに保持する最大のバイト数のデフォルト
+// (http.Request.ParseMultipartFormのデフォルトと同じ)
+const DefaultMultipartMaxMemory int64 = 32 << 20
+
+var multipartMaxMemory = DefaultMultipartMaxMemory
+
+// Bindでmultipart/form-dataのリクエストをパースする際に、メモリに保持する最大のバイト数を設定する。
+// 超えた分のファイルは一時ファイルに保存され、リクエストの処理の完了後に削除される。
+// (リクエストボディ全体のサイズの制限にはBodyLimitMiddlewareを利用する)
+func SetMultipartMaxMemory(n int64) {
+	mustNotStarted("SetMultipartMaxMemory")
+	multipartMaxMemory = n
+}
+
+func isMultipartRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), ContentTypeMultipart)
+}
+
+// multipart/form-dataのリクエストボディをパースする。
+// 既にパースしている場合は何もしない。
+func parseMultipartForm(r *http.Request) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+	if err := r.ParseMultipartForm(multipartMaxMemory); err != nil {
+		return err
+	}
+	// ミドルウェアでリクエストを複製している場合は、net/httpによる一時ファイルの削除の対象にならないため、
+	// リクエストの処理の完了(contextのキャンセル)時に削除する。
+	form := r.MultipartForm
+	context.AfterFunc(r.Context(), func() {
+		form.RemoveAll()
+	})
+	return nil
+}
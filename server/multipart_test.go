@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// テスト用のファイル
+type testMultipartFile struct {
+	field    string
+	filename string
+	content  string
+}
+
+// multipart/form-dataのリクエストを作成する。
+func newMultipartRequest(t *testing.T, fields [][2]string, files []testMultipartFile) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, f := range fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		fw, err := mw.CreateFormFile(f.field, f.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload?page=2", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindMultipart$ ./server
+func TestBindMultipart(t *testing.T) {
+	type uploadRequest struct {
+		Title   string  `form:"title"`
+		Count   int     `form:"count"`
+		Comment *string `form:"comment"`
+		Page    int     `query:"page"`
+	}
+
+	t.Run("成功：テキストのフィールドをbindする", func(t *testing.T) {
+		resetSetting()
+		req := newMultipartRequest(t, [][2]string{{"title", "report"}, {"count", "3"}}, []testMultipartFile{{"file", "a.txt", "hello"}})
+		var result uploadRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, result.Title, "report")
+		testutil.AssertEqual(t, result.Count, 3)
+		testutil.AssertEqual(t, result.Comment, (*string)(nil))
+		testutil.AssertEqual(t, result.Page, 2)
+
+		// パースした結果を再利用して、再度bindできる
+		var again uploadRequest
+		testutil.AssertEqual(t, Bind(req, &again), nil)
+		testutil.AssertEqual(t, again.Title, "report")
+	})
+
+	t.Run("成功：メモリの上限を超えたファイルがあってもbindできる", func(t *testing.T) {
+		resetSetting()
+		SetMultipartMaxMemory(1)
+		req := newMultipartRequest(t, [][2]string{{"title", "large"}}, []testMultipartFile{{"file", "large.bin", strings.Repeat("x", 1024)}})
+		var result uploadRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, result.Title, "large")
+		testutil.AssertEqual(t, len(req.MultipartForm.File["file"]), 1)
+	})
+
+	t.Run("失敗：値の形式が不正", func(t *testing.T) {
+		resetSetting()
+		req := newMultipartRequest(t, [][2]string{{"count", "x"}}, nil)
+		var result uploadRequest
+		var formatErr *ErrRequestFieldFormat
+		testutil.AssertEqual(t, errors.As(Bind(req, &result), &formatErr), true)
+		testutil.AssertEqual(t, formatErr.Field, "count")
+	})
+
+	t.Run("失敗：ボディの形式が不正", func(t *testing.T) {
+		resetSetting()
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("invalid"))
+		req.Header.Set("Content-Type", ContentTypeMultipart+"; boundary=xxx")
+		var result uploadRequest
+		var parseErr *ErrRequestFormParse
+		testutil.AssertEqual(t, errors.As(Bind(req, &result), &parseErr), true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestBindMultipartFile$ ./server
+func TestBindMultipartFile(t *testing.T) {
+	type uploadRequest struct {
+		Name        string                  `form:"name"`
+		Avatar      *multipart.FileHeader   `file:"avatar"`
+		Attachments []*multipart.FileHeader `file:"attachments"`
+		Cover       *multipart.FileHeader   `file:"cover"`
+	}
+	readFile := func(t *testing.T, fh *multipart.FileHeader) string {
+		t.Helper()
+		f, err := fh.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		return string(b)
+	}
+
+	t.Run("成功：ファイルをbindする", func(t *testing.T) {
+		resetSetting()
+		req := newMultipartRequest(t, [][2]string{{"name", "taro"}}, []testMultipartFile{
+			{"avatar", "me.png", "png"},
+			{"attachments", "a.txt", "a"},
+			{"attachments", "b.txt", "b"},
+		})
+		var result uploadRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, result.Name, "taro")
+		testutil.AssertEqual(t, result.Avatar.Filename, "me.png")
+		testutil.AssertEqual(t, result.Avatar.Size, int64(3))
+		testutil.AssertEqual(t, readFile(t, result.Avatar), "png")
+		testutil.AssertEqual(t, len(result.Attachments), 2)
+		testutil.AssertEqual(t, result.Attachments[0].Filename+","+result.Attachments[1].Filename, "a.txt,b.txt")
+		testutil.AssertEqual(t, readFile(t, result.Attachments[1]), "b")
+		// ファイルが無い場合は何もセットされない
+		testutil.AssertEqual(t, result.Cover, (*multipart.FileHeader)(nil))
+	})
+
+	t.Run("成功：メモリの上限を超えたファイル", func(t *testing.T) {
+		resetSetting()
+		SetMultipartMaxMemory(1)
+		content := strings.Repeat("x", 1024)
+		req := newMultipartRequest(t, nil, []testMultipartFile{{"avatar", "large.png", content}})
+		var result uploadRequest
+		testutil.AssertEqual(t, Bind(req, &result), nil)
+		testutil.AssertEqual(t, readFile(t, result.Avatar), content)
+	})
+
+	t.Run("失敗：multipart以外のリクエストはpanic", func(t *testing.T) {
+		resetSetting()
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		defer func() {
+			testutil.AssertEqual(t, recover(), any("file tag is only available in multipart request"))
+		}()
+		var result struct {
+			Avatar *multipart.FileHeader `file:"avatar"`
+		}
+		Bind(req, &result)
+	})
+
+	t.Run("失敗：フィールドの型が不正な場合はpanic", func(t *testing.T) {
+		resetSetting()
+		req := newMultipartRequest(t, nil, []testMultipartFile{{"avatar", "me.png", "png"}})
+		defer func() {
+			testutil.AssertEqual(t, recover(), any("file tag field must be *multipart.FileHeader or []*multipart.FileHeader: Avatar"))
+		}()
+		var result struct {
+			Avatar string `file:"avatar"`
+		}
+		Bind(req, &result)
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import "net/http"
+
+// 一括処理(バッチ)のエンドポイントで、項目ごとの結果を返すためのレスポンス
+// Succeed、Failで項目の結果を追加し、SetMultiStatusResponseで返す。
+//
+//	var ms server.MultiStatus
+//	for _, item := range req.Items {
+//		if err := save(item); err != nil {
+//			ms.Fail(item.ID, http.StatusConflict, err)
+//			continue
+//		}
+//		ms.Succeed(item.ID, http.StatusCreated, item)
+//	}
+//	server.SetMultiStatusResponse(w, r, &ms)
+type MultiStatus struct {
+	// 成功した項目の数
+	Succeeded int `json:"succeeded"`
+	// 失敗した項目の数
+	Failed int          `json:"failed"`
+	Items  []ItemStatus `json:"items"`
+}
+
+// 項目ごとの結果
+type ItemStatus struct {
+	// リクエストの項目を識別する値(IDや添字など)
+	ID string `json:"id"`
+	// 項目を単独で処理した場合のステータスコード
+	Status int `json:"status"`
+	// 成功した場合のデータ
+	Data any `json:"data,omitempty"`
+	// 失敗した場合のエラー
+	Error *ItemError `json:"error,omitempty"`
+}
+
+type ItemError struct {
+	Message string `json:"message"`
+}
+
+// 成功した項目の結果を追加する。
+func (m *MultiStatus) Succeed(id string, statusCode int, data any) {
+	m.Succeeded++
+	m.Items = append(m.Items, ItemStatus{ID: id, Status: statusCode, Data: data})
+}
+
+// 失敗した項目の結果を追加する。errのメッセージがレスポンスに含まれる。
+func (m *MultiStatus) Fail(id string, statusCode int, err error) {
+	m.Failed++
+	m.Items = append(m.Items, ItemStatus{ID: id, Status: statusCode, Error: &ItemError{Message: err.Error()}})
+}
+
+// 一括処理の結果をJSONで返す。
+// すべての項目が成功した場合(項目が無い場合を含む)は200、
+// 1つでも失敗した項目がある場合は207(Multi-Status)を返し、クライアントは項目ごとのstatusで結果を判断する。
+func SetMultiStatusResponse(w http.ResponseWriter, r *http.Request, m *MultiStatus) {
+	statusCode := http.StatusOK
+	if m.Failed > 0 {
+		statusCode = http.StatusMultiStatus
+	}
+	if m.Items == nil {
+		m.Items = []ItemStatus{}
+	}
+	SetResponseAsJson(w, r, statusCode, m)
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestSetMultiStatusResponse$ ./server
+func TestSetMultiStatusResponse(t *testing.T) {
+	t.Run("成功：失敗した項目がある場合は207", func(t *testing.T) {
+		var ms MultiStatus
+		ms.Succeed("1", http.StatusCreated, map[string]int{"id": 1})
+		ms.Fail("2", http.StatusConflict, errors.New("already exists"))
+		w := httptest.NewRecorder()
+		SetMultiStatusResponse(w, httptest.NewRequest(http.MethodPost, "/batch", nil), &ms)
+		testutil.AssertEqual(t, w.Code, http.StatusMultiStatus)
+		testutil.AssertEqual(t, w.Body.String(), `{"succeeded":1,"failed":1,"items":[{"id":"1","status":201,"data":{"id":1}},{"id":"2","status":409,"error":{"message":"already exists"}}]}`)
+	})
+
+	t.Run("成功：すべて成功した場合は200", func(t *testing.T) {
+		var ms MultiStatus
+		ms.Succeed("1", http.StatusCreated, nil)
+		w := httptest.NewRecorder()
+		SetMultiStatusResponse(w, httptest.NewRequest(http.MethodPost, "/batch", nil), &ms)
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), `{"succeeded":1,"failed":0,"items":[{"id":"1","status":201}]}`)
+	})
+
+	t.Run("成功：項目が無い場合は空の配列", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		SetMultiStatusResponse(w, httptest.NewRequest(http.MethodPost, "/batch", nil), &MultiStatus{})
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), `{"succeeded":0,"failed":0,"items":[]}`)
+	})
+}
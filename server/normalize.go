@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var normalizePath = false
+
+// ルーティングの前にリクエストのパスを正規化するかを設定する。
+// 有効にすると、連続する"/"をまとめ、"."、".."のセグメントを解決したパスでルートを探す。(例: /a/./b/../c -> /a/c)
+// 末尾の"/"は維持される。ハンドラのr.URL.Pathも正規化したパスとなる。
+// エスケープされた"/"、"."(%2F、%2E)はパスの区切り、セグメントとして扱わずにそのまま維持される。
+// デフォルトは無効(厳密にパスを比較する)。
+func SetPathNormalization(enabled bool) {
+	mustNotStarted("SetPathNormalization")
+	normalizePath = enabled
+}
+
+// パスの正規化が有効で、パスが正規化されていない場合は、パスを正規化したリクエストを返す。
+// エスケープされた"/"(%2F)をパスの区切りとして扱わないように、エスケープされたパスで正規化する。
+func normalizeRequestPath(r *http.Request) *http.Request {
+	if !normalizePath {
+		return r
+	}
+	escaped := r.URL.EscapedPath()
+	raw := cleanPath(escaped)
+	if raw == escaped {
+		return r
+	}
+	p, err := url.PathUnescape(raw)
+	if err != nil {
+		return r
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path = p
+	u.RawPath = raw
+	r2.URL = &u
+	return r2
+}
+
+// 連続する"/"と"."、".."のセグメントを解決したパスを返す。末尾の"/"は維持する。
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if !strings.Contains(p, "//") && !strings.Contains(p, "/.") && p[0] == '/' {
+		return p
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestPathNormalization$ ./server
+func TestPathNormalization(t *testing.T) {
+	setup := func(enabled bool) {
+		resetSetting()
+		SetPathNormalization(enabled)
+		echo := func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+r.URL.Path))
+		}
+		Get("/", echo)
+		Get("/a/c", echo)
+		Get("/docs/", echo)
+	}
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("成功：無効の場合は正規化しない", func(t *testing.T) {
+		setup(false)
+		testutil.AssertEqual(t, do("/a/./b/../c").Code, http.StatusNotFound)
+		testutil.AssertEqual(t, do("/////").Code, http.StatusNotFound)
+	})
+
+	for _, tt := range []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{"成功：連続する/", "/////", "/ /"},
+		{"成功：.と..", "/a/./b/../c", "/a/c /a/c"},
+		{"成功：連続する/と..", "//a//b/..//c", "/a/c /a/c"},
+		{"成功：末尾の/は維持", "/docs//./", "/docs/ /docs/"},
+		{"成功：ルートより上には行かない", "/../../a/c", "/a/c /a/c"},
+		{"成功：正規化済み", "/a/c", "/a/c /a/c"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(true)
+			w := do(tt.target)
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("成功：エスケープされた/は区切りとして扱わない", func(t *testing.T) {
+		setup(true)
+		Get("/files/:name", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(getPathParamVal(r, "name")+" "+r.URL.EscapedPath()))
+		})
+		w := do("/files//a%2F..%2Fb")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), "a/../b /files/a%2F..%2Fb")
+	})
+
+	t.Run("成功：末尾の/の有無は変えない", func(t *testing.T) {
+		setup(true)
+		testutil.AssertEqual(t, do("/a/c/").Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// OPTIONSのハンドラが登録されていないパスへのOPTIONSリクエストに応答する関数
+// allowedはパスに登録されているメソッド(OPTIONSを含む)で、ソートされている。
+// nilの場合は自動で応答せず、通常のルートが無い場合と同じ扱いとなる。
+var autoOptionsHandler func(w http.ResponseWriter, r *http.Request, allowed []string) = DefaultAutoOptionsHandler
+
+// 自動のOPTIONSのレスポンスのデフォルト
+// Allowヘッダーに許可されたメソッドを設定して204を返す。
+func DefaultAutoOptionsHandler(w http.ResponseWriter, r *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 自動のOPTIONSのレスポンスを返す関数を設定する。
+// CORSのプリフライトのレスポンスのように、ヘッダーを追加する場合に利用する。
+// nilを設定すると自動のOPTIONSのレスポンスを無効にする。
+//
+//	server.SetAutoOptionsHandler(func(w http.ResponseWriter, r *http.Request, allowed []string) {
+//		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+//		server.DefaultAutoOptionsHandler(w, r, allowed)
+//	})
+func SetAutoOptionsHandler(f func(w http.ResponseWriter, r *http.Request, allowed []string)) {
+	mustNotStarted("SetAutoOptionsHandler")
+	autoOptionsHandler = f
+}
+
+// パスに登録されているメソッドをソートして返す。
+// ルートがある場合はOPTIONSを含む。Anyで登録したルートは含まない。
+// ミドルウェアからも利用できる(CORSのAccess-Control-Allow-Methodsの設定など)。
+func AllowedMethods(r *http.Request) []string {
+	return allowedMethods(routingPath(r))
+}
+
+func allowedMethods(path string) []string {
+	if path == "" || path[0] != '/' {
+		return nil
+	}
+	var allowed []string
+	for method, root := range router {
+		if method == methodAny {
+			continue
+		}
+		var ps pathParamValues
+		if ru := root.lookup(path[1:], &ps); ru != nil && !ru.notFound {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	if !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	slices.Sort(allowed)
+	return allowed
+}
+
+// 自動のOPTIONSのレスポンスを返した場合はtrueを返す。
+// OPTIONSのルートが無い(またはRouter.NotFoundのルートのみ)場合に、パスに他のメソッドのルートがあれば応答する。
+func serveAutoOptions(w http.ResponseWriter, r *http.Request, ru *route) bool {
+	if r.Method != http.MethodOptions || autoOptionsHandler == nil || (ru != nil && !ru.notFound) {
+		return false
+	}
+	allowed := allowedMethods(routingPath(r))
+	if len(allowed) == 0 {
+		return false
+	}
+	autoOptionsHandler(w, r, allowed)
+	return true
+}
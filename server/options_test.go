@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestAutoOptions$ ./server
+func TestAutoOptions(t *testing.T) {
+	resetSetting()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Method))
+	}
+	Get("/items/:id", handler)
+	Delete("/items/:id", handler)
+	Put("/items/:id<int>", handler)
+	Options("/explicit", handler)
+	Get("/explicit", handler)
+	admin := NewRouter()
+	admin.Post("/users", handler)
+	admin.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusNotFound, []byte("admin not found"))
+	})
+	Mount("/admin", admin)
+
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodOptions, path, nil))
+		return w
+	}
+
+	t.Run("成功：登録されているメソッドを返す", func(t *testing.T) {
+		w := do("/items/abc")
+		testutil.AssertEqual(t, w.Code, http.StatusNoContent)
+		testutil.AssertEqual(t, w.Header().Get("Allow"), "DELETE, GET, OPTIONS")
+	})
+
+	t.Run("成功：パスパラメータの制約も考慮される", func(t *testing.T) {
+		testutil.AssertEqual(t, do("/items/1").Header().Get("Allow"), "DELETE, GET, OPTIONS, PUT")
+	})
+
+	t.Run("成功：OPTIONSのハンドラがある場合はハンドラを実行", func(t *testing.T) {
+		w := do("/explicit")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), http.MethodOptions)
+	})
+
+	t.Run("成功：RouterのNotFoundより優先される", func(t *testing.T) {
+		w := do("/admin/users")
+		testutil.AssertEqual(t, w.Code, http.StatusNoContent)
+		testutil.AssertEqual(t, w.Header().Get("Allow"), "OPTIONS, POST")
+	})
+
+	t.Run("失敗：ルートの無いパス", func(t *testing.T) {
+		testutil.AssertEqual(t, do("/unknown").Code, http.StatusNotFound)
+		testutil.AssertEqual(t, do("/admin/unknown").Body.String(), "admin not found")
+	})
+
+	t.Run("成功：ミドルウェアからメソッドを取得できる", func(t *testing.T) {
+		testutil.AssertEqual(t, strings.Join(AllowedMethods(httptest.NewRequest(http.MethodGet, "/explicit", nil)), ","), "GET,OPTIONS")
+	})
+
+	t.Run("成功：レスポンスの変更", func(t *testing.T) {
+		autoOptionsHandler = func(w http.ResponseWriter, r *http.Request, allowed []string) {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+			DefaultAutoOptionsHandler(w, r, allowed)
+		}
+		defer func() { autoOptionsHandler = DefaultAutoOptionsHandler }()
+		w := do("/items/abc")
+		testutil.AssertEqual(t, w.Code, http.StatusNoContent)
+		testutil.AssertEqual(t, w.Header().Get("Access-Control-Allow-Methods"), "DELETE, GET, OPTIONS")
+	})
+
+	t.Run("成功：無効にする", func(t *testing.T) {
+		SetAutoOptionsHandler(nil)
+		defer func() { autoOptionsHandler = DefaultAutoOptionsHandler }()
+		testutil.AssertEqual(t, do("/items/abc").Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+
+[個人情報(PII)のログからの除外について]
+構造体のフィールドに`pii:"true"`タグを指定すると、そのフィールドの名前
+("json"、"param"、"query"、"form"、"header"タグの値)は個人情報として扱われ、
+以下のログ出力の際に値が"***"に置き換えられる。
+・DebugDumpMiddlewareのヘッダー、クエリー、ボディ(json、form)
+・AccessLogMiddlewareのクエリー
+・Bindが返すエラーのメッセージ(ErrRequestJsonSyntaxErrorなどのJson、フィールドの値)
+
+	type createUserRequest struct {
+		Name  string `json:"name"`
+		Email string `json:"email" pii:"true"`
+	}
+
+Bindに渡された構造体の型は自動で登録される。
+最初のBindより前のリクエストも対象とするには、起動前にRegisterPIIで型を登録する。
+フィールドの名前で判定するため、同じ名前のフィールドは型に関わらず置き換えられる。
+
+*/
+
+// 個人情報の値を置き換える文字列
+const piiMaskedValue = "***"
+
+// 個人情報として扱うフィールドの名前
+var piiFields = struct {
+	sync.RWMutex
+	names map[string]struct{}
+}{names: map[string]struct{}{}}
+
+// 登録済みの型
+// キーはreflect.Type、バリューはstruct{}
+var piiTypeCache sync.Map
+
+// `pii:"true"`タグを含む構造体を登録する。
+// 構造体の値またはポインタを渡す。入れ子の構造体も対象となる。
+// サーバーの起動前に呼び出す必要がある。
+//
+//	server.RegisterPII(createUserRequest{}, &userResponse{})
+func RegisterPII(v ...any) {
+	mustNotStarted("RegisterPII")
+	for _, val := range v {
+		registerPIIType(reflect.TypeOf(val))
+	}
+}
+
+func registerPIIType(rt reflect.Type) {
+	if rt == nil {
+		return
+	}
+	for rt.Kind() == reflect.Pointer || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array || rt.Kind() == reflect.Map {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return
+	}
+	if _, loaded := piiTypeCache.LoadOrStore(rt, struct{}{}); loaded {
+		return
+	}
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if f.Tag.Get("pii") == "true" {
+			piiFields.Lock()
+			for _, key := range []string{"json", "param", "query", "form", "header"} {
+				if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+					if key == "header" {
+						// http.Headerのキーと比較するため
+						name = http.CanonicalHeaderKey(name)
+					}
+					piiFields.names[name] = struct{}{}
+				}
+			}
+			piiFields.Unlock()
+		}
+		registerPIIType(f.Type)
+	}
+}
+
+func hasPIIFields() bool {
+	piiFields.RLock()
+	defer piiFields.RUnlock()
+	return len(piiFields.names) > 0
+}
+
+func isPIIField(name string) bool {
+	piiFields.RLock()
+	defer piiFields.RUnlock()
+	_, ok := piiFields.names[name]
+	return ok
+}
+
+// 個人情報のキーの値を置き換えたurl.Valuesを返す。
+func scrubPIIValues(vals url.Values) url.Values {
+	scrubbed := make(url.Values, len(vals))
+	for k, v := range vals {
+		if isPIIField(k) {
+			v = []string{piiMaskedValue}
+		}
+		scrubbed[k] = v
+	}
+	return scrubbed
+}
+
+// ログに出力するためのリクエストURI(パスとクエリー)を返す。
+func scrubbedRequestURI(r *http.Request) string {
+	if r.URL.RawQuery == "" || !hasPIIFields() {
+		return r.URL.RequestURI()
+	}
+	u := *r.URL
+	u.RawQuery = scrubPIIValues(u.Query()).Encode()
+	return u.RequestURI()
+}
+
+// Content-Typeに応じてボディの個人情報を置き換える。
+// jsonとformのみが対象で、それ以外はそのまま返す。
+func scrubPIIBody(contentType string, body string) string {
+	if body == "" || !hasPIIFields() {
+		return body
+	}
+	if strings.HasPrefix(contentType, ContentTypeFormURLEnc) {
+		vals, _ := url.ParseQuery(body)
+		return scrubPIIValues(vals).Encode()
+	}
+	return scrubPIIJson(body)
+}
+
+// jsonの個人情報のキーの値を置き換える。
+// 構文エラーのjsonも対象とするため、パースせずに文字列として走査する。
+func scrubPIIJson(s string) string {
+	if !hasPIIFields() {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := skipJsonString(s, i)
+		token := s[i:end]
+		b.WriteString(token)
+		i = end
+
+		// 文字列の直後が":"の場合はキーとして扱う。
+		j := skipJsonSpace(s, i)
+		if j >= len(s) || s[j] != ':' {
+			continue
+		}
+		key, err := strconv.Unquote(token)
+		if err != nil {
+			key = strings.Trim(token, `"`)
+		}
+		if !isPIIField(key) {
+			continue
+		}
+		k := skipJsonSpace(s, j+1)
+		b.WriteString(s[i:k])
+		b.WriteString(`"` + piiMaskedValue + `"`)
+		i = skipJsonValue(s, k)
+	}
+	return b.String()
+}
+
+func skipJsonSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// s[i]から始まる文字列の、閉じる'"'の次のインデックスを返す。
+func skipJsonString(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// s[i]から始まる値(文字列、オブジェクト、配列、その他)の次のインデックスを返す。
+func skipJsonValue(s string, i int) int {
+	if i >= len(s) {
+		return i
+	}
+	switch s[i] {
+	case '"':
+		return skipJsonString(s, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s); {
+			switch s[j] {
+			case '"':
+				j = skipJsonString(s, j)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+			j++
+		}
+		return len(s)
+	}
+	j := i
+	for j < len(s) && strings.IndexByte(",}] \t\r\n", s[j]) < 0 {
+		j++
+	}
+	return j
+}
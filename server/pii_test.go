@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+type piiAddress struct {
+	City   string `json:"city"`
+	Street string `json:"piiStreet" pii:"true"`
+}
+
+type piiRequest struct {
+	Name    string     `json:"name"`
+	Email   string     `json:"piiEmail" pii:"true"`
+	Address piiAddress `json:"address"`
+	Phone   string     `query:"piiPhone" pii:"true"`
+	Token   string     `header:"x-pii-token" pii:"true"`
+}
+
+type piiAgeRequest struct {
+	Age int `query:"piiAge" pii:"true"`
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestScrubPIIJson$ ./server
+func TestScrubPIIJson(t *testing.T) {
+	RegisterPII(piiRequest{})
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{`{"name":"a","piiEmail":"a@example.com"}`, `{"name":"a","piiEmail":"***"}`},
+		{`{"address": {"city":"x", "piiStreet" : "1-2-3"}}`, `{"address": {"city":"x", "piiStreet" : "***"}}`},
+		{`[{"piiEmail":{"a":["}"]},"name":"\"piiEmail\""}]`, `[{"piiEmail":"***","name":"\"piiEmail\""}]`},
+		{`{"piiEmail":12345}`, `{"piiEmail":"***"}`},
+		// 構文エラーのjsonも対象とする。
+		{`{"piiEmail":"a@example.com",,`, `{"piiEmail":"***",,`},
+	} {
+		testutil.AssertEqual(t, scrubPIIJson(tc.in), tc.out)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestPIIScrubbing$ ./server
+func TestPIIScrubbing(t *testing.T) {
+	resetSetting()
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+	SetCommonMiddleware(AccessLogMiddleware, DebugDumpMiddleware)
+	RegisterPII(piiRequest{})
+
+	Post("/user", func(w http.ResponseWriter, r *http.Request) {
+		var req piiRequest
+		if err := Bind(r, &req); err != nil {
+			l.Error(r.Context(), err.Error())
+			SetResponseAsJson(w, r, http.StatusBadRequest, createResponse(false, nil))
+			return
+		}
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, req.Email))
+	})
+	Get("/age", func(w http.ResponseWriter, r *http.Request) {
+		var req piiAgeRequest
+		if err := Bind(r, &req); err != nil {
+			l.Error(r.Context(), err.Error())
+		}
+	})
+
+	post := func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+	noSecret := func(t *testing.T, secret string) {
+		t.Helper()
+		for _, log := range lg.logs {
+			if strings.Contains(log, secret) {
+				t.Errorf("secret found in log: %s", log)
+			}
+		}
+	}
+
+	t.Run("成功：ダンプとアクセスログで置き換えられ、ハンドラでは元の値を取得できる", func(t *testing.T) {
+		res := post("/user?piiPhone=0901234&page=1", `{"name":"a","piiEmail":"a@example.com"}`)
+		testutil.AssertEqual(t, res.Body.String(), `{"is_success":true,"data":"a@example.com"}`)
+		noSecret(t, "a@example.com")
+		noSecret(t, "0901234")
+		if !lg.contains(`"piiEmail":"***"`) || !lg.contains("piiPhone=%2A%2A%2A") || !lg.contains("page=1") {
+			t.Errorf("unexpected logs: %v", lg.logs)
+		}
+	})
+
+	t.Run("成功：headerタグのヘッダーはダンプで置き換えられる", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"name":"a"}`))
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		req.Header.Set("X-Pii-Token", "secret-token")
+		HTTPHandler().ServeHTTP(httptest.NewRecorder(), req)
+		noSecret(t, "secret-token")
+		if !lg.contains("X-Pii-Token: ***") {
+			t.Errorf("unexpected logs: %v", lg.logs)
+		}
+	})
+
+	t.Run("成功：Bindのエラーでも置き換えられる", func(t *testing.T) {
+		post("/user", `{"piiEmail":"b@example.com",`)
+		noSecret(t, "b@example.com")
+
+		req := httptest.NewRequest(http.MethodGet, "/age?piiAge=secret-age", nil)
+		HTTPHandler().ServeHTTP(httptest.NewRecorder(), req)
+		noSecret(t, "secret-age")
+		var piiAge piiAgeRequest
+		err := Bind(req, &piiAge)
+		if !errors.Is(err, errPIIFieldFormat) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+)
+
+// 認証、メトリクス、管理画面などの機能をまとめてサーバーに組み込むためのインターフェース
+// RegisterPluginで登録する。
+type Plugin interface {
+	// プラグインの名前。登録済みのプラグインと重複してはいけない。
+	Name() string
+	// 登録時に一度だけ呼ばれる。エラーの場合は登録されない。
+	Init() error
+	// プラグインが提供するルート
+	Routes() []PluginRoute
+	// プラグインが提供するミドルウェア
+	// 共通のミドルウェアの後、ルーティング処理の前に実行される。
+	Middleware() []Middleware
+	// サーバーのシャットダウン後に呼ばれる。
+	Shutdown(c context.Context) error
+}
+
+// プラグインが提供するルート
+type PluginRoute struct {
+	Method     string
+	Path       string
+	Handler    Handler
+	Middleware []Middleware
+}
+
+var (
+	plugins = []Plugin{}
+
+	// 登録されたプラグインのミドルウェア(登録順)
+	pluginMiddleware = []Middleware{}
+)
+
+// プラグインを登録する。
+// サーバーの起動前に呼び出す必要がある。
+// Initが成功した場合に、ルートとミドルウェアが設定される。
+// ルートが既に存在する場合、メソッドが不正な場合は他のルートの登録(Handle)と同様にpanicとなる。
+func RegisterPlugin(p Plugin) error {
+	mustNotStarted("RegisterPlugin")
+	for _, registered := range plugins {
+		if registered.Name() == p.Name() {
+			return fmt.Errorf("plugin %s is already registered", p.Name())
+		}
+	}
+	if err := p.Init(); err != nil {
+		return fmt.Errorf("failed to init plugin %s: %w", p.Name(), err)
+	}
+
+	for _, ru := range p.Routes() {
+		Handle(ru.Method, ru.Path, ru.Handler, ru.Middleware...)
+	}
+	pluginMiddleware = append(pluginMiddleware, p.Middleware()...)
+	plugins = append(plugins, p)
+	return nil
+}
+
+// 登録と逆の順番でプラグインのShutdownを実行する。
+// エラーはログに出力し、残りのプラグインのShutdownは継続する。
+func shutdownPlugins(c context.Context) {
+	for i := len(plugins) - 1; i >= 0; i-- {
+		if err := plugins[i].Shutdown(c); err != nil {
+			l.Error(c, fmt.Sprintf("failed to shutdown plugin %s: %s", plugins[i].Name(), err))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+type testPlugin struct {
+	name string
+	// ルートのメソッド。空の場合はGET
+	method   string
+	initErr  error
+	shutdown bool
+}
+
+func (p *testPlugin) Name() string { return p.name }
+
+func (p *testPlugin) Init() error { return p.initErr }
+
+func (p *testPlugin) Routes() []PluginRoute {
+	return []PluginRoute{
+		{
+			Method: cmp.Or(p.method, http.MethodGet),
+			Path:   "/" + p.name,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				SetResponseAsJson(w, r, http.StatusOK, createResponse(true, r.Header.Get("X-Plugin")))
+			},
+		},
+	}
+}
+
+func (p *testPlugin) Middleware() []Middleware {
+	return []Middleware{
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Set("X-Plugin", r.Header.Get("X-Common")+p.name)
+				next.ServeHTTP(w, r)
+			})
+		},
+	}
+}
+
+func (p *testPlugin) Shutdown(c context.Context) error {
+	p.shutdown = true
+	return nil
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRegisterPlugin$ ./server
+func TestRegisterPlugin(t *testing.T) {
+	resetSetting()
+	defer resetSetting()
+	SetCommonMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Common", "common-")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	p := &testPlugin{name: "metrics"}
+	if err := RegisterPlugin(p); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	t.Run("成功：ルートとミドルウェアが設定される", func(t *testing.T) {
+		// 共通のミドルウェアの後にプラグインのミドルウェアが実行される。
+		execRequest(t, http.MethodGet, "/metrics", nil, nil, http.StatusOK, createResponse(true, "common-metrics"))
+	})
+
+	t.Run("失敗：同じ名前のプラグイン", func(t *testing.T) {
+		if err := RegisterPlugin(&testPlugin{name: "metrics"}); err == nil {
+			t.Error("should be error")
+		}
+	})
+
+	t.Run("失敗：Initのエラー", func(t *testing.T) {
+		initErr := errors.New("init error")
+		err := RegisterPlugin(&testPlugin{name: "auth", initErr: initErr})
+		if !errors.Is(err, initErr) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		testutil.AssertEqual(t, len(plugins), 1)
+	})
+
+	t.Run("失敗：不正なメソッドのルートはpanicとなる", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidMethod, "GET /")))
+		}()
+		RegisterPlugin(&testPlugin{name: "admin", method: "GET /"})
+	})
+
+	t.Run("成功：シャットダウン時にShutdownが呼ばれる", func(t *testing.T) {
+		go StartServer(context.Background(), "127.0.0.1", 8089)
+		time.Sleep(time.Millisecond * 100)
+		Shutdown()
+		testutil.AssertEqual(t, p.shutdown, true)
+	})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ハンドラの処理結果(SetCommonPostHandlerの関数へ渡される)
+type ResponseInfo struct {
+	// レスポンスのステータスコード(panicの場合は500)
+	StatusCode int
+	// レスポンスのボディのバイト数
+	Bytes int
+	// リクエストの処理時間(共通のミドルウェアを含む)
+	Duration time.Duration
+	// 書き込まれたレスポンスヘッダー(参照のみ。変更してもレスポンスには反映されない)
+	Header http.Header
+}
+
+var (
+	// ハンドラの処理の後に実行する関数
+	commonPostHandlers = []func(r *http.Request, res ResponseInfo){}
+
+	// レスポンスヘッダーの書き込みの直前に実行する関数
+	commonHeaderHooks = []func(r *http.Request, h http.Header, statusCode int){}
+)
+
+// ハンドラがレスポンスを書き込んだ後に実行する関数を設定する。先頭から順に実行されていく。
+// SetCommonAfterMiddlewareのミドルウェアはハンドラの前に実行されるが、こちらはハンドラ(と共通のミドルウェア)の処理の完了後に、
+// ステータスコード、バイト数などの結果を受け取って実行される。
+// ルートが無い場合(404)、ミドルウェアがレスポンスを返した場合、panicの場合(500)も実行される。
+// レスポンスは書き込み済みのため、レスポンスのログやメトリクスの記録に利用する。(ヘッダーの変更はSetCommonHeaderHookを利用する)
+//
+//	server.SetCommonPostHandler(func(r *http.Request, res server.ResponseInfo) {
+//		log.Printf("%s %s %d %dB %s", r.Method, server.RoutePattern(r), res.StatusCode, res.Bytes, res.Duration)
+//	})
+func SetCommonPostHandler(f ...func(r *http.Request, res ResponseInfo)) {
+	mustNotStarted("SetCommonPostHandler")
+	commonPostHandlers = f
+}
+
+// レスポンスヘッダーの書き込みの直前に実行する関数を設定する。先頭から順に実行されていく。
+// ハンドラが決定したステータスコードを受け取り、ヘッダーを追加・変更できる。(エラーのレスポンスのみにヘッダーを付与するなど)
+// ハンドラが何も書き込まなかった場合は、200としてハンドラの処理の後に実行される。
+//
+//	server.SetCommonHeaderHook(func(r *http.Request, h http.Header, statusCode int) {
+//		if statusCode >= 400 {
+//			h.Set("Cache-Control", "no-store")
+//		}
+//	})
+func SetCommonHeaderHook(f ...func(r *http.Request, h http.Header, statusCode int)) {
+	mustNotStarted("SetCommonHeaderHook")
+	commonHeaderHooks = f
+}
+
+// 後処理が設定されている場合に、ResponseWriterを包んで結果を記録する。
+// 戻り値の関数はハンドラの処理(panicのリカバリーを含む)の完了後に呼び出す。
+func startPostProcess(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	if len(commonPostHandlers) == 0 && len(commonHeaderHooks) == 0 {
+		return w, func() {}
+	}
+	start := time.Now()
+	pw := &postProcessWriter{ResponseWriter: w, r: r}
+	return pw, func() {
+		if pw.status == 0 {
+			// ハンドラが何も書き込まなかった場合もヘッダーの関数を実行する。
+			pw.WriteHeader(http.StatusOK)
+		}
+		res := ResponseInfo{
+			StatusCode: pw.status,
+			Bytes:      pw.bytes,
+			Duration:   time.Since(start),
+			Header:     w.Header().Clone(),
+		}
+		for _, f := range commonPostHandlers {
+			f(r, res)
+		}
+	}
+}
+
+// ヘッダーの書き込みの直前にSetCommonHeaderHookの関数を実行し、ステータスコードとバイト数を記録するhttp.ResponseWriter
+type postProcessWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	status int
+	bytes  int
+}
+
+func (w *postProcessWriter) WriteHeader(statusCode int) {
+	// 1xx(101を除く)は最終的なレスポンスではないため、そのまま書き込む。
+	if w.status == 0 && (statusCode >= 200 || statusCode == http.StatusSwitchingProtocols) {
+		w.status = statusCode
+		for _, f := range commonHeaderHooks {
+			f(w.r, w.Header(), statusCode)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *postProcessWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *postProcessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *postProcessWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *postProcessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
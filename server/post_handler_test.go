@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestCommonPostHandler$ ./server
+func TestCommonPostHandler(t *testing.T) {
+	resetSetting()
+
+	var order []string
+	var results []string
+	SetCommonMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "deny" {
+				SetResponse(w, r, ContentTypePlainText, http.StatusUnauthorized, []byte("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	SetCommonAfterMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "after middleware")
+			next.ServeHTTP(w, r)
+		})
+	})
+	SetCommonPostHandler(func(r *http.Request, res ResponseInfo) {
+		order = append(order, "post handler")
+		results = append(results, fmt.Sprintf("%s %d %dB %s", RoutePattern(r), res.StatusCode, res.Bytes, res.Header.Get("Content-Type")))
+	}, func(r *http.Request, res ResponseInfo) {
+		order = append(order, "post handler2")
+	})
+	Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+		SetResponse(w, r, ContentTypePlainText, http.StatusCreated, []byte("hello"))
+	})
+	Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	})
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		auth   string
+		order  string
+		result string
+	}{
+		{"成功：ハンドラの後に結果を受け取る", "/users/1", "", "after middleware,handler,post handler,post handler2", "/users/:id 201 5B " + ContentTypePlainText},
+		{"成功：何も書き込まない場合は200", "/empty", "", "after middleware,post handler,post handler2", "/empty 200 0B "},
+		{"成功：panicの場合は500", "/panic", "", "after middleware,post handler,post handler2", "/panic 500 " + fmt.Sprint(len(internalServerErrorResponse)) + "B " + internalServerErrorContentType},
+		{"成功：ルートが無い場合", "/none", "", "post handler,post handler2", " 404 " + fmt.Sprint(len(noMethodResponse)) + "B " + noMethodContentType},
+		{"成功：ミドルウェアがレスポンスを返した場合", "/users/1", "deny", "post handler,post handler2", "/users/:id 401 12B " + ContentTypePlainText},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			order, results = nil, nil
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set("Authorization", tc.auth)
+			HTTPHandler().ServeHTTP(httptest.NewRecorder(), r)
+			testutil.AssertEqual(t, strings.Join(order, ","), tc.order)
+			testutil.AssertEqual(t, strings.Join(results, ","), tc.result)
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestCommonHeaderHook$ ./server
+func TestCommonHeaderHook(t *testing.T) {
+	resetSetting()
+
+	SetCommonHeaderHook(func(r *http.Request, h http.Header, statusCode int) {
+		if statusCode >= 400 {
+			h.Set("Cache-Control", "no-store")
+		}
+	}, func(r *http.Request, h http.Header, statusCode int) {
+		h.Set("X-Status", fmt.Sprint(statusCode))
+	})
+	Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("ok"))
+	})
+	Get("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		SetResponse(w, r, ContentTypePlainText, http.StatusBadRequest, []byte("bad"))
+	})
+	Get("/write", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	})
+	Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name         string
+		target       string
+		code         int
+		cacheControl string
+	}{
+		{"成功：ステータスコードに応じてヘッダーを変更する", "/error", http.StatusBadRequest, "no-store"},
+		{"成功：変更しない場合はハンドラのヘッダー", "/ok", http.StatusOK, "max-age=60"},
+		{"成功：WriteHeaderを呼ばずに書き込んだ場合", "/write", http.StatusOK, ""},
+		{"成功：何も書き込まない場合", "/empty", http.StatusOK, ""},
+		{"成功：ルートが無い場合", "/none", http.StatusNotFound, "no-store"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			testutil.AssertEqual(t, w.Code, tc.code)
+			testutil.AssertEqual(t, w.Header().Get("Cache-Control"), tc.cacheControl)
+			testutil.AssertEqual(t, w.Header().Get("X-Status"), fmt.Sprint(tc.code))
+		})
+	}
+}
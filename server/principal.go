@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// 認証されたリクエストの主体(ユーザーやサービスアカウントなど)
+// 認証のミドルウェアがSetPrincipalでcontextへセットし、
+// ハンドラやその他のミドルウェアはPrincipalFrom、MustPrincipalで取得する。
+type Principal struct {
+	// 主体を一意に識別するID
+	ID string
+
+	// 主体に許可されているスコープ(ロール)
+	Scopes []string
+
+	// 認証方式ごとの追加情報(トークンのクレームなど)
+	Attributes map[string]any
+
+	// 代理操作(なりすまし)の場合の、実際に認証された主体
+	// ImpersonationMiddlewareによってセットされる。
+	Impersonator *Principal
+}
+
+// 実際に操作を行っている主体を返す。
+// 代理操作の場合はImpersonator、そうでない場合は自身を返す。
+// 監査ログなどに記録する場合に利用する。
+func (p *Principal) Actor() *Principal {
+	if p.Impersonator != nil {
+		return p.Impersonator
+	}
+	return p
+}
+
+// 指定したスコープを持っているかを返す。
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+var principalContextKey = contextKey{Key: "principal"}
+
+// Principalをセットしたリクエストを返す。
+// 認証のミドルウェアから呼び出し、戻り値のリクエストを後続のハンドラへ渡す。
+func SetPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalContextKey, p))
+}
+
+// SetPrincipalでセットされたPrincipalを取得する。
+// セットされていない場合はfalseを返す。
+func PrincipalFrom(r *http.Request) (*Principal, bool) {
+	p, ok := r.Context().Value(principalContextKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// SetPrincipalでセットされたPrincipalを取得する。
+// 認証のミドルウェアを通過したルートで利用する想定であり、
+// セットされていない場合はpanicとなる(500エラーとして返される)。
+func MustPrincipal(r *http.Request) *Principal {
+	p, ok := PrincipalFrom(r)
+	if !ok {
+		panic("principal is not set. the route may lack an authentication middleware")
+	}
+	return p
+}
+
+// 認証を行うミドルウェア
+// authenticateがエラーを返した場合は401を返し、
+// 成功した場合は戻り値のPrincipalをセットして後続の処理を実行する。
+//
+//	server.Get("/me", h, server.AuthMiddleware(verifyIDToken))
+func AuthMiddleware(authenticate func(r *http.Request) (*Principal, error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := authenticate(r)
+			if err != nil || p == nil {
+				SetResponseAsJson(w, r, http.StatusUnauthorized, map[string]string{"message": "unauthorized"})
+				return
+			}
+			next.ServeHTTP(w, SetPrincipal(r, p))
+		})
+	}
+}
+
+// 代理操作で対象のユーザーを指定するヘッダー
+const ImpersonationHeader = "X-Impersonate-User"
+
+// 代理操作を許可するかを判定する。
+// actorは認証された主体、targetIDはヘッダーで指定された対象のユーザーのID。
+// 許可する場合は対象のユーザーのPrincipalを返し、許可しない場合はエラーを返す。
+type ImpersonationPolicy func(r *http.Request, actor *Principal, targetID string) (*Principal, error)
+
+// 特権を持つ主体が、ImpersonationHeaderで指定した別のユーザーとして操作するためのミドルウェア
+// AuthMiddlewareなどでPrincipalがセットされた後に実行する必要がある。
+// policyが許可した場合は、対象のユーザーのPrincipal(Impersonatorに元の主体をセット)で置き換え、
+// 双方のIDを監査ログとしてInfoで出力する。
+// ヘッダーが無い場合は何もしない。
+// Principalがセットされていない場合は401、policyが許可しない場合は403を返す。
+//
+//	server.Get("/orders", h, server.AuthMiddleware(auth), server.ImpersonationMiddleware(supportOnly))
+func ImpersonationMiddleware(policy ImpersonationPolicy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			targetID := r.Header.Get(ImpersonationHeader)
+			if targetID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			actor, ok := PrincipalFrom(r)
+			if !ok {
+				SetResponseAsJson(w, r, http.StatusUnauthorized, map[string]string{"message": "unauthorized"})
+				return
+			}
+			target, err := policy(r, actor, targetID)
+			if err != nil || target == nil {
+				l.Warn(r.Context(), fmt.Sprintf("impersonation denied: actor=%s target=%s %s %s: %v", actor.ID, targetID, r.Method, r.URL.Path, err))
+				SetResponseAsJson(w, r, http.StatusForbidden, map[string]string{"message": "impersonation not allowed"})
+				return
+			}
+			// policyが返した値を書き換えないようにコピーしてからセットする。
+			impersonated := *target
+			impersonated.Impersonator = actor
+			l.Info(r.Context(), fmt.Sprintf("impersonation: actor=%s target=%s %s %s", actor.ID, impersonated.ID, r.Method, r.URL.Path))
+			next.ServeHTTP(w, SetPrincipal(r, &impersonated))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// テスト用の認証処理。Authorizationヘッダーの値をIDとして扱う。
+func testAuthenticate(r *http.Request) (*Principal, error) {
+	id := r.Header.Get("Authorization")
+	if id == "" {
+		return nil, errors.New("no token")
+	}
+	return &Principal{ID: id, Scopes: []string{"user:read"}}, nil
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestPrincipal$ ./server
+func TestPrincipal(t *testing.T) {
+	resetSetting()
+	Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		p := MustPrincipal(r)
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, map[string]any{"id": p.ID, "read": p.HasScope("user:read"), "write": p.HasScope("user:write")}))
+	}, AuthMiddleware(testAuthenticate))
+	Get("/public", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := PrincipalFrom(r)
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, ok))
+	})
+	Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		MustPrincipal(r)
+	})
+
+	serve := func(path string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("成功：認証されたPrincipalをハンドラで取得できる", func(t *testing.T) {
+		res := serve("/me", "user1")
+		testutil.AssertEqual(t, res.Code, http.StatusOK)
+		testutil.AssertEqual(t, res.Body.String(), `{"is_success":true,"data":{"id":"user1","read":true,"write":false}}`)
+	})
+
+	t.Run("失敗：認証に失敗した場合は401", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/me", "").Code, http.StatusUnauthorized)
+	})
+
+	t.Run("成功：認証の無いルートではPrincipalはセットされていない", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/public", "").Body.String(), `{"is_success":true,"data":false}`)
+	})
+
+	t.Run("失敗：セットされていない場合のMustPrincipalは500", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/missing", "").Code, http.StatusInternalServerError)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestImpersonationMiddleware$ ./server
+func TestImpersonationMiddleware(t *testing.T) {
+	resetSetting()
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+
+	// "admin"のみ他のユーザーとして操作できる。
+	policy := func(r *http.Request, actor *Principal, targetID string) (*Principal, error) {
+		if actor.ID != "admin" {
+			return nil, errors.New("not admin")
+		}
+		return &Principal{ID: targetID}, nil
+	}
+	Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		p := MustPrincipal(r)
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, map[string]any{"id": p.ID, "actor": p.Actor().ID}))
+	}, AuthMiddleware(testAuthenticate), ImpersonationMiddleware(policy))
+
+	serve := func(token string, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", token)
+		if target != "" {
+			req.Header.Set(ImpersonationHeader, target)
+		}
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("成功：ヘッダーが無い場合は認証された主体のまま", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("user1", "").Body.String(), `{"is_success":true,"data":{"actor":"user1","id":"user1"}}`)
+	})
+
+	t.Run("成功：許可された場合は対象のユーザーとして処理され、双方がログに出力される", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("admin", "user2").Body.String(), `{"is_success":true,"data":{"actor":"admin","id":"user2"}}`)
+		if !lg.contains("impersonation: actor=admin target=user2 GET /me") {
+			t.Errorf("audit log not found: %v", lg.logs)
+		}
+	})
+
+	t.Run("失敗：許可されない場合は403", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("user1", "user2").Code, http.StatusForbidden)
+		if !lg.contains("impersonation denied: actor=user1 target=user2") {
+			t.Errorf("audit log not found: %v", lg.logs)
+		}
+	})
+}
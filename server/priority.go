@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// RFC 9218のPriorityヘッダーで指定される優先度
+type Priority struct {
+	// 緊急度。0(最も高い)から7(最も低い)で、デフォルトは3
+	Urgency int
+	// レスポンスを段階的に処理できるか
+	Incremental bool
+}
+
+const (
+	defaultPriorityUrgency = 3
+	maxPriorityUrgency     = 7
+)
+
+// Priorityヘッダーの値をパースする。(例: "u=1, i")
+// 不正な値や未知のパラメータは無視され、指定されていない値はデフォルトとなる。
+func ParsePriority(header string) Priority {
+	p := Priority{Urgency: defaultPriorityUrgency}
+	for _, member := range strings.Split(header, ",") {
+		key, val, hasVal := strings.Cut(strings.TrimSpace(member), "=")
+		// パラメータ(";"以降)は利用しない。
+		val, _, _ = strings.Cut(val, ";")
+		key, _, _ = strings.Cut(key, ";")
+		switch key {
+		case "u":
+			if u, err := strconv.Atoi(val); err == nil && u >= 0 && u <= maxPriorityUrgency {
+				p.Urgency = u
+			}
+		case "i":
+			// 値が無い場合は真(構造化フィールドのBoolean)
+			p.Incremental = !hasVal || val == "?1"
+		}
+	}
+	return p
+}
+
+// リクエストのPriorityヘッダーの優先度を返す。
+// ヘッダーが無い場合はデフォルトの優先度(u=3)を返す。
+func RequestPriority(r *http.Request) Priority {
+	return ParsePriority(r.Header.Get("Priority"))
+}
+
+// 同時に処理するリクエスト数を制限し、超えた場合に優先度の低いリクエストから拒否するミドルウェア
+// 緊急度がデフォルト(u=3)以上のリクエストは同時にmaxInFlightまで、
+// それより低いリクエスト(u=4〜7)は段階的に少ない数(u=7の場合はmaxInFlightの20%)までを受け付け、
+// 超えた場合は503とRetry-Afterヘッダーを返す。
+// クライアントやプロキシの緊急度の指定が、過負荷時にどのリクエストを拒否するかに反映される。
+// maxInFlightが1未満の場合はpanicとなる。
+func LoadShedMiddleware(maxInFlight int64) Middleware {
+	if maxInFlight < 1 {
+		panic(fmt.Sprintf("maxInFlight must be positive, got %d", maxInFlight))
+	}
+	var inFlight atomic.Int64
+	stat := registerLimiter("load_shed", func() map[string]int64 {
+		return map[string]int64{"in_flight": inFlight.Load(), "max_in_flight": maxInFlight}
+	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			if n > admissionLimit(maxInFlight, RequestPriority(r).Urgency) {
+				stat.reject(r)
+				w.Header().Set("Retry-After", "1")
+				SetResponseAsJson(w, r, http.StatusServiceUnavailable, map[string]string{"message": "server overloaded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// 緊急度ごとの同時に受け付けるリクエスト数の上限
+func admissionLimit(maxInFlight int64, urgency int) int64 {
+	if urgency <= defaultPriorityUrgency {
+		return maxInFlight
+	}
+	// u=4から順に80%、60%、40%、20%
+	return max(1, maxInFlight*int64(maxPriorityUrgency+1-urgency)/5)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestParsePriority$ ./server
+func TestParsePriority(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		expect Priority
+	}{
+		{"", Priority{Urgency: 3}},
+		{"u=1", Priority{Urgency: 1}},
+		{"u=5, i", Priority{Urgency: 5, Incremental: true}},
+		{"i=?0,u=0", Priority{Urgency: 0}},
+		{"u=9, x=1", Priority{Urgency: 3}},
+		{"u=a;p=1, i=?1", Priority{Urgency: 3, Incremental: true}},
+	} {
+		testutil.AssertEqual(t, ParsePriority(tc.header), tc.expect)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestLoadShedMiddleware$ ./server
+func TestLoadShedMiddleware(t *testing.T) {
+	testutil.AssertEqual(t, admissionLimit(10, 0), int64(10))
+	testutil.AssertEqual(t, admissionLimit(10, 3), int64(10))
+	testutil.AssertEqual(t, admissionLimit(10, 4), int64(8))
+	testutil.AssertEqual(t, admissionLimit(10, 7), int64(2))
+	testutil.AssertEqual(t, admissionLimit(2, 7), int64(1))
+
+	resetSetting()
+	SetCommonMiddleware(LoadShedMiddleware(2))
+	// 処理中のリクエストを待機させる。
+	started := make(chan struct{})
+	release := make(chan struct{})
+	Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+	Get("/fast", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(path string, priority string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if priority != "" {
+			req.Header.Set("Priority", priority)
+		}
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res.Code
+	}
+
+	// 1件を処理中にする。
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve("/slow", "")
+	}()
+	<-started
+
+	t.Run("失敗：優先度の低いリクエストは拒否される", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/fast", "u=7"), http.StatusServiceUnavailable)
+	})
+
+	t.Run("成功：優先度がデフォルト以上のリクエストは受け付けられる", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/fast", ""), http.StatusOK)
+		testutil.AssertEqual(t, serve("/fast", "u=0"), http.StatusOK)
+	})
+
+	close(release)
+	wg.Wait()
+}
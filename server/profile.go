@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+
+[ルート単位のプロファイルについて]
+GoのCPUプロファイルはプロセス全体が対象となるため、ルート単位で取得することはできない。
+そのため、プロファイル取得中は対象のルートを処理するgoroutineにpprofのラベル(route)を付与する。
+出力されたファイルは以下のようにラベルで絞り込んで確認する。
+	go tool pprof -tagfocus 'route=GET /friend/:number' cpu-xxx.pprof
+
+ヒーププロファイルはプロファイル取得終了時点のスナップショットであり、ルートでの絞り込みはできない。
+
+*/
+
+const profileLabelKey = "route"
+
+var (
+	// ProfileHandlerで出力するプロファイルのディレクトリ
+	ProfileOutputDir = os.TempDir()
+
+	// ProfileHandlerで指定可能な最大の秒数
+	ProfileMaxDuration = 60 * time.Second
+
+	// プロファイル取得中のルート("METHOD pattern")
+	// リクエストごとに参照されるためatomicにしている。
+	profilingRoute atomic.Pointer[string]
+
+	// プロファイルは同時に1つしか取得できない。
+	profileMu sync.Mutex
+
+	ErrProfileRouteNotFound = errors.New("route to profile not found")
+	ErrProfileInProgress    = errors.New("another profile is in progress")
+)
+
+var unsafeFileChar = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// 指定したルートのCPUプロファイルとヒーププロファイルを取得し、dirに書き出す。
+// pathは登録時のパス(例: /friend/:number)を指定する。
+// dの間ブロックし、書き出したファイルのパスを返す。
+func ProfileRoute(method string, path string, d time.Duration, dir string) (cpuFile string, heapFile string, err error) {
+	if findRouteByPattern(method, path) == nil {
+		return "", "", ErrProfileRouteNotFound
+	}
+	if !profileMu.TryLock() {
+		return "", "", ErrProfileInProgress
+	}
+	defer profileMu.Unlock()
+
+	name := unsafeFileChar.ReplaceAllString(method+"_"+path, "_") + "-" + time.Now().Format("20060102150405")
+	cpuFile = filepath.Join(dir, "cpu-"+name+".pprof")
+	heapFile = filepath.Join(dir, "heap-"+name+".pprof")
+
+	cf, err := os.Create(cpuFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer cf.Close()
+	if err := pprof.StartCPUProfile(cf); err != nil {
+		return "", "", err
+	}
+	key := method + " " + path
+	profilingRoute.Store(&key)
+	time.Sleep(d)
+	profilingRoute.Store(nil)
+	pprof.StopCPUProfile()
+
+	hf, err := os.Create(heapFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer hf.Close()
+	runtime.GC() // 最新の状態を反映させるため
+	if err := pprof.WriteHeapProfile(hf); err != nil {
+		return "", "", err
+	}
+
+	return cpuFile, heapFile, nil
+}
+
+// ProfileRouteをリクエストから実行するハンドラ
+// クエリーパラメータのmethod、path、seconds(デフォルトは10秒)で対象を指定する。
+// 管理用のエンドポイントとして、認証を行うミドルウェアと共に登録すること。
+//
+//	server.Post("/admin/profile", server.ProfileHandler, adminAuth)
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	seconds := 10
+	if s := q.Get("seconds"); s != "" {
+		var err error
+		if seconds, err = strconv.Atoi(s); err != nil || seconds <= 0 {
+			SetResponseAsJson(w, r, http.StatusBadRequest, map[string]string{"message": "invalid seconds: " + s})
+			return
+		}
+	}
+	d := time.Duration(seconds) * time.Second
+	if d > ProfileMaxDuration {
+		d = ProfileMaxDuration
+	}
+
+	method := q.Get("method")
+	if method == "" {
+		method = http.MethodGet
+	}
+	cpuFile, heapFile, err := ProfileRoute(method, q.Get("path"), d, ProfileOutputDir)
+	switch {
+	case errors.Is(err, ErrProfileRouteNotFound):
+		SetResponseAsJson(w, r, http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, ErrProfileInProgress):
+		SetResponseAsJson(w, r, http.StatusConflict, map[string]string{"message": err.Error()})
+	case err != nil:
+		panic(fmt.Sprintf("failed to profile: %s", err))
+	default:
+		SetResponseAsJson(w, r, http.StatusOK, map[string]string{"cpu": cpuFile, "heap": heapFile})
+	}
+}
+
+func isProfilingRoute(method string, pattern string) bool {
+	key := profilingRoute.Load()
+	return key != nil && *key == method+" "+pattern
+}
+
+func findRouteByPattern(method string, pattern string) *route {
+	return patternIndex[method+" "+pattern]
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestProfileRoute$ ./server
+func TestProfileRoute(t *testing.T) {
+	resetSetting()
+	Get("/friend/:number", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, "ok")
+	})
+
+	t.Run("成功：プロファイルの出力", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			deadline := time.Now().Add(300 * time.Millisecond)
+			for time.Now().Before(deadline) {
+				execRequest[any](t, http.MethodGet, "/friend/1", nil, nil, http.StatusOK, nil)
+			}
+		}()
+		cpuFile, heapFile, err := ProfileRoute(http.MethodGet, "/friend/:number", 200*time.Millisecond, t.TempDir())
+		<-done
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		for _, f := range []string{cpuFile, heapFile} {
+			info, err := os.Stat(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() == 0 {
+				t.Errorf("%s should not be empty", f)
+			}
+		}
+		testutil.AssertEqual(t, isProfilingRoute(http.MethodGet, "/friend/:number"), false)
+	})
+
+	t.Run("失敗：存在しないルート", func(t *testing.T) {
+		_, _, err := ProfileRoute(http.MethodPost, "/friend/:number", time.Millisecond, t.TempDir())
+		testutil.AssertEqual(t, err, ErrProfileRouteNotFound)
+	})
+}
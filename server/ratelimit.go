@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// トークンバケット
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// キーごとのトークンバケットによるレートリミッター
+type rateLimiter struct {
+	mu sync.Mutex
+	// 1秒あたりに補充されるトークン数
+	rate float64
+	// バケットの最大のトークン数
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+// 一定数を超えた場合に、満タンになった(しばらく利用の無い)バケットを削除する。
+const maxIdleRateLimitBuckets = 10000
+
+// costのトークンを消費する。
+// トークンが足りない場合はfalseを返す。
+// costがburstを超える場合は、常に消費できなくならないようにburstとして扱う。
+// RateLimitInfoのResetは、消費できなかった場合は消費できるようになるまでの時間、
+// それ以外はバケットが満タンになるまでの時間となる。
+func (rl *rateLimiter) take(key string, cost int64, now time.Time) (bool, RateLimitInfo) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if len(rl.buckets) > maxIdleRateLimitBuckets {
+		rl.purge(now)
+	}
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+	b.last = now
+	info := RateLimitInfo{Limit: int64(rl.burst)}
+	if float64(cost) > rl.burst {
+		cost = int64(rl.burst)
+	}
+	if b.tokens < float64(cost) {
+		info.Remaining = int64(b.tokens)
+		info.Reset = rl.durationFor(float64(cost) - b.tokens)
+		return false, info
+	}
+	b.tokens -= float64(cost)
+	info.Remaining = int64(b.tokens)
+	info.Reset = rl.durationFor(rl.burst - b.tokens)
+	return true, info
+}
+
+// tokensが補充されるまでの時間
+func (rl *rateLimiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / rl.rate * float64(time.Second))
+}
+
+// キーの数と、トークンが1未満(制限中)のキーの数を返す。
+func (rl *rateLimiter) state(now time.Time) map[string]int64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	var limited int64
+	for _, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate < 1 {
+			limited++
+		}
+	}
+	return map[string]int64{"buckets": int64(len(rl.buckets)), "limited": limited}
+}
+
+func (rl *rateLimiter) purge(now time.Time) {
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// トークンバケットによるレート制限のミドルウェア
+// keyが返す値(クライアントのIPアドレス、テナントなど)ごとに、1秒あたりrateのトークンを最大burstまで補充し、
+// リクエストごとにルートのコスト(Route.Cost、デフォルトは1)を消費する。(burstを超えるコストはburstとして扱う)
+// レスポンスにはRateLimit-Limit、RateLimit-Remaining、RateLimit-Resetヘッダーを付与し、
+// トークンが足りない場合はSetTooManyRequestsResponseで429を返す。
+// ルートのコストを参照するため、SetCommonAfterMiddlewareまたはルートのミドルウェアとして設定する。
+// rate、burstが0以下の場合はpanicとなる。
+//
+//	server.SetCommonAfterMiddleware(server.RateLimitMiddleware(10, 20, clientIP))
+func RateLimitMiddleware(rate float64, burst int64, key func(r *http.Request) string) Middleware {
+	if rate <= 0 || burst <= 0 {
+		panic(fmt.Sprintf("rate limit must be positive, got rate=%v burst=%d", rate, burst))
+	}
+	rl := &rateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+	stat := registerLimiter("rate_limit", func() map[string]int64 { return rl.state(time.Now()) })
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, info := rl.take(key(r), RouteCost(r), time.Now())
+			if !ok {
+				stat.reject(r)
+				SetTooManyRequestsResponse(w, r, info)
+				return
+			}
+			SetRateLimitHeaders(w, info)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// レート制限の状態
+type RateLimitInfo struct {
+	// 期間内に利用できる最大の量
+	Limit int64 `json:"limit"`
+	// 残りの量
+	Remaining int64 `json:"remaining"`
+	// 制限が解除される(残りの量が回復する)までの時間
+	Reset time.Duration `json:"-"`
+}
+
+// 429の場合に返すレスポンス
+type tooManyRequestsResponse struct {
+	Message string `json:"message"`
+	RateLimitInfo
+	// Resetの秒数
+	ResetSeconds int64 `json:"reset"`
+}
+
+// RateLimit-Limit、RateLimit-Remaining、RateLimit-Resetヘッダーを付与する。
+// Resetは秒数(切り上げ)で出力する。
+func SetRateLimitHeaders(w http.ResponseWriter, info RateLimitInfo) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.FormatInt(info.Limit, 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(info.Remaining, 10))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(info.Reset), 10))
+}
+
+// 制限を超えた場合の429のレスポンスを返す。
+// RateLimitヘッダーとRetry-Afterヘッダーを付与し、以下のjsonを返す。
+// 独自のレート制限のミドルウェアでも同じ形式で返すために利用する。
+//
+//	{"message":"too many requests","limit":20,"remaining":0,"reset":3}
+func SetTooManyRequestsResponse(w http.ResponseWriter, r *http.Request, info RateLimitInfo) {
+	SetRateLimitHeaders(w, info)
+	reset := ceilSeconds(info.Reset)
+	w.Header().Set("Retry-After", strconv.FormatInt(reset, 10))
+	SetResponseAsJson(w, r, http.StatusTooManyRequests, tooManyRequestsResponse{
+		Message:       "too many requests",
+		RateLimitInfo: info,
+		ResetSeconds:  reset,
+	})
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
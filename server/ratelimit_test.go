@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRateLimiter$ ./server
+func TestRateLimiter(t *testing.T) {
+	rl := &rateLimiter{rate: 1, burst: 3, buckets: map[string]*tokenBucket{}}
+	now := time.Now()
+
+	ok, _ := rl.take("a", 3, now)
+	testutil.AssertEqual(t, ok, true)
+	ok, info := rl.take("a", 2, now)
+	testutil.AssertEqual(t, ok, false)
+	testutil.AssertEqual(t, info, RateLimitInfo{Limit: 3, Remaining: 0, Reset: 2 * time.Second})
+
+	// キーごとに独立している。
+	ok, info = rl.take("b", 1, now)
+	testutil.AssertEqual(t, ok, true)
+	testutil.AssertEqual(t, info, RateLimitInfo{Limit: 3, Remaining: 2, Reset: time.Second})
+
+	// 時間の経過で補充される。
+	ok, _ = rl.take("a", 2, now.Add(2*time.Second))
+	testutil.AssertEqual(t, ok, true)
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRateLimitMiddleware$ ./server
+func TestRateLimitMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonAfterMiddleware(RateLimitMiddleware(0.001, 5, func(r *http.Request) string { return "client" }))
+	Get("/search", func(w http.ResponseWriter, r *http.Request) {}).Cost(3)
+	Get("/friends", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+
+	// 5トークンのうち、3 + 1 + 1を消費する。
+	testutil.AssertEqual(t, serve("/search").Code, http.StatusOK)
+	res := serve("/friends")
+	testutil.AssertEqual(t, res.Code, http.StatusOK)
+	testutil.AssertEqual(t, res.Header().Get("RateLimit-Limit"), "5")
+	testutil.AssertEqual(t, res.Header().Get("RateLimit-Remaining"), "1")
+	testutil.AssertEqual(t, serve("/search").Code, http.StatusTooManyRequests)
+	testutil.AssertEqual(t, serve("/friends").Code, http.StatusOK)
+	res = serve("/friends")
+	testutil.AssertEqual(t, res.Code, http.StatusTooManyRequests)
+	testutil.AssertEqual(t, res.Header().Get("RateLimit-Remaining"), "0")
+	testutil.AssertEqual(t, res.Header().Get("Retry-After"), "1000")
+	testutil.AssertEqual(t, res.Body.String(), `{"message":"too many requests","limit":5,"remaining":0,"reset":1000}`)
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRateLimitCostExceedsBurst$ ./server
+func TestRateLimitCostExceedsBurst(t *testing.T) {
+	resetSetting()
+	key := func(r *http.Request) string { return "" }
+	Get("/export", func(w http.ResponseWriter, r *http.Request) {}, RateLimitMiddleware(0.001, 5, key)).Cost(10)
+	Get("/report", func(w http.ResponseWriter, r *http.Request) {}, StoreRateLimitMiddleware(NewMemoryStore(), 5, time.Minute, key)).Cost(10)
+
+	serve := func(path string) int {
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res.Code
+	}
+
+	t.Run("成功：上限を超えるコストは上限として扱い、満タンの場合は処理する", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("/export"), http.StatusOK)
+		testutil.AssertEqual(t, serve("/export"), http.StatusTooManyRequests)
+		testutil.AssertEqual(t, serve("/report"), http.StatusOK)
+		testutil.AssertEqual(t, serve("/report"), http.StatusTooManyRequests)
+	})
+}
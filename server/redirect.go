@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 指定したURLへリダイレクトするレスポンスを返す。
+// urlが相対パスの場合はリクエストのパスを基準に解決される。(http.Redirectを参照)
+// statusCodeが3xxでない場合はpanicとなる。
+func Redirect(w http.ResponseWriter, r *http.Request, statusCode int, url string) {
+	if statusCode < 300 || statusCode > 399 {
+		panic(fmt.Sprintf("redirect status code must be 3xx, got %d", statusCode))
+	}
+	http.Redirect(w, r, url, statusCode)
+}
+
+// fromへのGETリクエストをtoへリダイレクトするルートを登録する。
+// リクエストのクエリ文字列はリダイレクト先へ引き継がれる。(toにクエリがある場合は後ろに追加する)
+// fromがパスパラメータを含む場合、toの同名のパスパラメータは値で置き換えられる。(ワイルドカードの場合は"*名前"、":名前..."を置き換える)
+// statusCodeが3xxでない場合はpanicとなる。
+//
+//	server.RedirectRoute("/old", "/new", http.StatusMovedPermanently)
+//	server.RedirectRoute("/users/:id", "/members/:id", http.StatusPermanentRedirect)
+//	server.RedirectRoute("/docs/*rest", "/manual/*rest", http.StatusMovedPermanently)
+func RedirectRoute(from string, to string, statusCode int) *Route {
+	if statusCode < 300 || statusCode > 399 {
+		panic(fmt.Sprintf("redirect status code must be 3xx, got %d", statusCode))
+	}
+	return Get(from, redirectHandler(from, to, statusCode))
+}
+
+func redirectHandler(from string, to string, statusCode int) Handler {
+	pathParamNames, wildcard := patternParams(from)
+	return func(w http.ResponseWriter, r *http.Request) {
+		location := to
+		for i, name := range pathParamNames {
+			val := getPathParamVal(r, name)
+			if wildcard && i == len(pathParamNames)-1 {
+				// 残りのパスはセグメントごとにエスケープする
+				segments := strings.Split(val, "/")
+				for i, seg := range segments {
+					segments[i] = url.PathEscape(seg)
+				}
+				rest := strings.Join(segments, "/")
+				location = strings.ReplaceAll(location, ":"+name+"...", rest)
+				location = strings.ReplaceAll(location, "*"+name, rest)
+			} else {
+				location = strings.ReplaceAll(location, ":"+name, url.PathEscape(val))
+			}
+		}
+		if q := r.URL.RawQuery; q != "" {
+			if strings.Contains(location, "?") {
+				location += "&" + q
+			} else {
+				location += "?" + q
+			}
+		}
+		http.Redirect(w, r, location, statusCode)
+	}
+}
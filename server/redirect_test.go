@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRedirectRoute$ ./server
+func TestRedirectRoute(t *testing.T) {
+	resetSetting()
+	RedirectRoute("/old", "/new", http.StatusMovedPermanently)
+	RedirectRoute("/search", "/find?v=2", http.StatusFound)
+	RedirectRoute("/users/:id", "/members/:id", http.StatusPermanentRedirect)
+	RedirectRoute("/docs/*rest", "/manual/*rest", http.StatusMovedPermanently)
+	RedirectRoute("/blog/:rest...", "/posts/:rest...", http.StatusMovedPermanently)
+	Get("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		Redirect(w, r, http.StatusSeeOther, "/done")
+	})
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		status   int
+		location string
+	}{
+		{"成功：リダイレクトされる", "/old", http.StatusMovedPermanently, "/new"},
+		{"成功：クエリが引き継がれる", "/old?a=1&b=2", http.StatusMovedPermanently, "/new?a=1&b=2"},
+		{"成功：リダイレクト先のクエリの後ろに追加される", "/search?q=go", http.StatusFound, "/find?v=2&q=go"},
+		{"成功：パスパラメータが置き換えられる", "/users/a%3Fb", http.StatusPermanentRedirect, "/members/a%3Fb"},
+		{"成功：ワイルドカードが置き換えられる", "/docs/a/b%3Fc", http.StatusMovedPermanently, "/manual/a/b%3Fc"},
+		{"成功：残りのパスのパラメータが置き換えられる", "/blog/2024/a", http.StatusMovedPermanently, "/posts/2024/a"},
+		{"成功：Redirect関数", "/redirect", http.StatusSeeOther, "/done"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			testutil.AssertEqual(t, w.Code, tc.status)
+			testutil.AssertEqual(t, w.Header().Get("Location"), tc.location)
+		})
+	}
+
+	t.Run("失敗：3xx以外のステータスコードはpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		RedirectRoute("/other", "/new", http.StatusOK)
+	})
+}
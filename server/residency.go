@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 他のリージョンへ転送したリクエストに付与するヘッダー
+// 転送先で再度転送されること(転送のループ)を防ぐために利用する。
+const ResidencyForwardedHeader = "X-Residency-Forwarded-From"
+
+// 転送したリクエストの署名を付与するヘッダー
+// 値は"<unix時刻>.<HMAC-SHA256の16進数>"
+const ResidencySignatureHeader = "X-Residency-Signature"
+
+// 転送したリクエストの署名の有効期間
+const residencySignatureTTL = 5 * time.Minute
+
+// リクエストのテナントがデータを保持するリージョンを返す。
+// 空文字を返した場合は、自身のリージョンで処理する。
+type RegionResolver func(r *http.Request) (region string, err error)
+
+// テナントのデータの保存先(リージョン)に応じて、リクエストを転送するミドルウェア
+// resolveが返したリージョンがlocalRegionと異なる場合は、regionsに指定したそのリージョンのデプロイ先へ
+// リバースプロキシで転送し、後続の処理は実行しない。
+// 転送するリクエストには、各リージョンで共有するsecretによる署名(ResidencySignatureHeader)を付与する。
+// 署名が正しい転送されたリクエストは再度転送せずに処理する。
+// 署名が無い、または正しくない場合は、クライアントが付与したものとしてResidencyForwardedHeaderを削除して通常通り処理する。
+// resolveがエラーを返した場合、またはregionsに無いリージョンの場合は500を返す。
+// secretが空の場合はpanicとなる。
+//
+//	server.SetCommonMiddleware(server.ResidencyMiddleware("ap-northeast-1", os.Getenv("RESIDENCY_SECRET"), tenantRegion, map[string]*url.URL{
+//		"eu-west-1": {Scheme: "https", Host: "eu.api.example.com"},
+//	}))
+func ResidencyMiddleware(localRegion string, secret string, resolve RegionResolver, regions map[string]*url.URL) Middleware {
+	if secret == "" {
+		panic("residency secret must not be empty")
+	}
+	proxies := map[string]*httputil.ReverseProxy{}
+	for region, target := range regions {
+		if region == localRegion {
+			continue
+		}
+		proxies[region] = &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+				pr.SetXForwarded()
+				pr.Out.Header.Set(ResidencyForwardedHeader, localRegion)
+				pr.Out.Header.Set(ResidencySignatureHeader, signResidency(secret, localRegion, pr.Out.Method, pr.Out.URL.RequestURI(), time.Now()))
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				l.Error(r.Context(), fmt.Sprintf("failed to forward request to region %s: %s", region, err))
+				SetResponseAsJson(w, r, http.StatusBadGateway, map[string]string{"message": "bad gateway"})
+			},
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if from := r.Header.Get(ResidencyForwardedHeader); from != "" {
+				if verifyResidency(secret, from, r.Method, r.URL.RequestURI(), r.Header.Get(ResidencySignatureHeader), time.Now()) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				l.Warn(r.Context(), fmt.Sprintf("invalid residency signature: forwarded from %s", from))
+				r.Header.Del(ResidencyForwardedHeader)
+			}
+			r.Header.Del(ResidencySignatureHeader)
+			region, err := resolve(r)
+			if err != nil {
+				l.Error(r.Context(), fmt.Sprintf("failed to resolve region: %s", err))
+				SetResponse(w, r, internalServerErrorContentType, http.StatusInternalServerError, internalServerErrorResponseFor(r))
+				return
+			}
+			if region == "" || region == localRegion {
+				next.ServeHTTP(w, r)
+				return
+			}
+			proxy, ok := proxies[region]
+			if !ok {
+				l.Error(r.Context(), fmt.Sprintf("no deployment for region %s", region))
+				SetResponse(w, r, internalServerErrorContentType, http.StatusInternalServerError, internalServerErrorResponseFor(r))
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		})
+	}
+}
+
+// 転送元のリージョン、メソッド、リクエストURI、時刻に対する署名を返す。
+func signResidency(secret, region, method, requestURI string, now time.Time) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	return ts + "." + residencyMAC(secret, region, method, requestURI, ts)
+}
+
+// 署名が正しく、有効期間内かどうかを返す。
+func verifyResidency(secret, region, method, requestURI, signature string, now time.Time) bool {
+	ts, mac, ok := strings.Cut(signature, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > residencySignatureTTL || d < -residencySignatureTTL {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(residencyMAC(secret, region, method, requestURI, ts)))
+}
+
+func residencyMAC(secret, region, method, requestURI, ts string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(region + "\n" + method + "\n" + requestURI + "\n" + ts))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestResidencyMiddleware$ ./server
+func TestResidencyMiddleware(t *testing.T) {
+	// 転送先のリージョンのデプロイ
+	eu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from := r.Header.Get(ResidencyForwardedHeader)
+		verified := verifyResidency("secret", from, r.Method, r.URL.RequestURI(), r.Header.Get(ResidencySignatureHeader), time.Now())
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("eu:"+r.URL.Path+":"+from+":"+strconv.FormatBool(verified)))
+	}))
+	defer eu.Close()
+	euURL, _ := url.Parse(eu.URL)
+
+	resetSetting()
+	tenantRegion := func(r *http.Request) (string, error) {
+		return r.Header.Get("X-Tenant-Region"), nil
+	}
+	SetCommonMiddleware(ResidencyMiddleware("jp", "secret", tenantRegion, map[string]*url.URL{"eu": euURL}))
+	Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("jp"))
+	})
+
+	serve := func(region string, forwarded string, signature ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("X-Tenant-Region", region)
+		if forwarded != "" {
+			req.Header.Set(ResidencyForwardedHeader, forwarded)
+		}
+		if len(signature) > 0 {
+			req.Header.Set(ResidencySignatureHeader, signature[0])
+		}
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("成功：自身のリージョンのテナントはそのまま処理する", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("jp", "").Body.String(), "jp")
+		testutil.AssertEqual(t, serve("", "").Body.String(), "jp")
+	})
+
+	t.Run("成功：他のリージョンのテナントは転送する", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("eu", "").Body.String(), "eu:/orders:jp:true")
+	})
+
+	t.Run("成功：署名が正しい転送されたリクエストは再度転送しない", func(t *testing.T) {
+		sig := signResidency("secret", "us", http.MethodGet, "/orders", time.Now())
+		testutil.AssertEqual(t, serve("eu", "us", sig).Body.String(), "jp")
+	})
+
+	t.Run("成功：署名が無い、または正しくない転送のヘッダーは無視して転送する", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("eu", "us").Body.String(), "eu:/orders:jp:true")
+		testutil.AssertEqual(t, serve("eu", "us", signResidency("wrong", "us", http.MethodGet, "/orders", time.Now())).Body.String(), "eu:/orders:jp:true")
+		testutil.AssertEqual(t, serve("eu", "us", signResidency("secret", "us", http.MethodGet, "/other", time.Now())).Body.String(), "eu:/orders:jp:true")
+		testutil.AssertEqual(t, serve("eu", "us", signResidency("secret", "us", http.MethodGet, "/orders", time.Now().Add(-time.Hour))).Body.String(), "eu:/orders:jp:true")
+	})
+
+	t.Run("失敗：secretが空の場合はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		ResidencyMiddleware("jp", "", tenantRegion, nil)
+	})
+
+	t.Run("失敗：転送先の無いリージョンは500", func(t *testing.T) {
+		testutil.AssertEqual(t, serve("us", "").Code, http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Retryの設定
+// ゼロ値の項目はデフォルト値が利用される。
+type RetryPolicy struct {
+	// 最大の試行回数(初回を含む)。デフォルトは3
+	MaxAttempts int
+	// 初回の待機時間。デフォルトは100ms
+	InitialInterval time.Duration
+	// 待機時間の上限。デフォルトは10s
+	MaxInterval time.Duration
+	// 待機時間の倍率。デフォルトは2
+	Multiplier float64
+	// 待機時間に加えるばらつきの割合(0〜1)。例えば0.2の場合は±20%となる。
+	Jitter float64
+	// エラーを再試行するかを判定する。nilの場合はすべてのエラーを再試行する。
+	Retryable func(err error) bool
+}
+
+// 試行回数nの後の待機時間(ばらつきを含まない)
+func (p RetryPolicy) interval(n int) time.Duration {
+	initial := cmp.Or(p.InitialInterval, 100*time.Millisecond)
+	maxInterval := cmp.Or(p.MaxInterval, 10*time.Second)
+	multiplier := cmp.Or(p.Multiplier, 2)
+	d := float64(initial)
+	for range n - 1 {
+		d *= multiplier
+		if d >= float64(maxInterval) {
+			return maxInterval
+		}
+	}
+	return time.Duration(d)
+}
+
+// fnがエラーを返した場合に、指数関数的に待機時間を増やしながら再試行する。
+// ハンドラ内での不安定な外部呼び出しなどで、再試行の処理を統一するために利用する。
+// 再試行の際はWarnを出力する。
+// contextがキャンセルされた場合、または次の試行がcontextの期限(リクエストのタイムアウトなど)までに間に合わない場合は、
+// 待機せずに最後のエラーをラップして返す。
+// 最大の試行回数に達した場合、Retryableがfalseを返した場合も最後のエラーを返す。
+//
+//	err := server.Retry(r.Context(), server.RetryPolicy{MaxAttempts: 5, Jitter: 0.2}, func(c context.Context) error {
+//		return callPaymentAPI(c, req)
+//	})
+func Retry(c context.Context, policy RetryPolicy, fn func(c context.Context) error) error {
+	maxAttempts := cmp.Or(policy.MaxAttempts, 3)
+	for n := 1; ; n++ {
+		err := fn(c)
+		if err == nil {
+			return nil
+		}
+		if n >= maxAttempts || (policy.Retryable != nil && !policy.Retryable(err)) {
+			return err
+		}
+		wait := policy.interval(n)
+		if policy.Jitter > 0 {
+			wait = time.Duration(float64(wait) * (1 + policy.Jitter*(rand.Float64()*2-1)))
+		}
+		if deadline, ok := c.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("retry: deadline exceeded after %d attempts: %w", n, err)
+		}
+		l.Warn(c, fmt.Sprintf("retry: attempt %d failed: %s, retrying in %s", n, err, wait))
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.Done():
+			timer.Stop()
+			return fmt.Errorf("retry: %w after %d attempts: %w", c.Err(), n, err)
+		case <-timer.C:
+		}
+	}
+}
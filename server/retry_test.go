@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRetry$ ./server
+func TestRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+	policy := RetryPolicy{MaxAttempts: 4, InitialInterval: time.Millisecond, Retryable: func(err error) bool {
+		return !errors.Is(err, errPermanent)
+	}}
+
+	t.Run("成功：再試行で成功する", func(t *testing.T) {
+		lg := &recordLogger{}
+		SetLogger(lg)
+		defer SetLogger(&defaultLogger{})
+		attempts := 0
+		err := Retry(context.Background(), policy, func(c context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errTemporary
+			}
+			return nil
+		})
+		testutil.AssertEqual(t, err, nil)
+		testutil.AssertEqual(t, attempts, 3)
+		testutil.AssertEqual(t, lg.contains("retry: attempt 2 failed: temporary, retrying in 2ms"), true)
+	})
+
+	t.Run("失敗：最大の試行回数で最後のエラーを返す", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), policy, func(c context.Context) error {
+			attempts++
+			return errTemporary
+		})
+		testutil.AssertEqual(t, err, errTemporary)
+		testutil.AssertEqual(t, attempts, 4)
+	})
+
+	t.Run("失敗：再試行しないエラーは即座に返す", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), policy, func(c context.Context) error {
+			attempts++
+			return errPermanent
+		})
+		testutil.AssertEqual(t, err, errPermanent)
+		testutil.AssertEqual(t, attempts, 1)
+	})
+
+	t.Run("失敗：期限までに間に合わない場合は待機しない", func(t *testing.T) {
+		c, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		attempts := 0
+		start := time.Now()
+		err := Retry(c, RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second}, func(c context.Context) error {
+			attempts++
+			return errTemporary
+		})
+		testutil.AssertEqual(t, errors.Is(err, errTemporary), true)
+		testutil.AssertEqual(t, attempts, 1)
+		testutil.AssertEqual(t, time.Since(start) < time.Millisecond*50, true)
+	})
+
+	t.Run("失敗：キャンセルされた場合は待機を中断する", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(time.Millisecond*20, cancel)
+		err := Retry(c, RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second}, func(c context.Context) error {
+			return errTemporary
+		})
+		testutil.AssertEqual(t, errors.Is(err, context.Canceled), true)
+		testutil.AssertEqual(t, errors.Is(err, errTemporary), true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRetryPolicyInterval$ ./server
+func TestRetryPolicyInterval(t *testing.T) {
+	p := RetryPolicy{InitialInterval: time.Second, MaxInterval: time.Second * 5, Multiplier: 3}
+	testutil.AssertEqual(t, p.interval(1), time.Second)
+	testutil.AssertEqual(t, p.interval(2), time.Second*3)
+	testutil.AssertEqual(t, p.interval(3), time.Second*5)
+	testutil.AssertEqual(t, RetryPolicy{}.interval(2), time.Millisecond*200)
+}
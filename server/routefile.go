@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// 設定ファイルから読み込むルートの一覧
+//
+//	{
+//	  "routes": [
+//	    {"path": "/old", "redirect": "/new", "status": 301},
+//	    {"path": "/favicon.ico", "file": "./public/favicon.ico"},
+//	    {"path": "/assets/:name", "dir": "./public/assets"},
+//	    {"path": "/legacy/users", "proxy": "http://legacy:8080"},
+//	    {"method": "POST", "path": "/mock/orders", "mock": {"status": 201, "body": {"id": 1}}}
+//	  ]
+//	}
+type RouteFile struct {
+	Routes []RouteDefinition `json:"routes"`
+}
+
+// 設定ファイルで定義するルート
+// File、Dir、Redirect、Proxy、Mockのいずれか1つを指定する。
+type RouteDefinition struct {
+	// 省略した場合はGET
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// 指定したファイルを返す。
+	File string `json:"file"`
+	// 指定したディレクトリから、パスパラメータの名前のファイルを返す。
+	// Pathはパスパラメータを含む必要がある。
+	Dir string `json:"dir"`
+	// 指定したURLへリダイレクトする。(RedirectRouteを参照)
+	Redirect string `json:"redirect"`
+	// 指定したURL(スキームとホスト)へリクエストをそのまま転送する。
+	Proxy string `json:"proxy"`
+	// 固定のレスポンスを返す。
+	Mock *MockResponse `json:"mock"`
+
+	// Redirectのステータスコード。省略した場合は302
+	Status int `json:"status"`
+}
+
+// 設定ファイルで定義する固定のレスポンス
+type MockResponse struct {
+	// 省略した場合は200
+	Status int `json:"status"`
+	// 省略した場合はapplication/json
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers"`
+	// Content-TypeがJSONの場合はJSONの値をそのまま返し、
+	// それ以外の場合はJSONの文字列の値を返す。
+	Body json.RawMessage `json:"body"`
+}
+
+// JSONの設定ファイルからルートを読み込んで登録する。
+// リダイレクトや静的ファイルの追加など、コードの変更が不要なルートの設定に利用する。
+// サーバーの起動前に呼び出す必要がある。
+// 定義に問題がある場合は、すべての問題をまとめたErrInvalidRoutesを返し、ルートは1つも登録しない。
+//
+//	if err := server.LoadRoutesFile("routes.json"); err != nil {
+//		log.Fatal(err)
+//	}
+func LoadRoutesFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return LoadRoutes(f)
+}
+
+// JSONのルートの定義を読み込んで登録する。(LoadRoutesFileを参照)
+func LoadRoutes(r io.Reader) error {
+	var rf RouteFile
+	dec := json.NewDecoder(r)
+	// 項目名の誤りを検出するため
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rf); err != nil {
+		return fmt.Errorf("failed to decode routes: %w", err)
+	}
+
+	var errs []error
+	handlers := make([]Handler, len(rf.Routes))
+	seen := map[string]bool{}
+	for i, def := range rf.Routes {
+		if def.Method == "" {
+			rf.Routes[i].Method = http.MethodGet
+			def.Method = http.MethodGet
+		}
+		hr, err := def.handler()
+		if err == nil {
+			if !isValidMethod(def.Method) {
+				err = fmt.Errorf("invalid method %q", def.Method)
+			}
+		}
+		if err == nil {
+			// パスパラメータの名前のみが異なるパターンも同じルートとして扱う。
+			segments, _, _ := validatePattern(def.Path)
+			key := def.Method + " " + patternShape(segments)
+			if seen[key] || getRoute(def.Path, def.Method) != nil {
+				err = fmt.Errorf("route %s %s is already registered", def.Method, def.Path)
+			}
+			seen[key] = true
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("routes[%d]: %w", i, err))
+			continue
+		}
+		handlers[i] = hr
+	}
+	if len(errs) > 0 {
+		return &ErrInvalidRoutes{Errs: errs}
+	}
+
+	for i, def := range rf.Routes {
+		setHandler(def.Path, handlers[i], def.Method)
+	}
+	return nil
+}
+
+// 定義に応じたハンドラを返す。
+func (def RouteDefinition) handler() (Handler, error) {
+	if !strings.HasPrefix(def.Path, "/") {
+		return nil, fmt.Errorf("path must start with /, got %q", def.Path)
+	}
+	_, names, err := validatePattern(def.Path)
+	if err != nil {
+		return nil, err
+	}
+	var paramName string
+	if len(names) > 0 {
+		paramName = names[len(names)-1]
+	}
+
+	n := 0
+	for _, set := range []bool{def.File != "", def.Dir != "", def.Redirect != "", def.Proxy != "", def.Mock != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("exactly one of file, dir, redirect, proxy and mock must be set")
+	}
+
+	switch {
+	case def.File != "":
+		if info, err := os.Stat(def.File); err != nil {
+			return nil, fmt.Errorf("file %q is not readable: %w", def.File, err)
+		} else if info.IsDir() {
+			return nil, fmt.Errorf("file %q is a directory", def.File)
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, def.File)
+		}, nil
+
+	case def.Dir != "":
+		if paramName == "" {
+			return nil, fmt.Errorf("path %q must have a path parameter to serve dir", def.Path)
+		}
+		if info, err := os.Stat(def.Dir); err != nil {
+			return nil, fmt.Errorf("dir %q is not readable: %w", def.Dir, err)
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("dir %q is not a directory", def.Dir)
+		}
+		fsys := os.DirFS(def.Dir)
+		return func(w http.ResponseWriter, r *http.Request) {
+			name := getPathParamVal(r, paramName)
+			// ".."などでディレクトリの外を参照させない。
+			if !fs.ValidPath(name) || name == "." {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeFileFS(w, r, fsys, name)
+		}, nil
+
+	case def.Redirect != "":
+		status := def.Status
+		if status == 0 {
+			status = http.StatusFound
+		}
+		if status < 300 || status > 399 {
+			return nil, fmt.Errorf("redirect status must be 3xx, got %d", status)
+		}
+		return redirectHandler(def.Path, def.Redirect, status), nil
+
+	case def.Proxy != "":
+		target, err := url.Parse(def.Proxy)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("proxy %q must be an absolute URL", def.Proxy)
+		}
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+				pr.SetXForwarded()
+			},
+		}
+		return proxy.ServeHTTP, nil
+
+	default:
+		m := def.Mock
+		status := m.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		contentType := m.ContentType
+		if contentType == "" {
+			contentType = ContentTypeJSON
+		}
+		body := []byte(m.Body)
+		if !strings.Contains(contentType, "json") && len(body) > 0 {
+			var s string
+			if err := json.Unmarshal(body, &s); err != nil {
+				return nil, fmt.Errorf("mock body must be a string for content type %s", contentType)
+			}
+			body = []byte(s)
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range m.Headers {
+				w.Header().Set(k, v)
+			}
+			SetResponse(w, r, contentType, status, body)
+		}, nil
+	}
+}
+
+type ErrInvalidRoutes struct {
+	Errs []error
+}
+
+func (e *ErrInvalidRoutes) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid routes:\n\t%s", strings.Join(msgs, "\n\t"))
+}
+
+func (e *ErrInvalidRoutes) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestLoadRoutes$ ./server
+func TestLoadRoutes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "backend "+r.URL.RequestURI())
+	}))
+	defer backend.Close()
+
+	t.Run("成功：定義したルートが登録される", func(t *testing.T) {
+		resetSetting()
+		err := LoadRoutes(strings.NewReader(`{"routes": [
+			{"path": "/old", "redirect": "/new", "status": 301},
+			{"path": "/robots.txt", "file": "` + filepath.ToSlash(filepath.Join(dir, "robots.txt")) + `"},
+			{"path": "/public/:name", "dir": "` + filepath.ToSlash(dir) + `"},
+			{"path": "/legacy", "proxy": "` + backend.URL + `"},
+			{"method": "POST", "path": "/mock", "mock": {"status": 201, "headers": {"X-Mock": "1"}, "body": {"id": 1}}},
+			{"path": "/mock/text", "mock": {"content_type": "text/plain", "body": "hello"}}
+		]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			method, path string
+			status       int
+			body         string
+			header       map[string]string
+		}{
+			{http.MethodGet, "/old", http.StatusMovedPermanently, "", map[string]string{"Location": "/new"}},
+			{http.MethodGet, "/robots.txt", http.StatusOK, "User-agent: *", nil},
+			{http.MethodGet, "/public/robots.txt", http.StatusOK, "User-agent: *", nil},
+			{http.MethodGet, "/public/..", http.StatusNotFound, "", nil},
+			{http.MethodGet, "/legacy?a=1", http.StatusOK, "backend /legacy?a=1", nil},
+			{http.MethodPost, "/mock", http.StatusCreated, `{"id": 1}`, map[string]string{"X-Mock": "1", "Content-Type": ContentTypeJSON}},
+			{http.MethodGet, "/mock/text", http.StatusOK, "hello", map[string]string{"Content-Type": ContentTypePlainText}},
+		} {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			testutil.AssertEqual(t, w.Code, tc.status)
+			if tc.body != "" {
+				testutil.AssertEqual(t, w.Body.String(), tc.body)
+			}
+			for k, v := range tc.header {
+				testutil.AssertEqual(t, w.Header().Get(k), v)
+			}
+		}
+	})
+
+	t.Run("失敗：定義の問題をまとめて返し、ルートを登録しない", func(t *testing.T) {
+		resetSetting()
+		Get("/exists", func(w http.ResponseWriter, r *http.Request) {})
+		err := LoadRoutes(strings.NewReader(`{"routes": [
+			{"path": "/ok", "redirect": "/new"},
+			{"path": "no-slash", "redirect": "/new"},
+			{"path": "/both", "redirect": "/new", "proxy": "http://example.com"},
+			{"path": "/exists", "redirect": "/new"},
+			{"path": "/dir", "dir": "` + filepath.ToSlash(dir) + `"},
+			{"path": "/status", "redirect": "/new", "status": 200},
+			{"path": "/proxy", "proxy": "/relative"},
+			{"path": "/missing", "file": "` + filepath.ToSlash(filepath.Join(dir, "missing")) + `"},
+			{"path": "/users/:id/:id", "redirect": "/new"},
+			{"path": "/u/:id", "redirect": "/new"},
+			{"path": "/u/:name", "redirect": "/new"},
+			{"method": "GE T", "path": "/method", "redirect": "/new"}
+		]}`))
+		var invalid *ErrInvalidRoutes
+		if !errors.As(err, &invalid) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testutil.AssertEqual(t, len(invalid.Errs), 10)
+		testutil.AssertEqual(t, getRoute("/ok", http.MethodGet) == nil, true)
+		testutil.AssertEqual(t, getRoute("/u/:id", http.MethodGet) == nil, true)
+	})
+
+	t.Run("失敗：未知の項目はエラー", func(t *testing.T) {
+		resetSetting()
+		err := LoadRoutes(strings.NewReader(`{"routes": [{"path": "/old", "redirct": "/new"}]}`))
+		if err == nil || !strings.Contains(err.Error(), "redirct") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
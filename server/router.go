@@ -0,0 +1,448 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ルーティングの木(トライ木)のノード
+// パスを"/"で区切ったセグメントごとにノードを持ち、メソッドごとに1つの木を構築する。
+// 例: /users/:id/posts と /users/me は、rootの下のusersノードの下に、
+// paramsの子ノード(その下にposts)とstaticの子ノード(me)として格納される。
+type node struct {
+	// 固定のセグメントの子ノード
+	static map[string]*node
+	// パスパラメータ(:name)のセグメントの子ノード
+	// パラメータ名はルートごとに持つため、同じ位置のパスパラメータは名前が異なっても制約が同じであれば同じノードとなる。
+	// 制約を持つノードが先、制約の無いノードが最後になるように並べる。
+	params []*node
+	// パスパラメータの制約(paramsの要素のノードのみ)
+	// 制約が無い場合はnil
+	constraint *paramConstraint
+	// 残りのパス全体にマッチするワイルドカード(*name)のルート
+	wildcard *route
+	// このノードで終わるパスのルート
+	route *route
+}
+
+// ルーティングで取得したパスパラメータの値
+// ルートのpathParamNamesと同じ順番で格納される。
+// リクエストごとにスライスを生成しないように固定長の配列で保持する。
+type pathParamValues struct {
+	vals [maxPathParams]string
+	n    int
+}
+
+// パスのセグメントに対応するルートを検索する。
+// pathは先頭の"/"を除いたエスケープされたパス(例: /users/1 の場合は users/1)。
+// セグメントごとにデコードしてから比較するため、パスパラメータの値に"/"(%2F)を含められる。
+// 各セグメントで、固定のセグメント、パスパラメータ、ワイルドカードの順に探し、
+// マッチするルートが無い場合は次の候補に戻って探す。
+func (n *node) lookup(path string, ps *pathParamValues) *route {
+	seg, rest, more := strings.Cut(path, "/")
+	decoded, ok := unescapeSegment(seg)
+	if !ok {
+		return nil
+	}
+	if child, ok := n.static[decoded]; ok {
+		if ru := child.next(rest, more, ps); ru != nil {
+			return ru
+		}
+	}
+	for _, param := range n.params {
+		if param.constraint != nil && !param.constraint.match(decoded) {
+			continue
+		}
+		i := ps.n
+		ps.vals[i] = paramValue(seg, decoded)
+		ps.n++
+		if ru := param.next(rest, more, ps); ru != nil {
+			return ru
+		}
+		ps.n = i
+	}
+	if n.wildcard != nil {
+		decoded, ok := unescapeSegment(path)
+		if !ok {
+			return nil
+		}
+		ps.vals[ps.n] = paramValue(path, decoded)
+		ps.n++
+		return n.wildcard
+	}
+	return nil
+}
+
+func (n *node) next(rest string, more bool, ps *pathParamValues) *route {
+	if !more {
+		return n.route
+	}
+	return n.lookup(rest, ps)
+}
+
+// Route.Priorityを設定したルートがあるかどうか
+// 無い場合は、最初に見つかったルートを返す(すべての候補を探さない)。
+var routePriorityUsed = false
+
+// パスに対応するルートを、優先順位(Route.Priority)を考慮して検索する。
+// 優先順位を設定したルートがある場合は、マッチするすべてのルートから優先順位が最も高いものを返し、
+// 同じ優先順位の場合はlookupと同じ順で先に見つかったルートを返す。
+func (n *node) find(path string, ps *pathParamValues) *route {
+	if !routePriorityUsed {
+		return n.lookup(path, ps)
+	}
+	var m routeMatch
+	n.lookupAll(path, ps, &m)
+	if m.ru != nil {
+		*ps = m.ps
+	}
+	return m.ru
+}
+
+// lookupAllで見つかった最も優先順位が高いルートとパスパラメータの値
+type routeMatch struct {
+	ru *route
+	ps pathParamValues
+}
+
+func (m *routeMatch) consider(ru *route, ps *pathParamValues) {
+	if m.ru == nil || ru.priority > m.ru.priority {
+		m.ru = ru
+		m.ps = *ps
+	}
+}
+
+// lookupと同じ順で、マッチするすべてのルートをmへ渡す。
+func (n *node) lookupAll(path string, ps *pathParamValues, m *routeMatch) {
+	seg, rest, more := strings.Cut(path, "/")
+	decoded, ok := unescapeSegment(seg)
+	if !ok {
+		return
+	}
+	if child, ok := n.static[decoded]; ok {
+		child.nextAll(rest, more, ps, m)
+	}
+	for _, param := range n.params {
+		if param.constraint != nil && !param.constraint.match(decoded) {
+			continue
+		}
+		i := ps.n
+		ps.vals[i] = paramValue(seg, decoded)
+		ps.n++
+		param.nextAll(rest, more, ps, m)
+		ps.n = i
+	}
+	if decoded, ok := unescapeSegment(path); ok && n.wildcard != nil {
+		i := ps.n
+		ps.vals[i] = paramValue(path, decoded)
+		ps.n++
+		m.consider(n.wildcard, ps)
+		ps.n = i
+	}
+}
+
+func (n *node) nextAll(rest string, more bool, ps *pathParamValues, m *routeMatch) {
+	if !more {
+		if n.route != nil {
+			m.consider(n.route, ps)
+		}
+		return
+	}
+	n.lookupAll(rest, ps, m)
+}
+
+// パターンのセグメントに対応するノードを返す。
+// createがtrueの場合は、存在しないノードを作成する。falseの場合に存在しなければnilを返す。
+// パターンの最後のセグメントがワイルドカードの場合は、wildcardにtrueを返す。(ノードはワイルドカードを持つノード)
+func (n *node) walk(segments []string, create bool) (nd *node, wildcard bool) {
+	nd = n
+	for _, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			return nd, true
+		case strings.HasPrefix(seg, ":"):
+			_, constraint, _ := splitParam(seg)
+			i := slices.IndexFunc(nd.params, func(p *node) bool { return p.constraint.equal(constraint) })
+			if i < 0 {
+				if !create {
+					return nil, false
+				}
+				nd.params = append(nd.params, &node{constraint: constraint})
+				// 制約の無いノードを最後にする(制約を持つノード同士は登録順)
+				slices.SortStableFunc(nd.params, func(a, b *node) int {
+					return compareBool(a.constraint == nil, b.constraint == nil)
+				})
+				i = slices.IndexFunc(nd.params, func(p *node) bool { return p.constraint.equal(constraint) })
+			}
+			nd = nd.params[i]
+		default:
+			child, ok := nd.static[seg]
+			if !ok {
+				if !create {
+					return nil, false
+				}
+				if nd.static == nil {
+					nd.static = map[string]*node{}
+				}
+				child = &node{}
+				nd.static[seg] = child
+			}
+			nd = child
+		}
+	}
+	return nd, false
+}
+
+// パターンを検証し、セグメントとパスパラメータの名前を返す。
+// パターンが不正な場合はpanicとなる。(validatePatternを参照)
+func parsePattern(pattern string) (segments []string, pathParamNames []string) {
+	segments, pathParamNames, err := validatePattern(pattern)
+	if err != nil {
+		panic(err.Error())
+	}
+	return segments, pathParamNames
+}
+
+// パターンを検証し、セグメントとパスパラメータの名前を返す。
+// パターンが"/"で始まらない、パスパラメータの名前が空または重複している、パスパラメータが多すぎる、
+// ワイルドカードが最後のセグメントではない場合はエラーを返す。
+// ワイルドカードのセグメントは":名前..."で指定した場合も"*名前"として返す。
+func validatePattern(pattern string) (segments []string, pathParamNames []string, err error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, nil, fmt.Errorf(PanicInvalidPattern, pattern)
+	}
+	segments = strings.Split(pattern[1:], "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") && strings.HasSuffix(seg, "...") {
+			// ":名前..."は"*名前"と同じワイルドカードとして扱う。
+			seg = "*" + strings.TrimSuffix(seg[1:], "...")
+			segments[i] = seg
+		}
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			name := seg[1:]
+			if i != len(segments)-1 || name == "" || strings.ContainsAny(name, ":*") {
+				return nil, nil, fmt.Errorf(PanicInvalidWildcard, pattern)
+			}
+			pathParamNames = append(pathParamNames, name)
+		case strings.HasPrefix(seg, ":"):
+			name, _, ok := splitParam(seg)
+			if !ok || name == "" || slices.Contains(pathParamNames, name) {
+				return nil, nil, fmt.Errorf(PanicInvalidPattern, pattern)
+			}
+			pathParamNames = append(pathParamNames, name)
+		}
+	}
+	if len(pathParamNames) > maxPathParams {
+		return nil, nil, fmt.Errorf(PanicInvalidPattern, pattern)
+	}
+	return segments, pathParamNames, nil
+}
+
+// パスパラメータの名前を除いたパターンを返す。
+// 同じ位置へ登録されるパターン(/users/:id と /users/:name など)は同じ値となる。
+func patternShape(segments []string) string {
+	shape := make([]string, len(segments))
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			shape[i] = "*"
+		case strings.HasPrefix(seg, ":"):
+			shape[i] = ":"
+			if j := strings.Index(seg, "<"); j >= 0 {
+				shape[i] += seg[j:]
+			}
+		default:
+			shape[i] = seg
+		}
+	}
+	return "/" + strings.Join(shape, "/")
+}
+
+// パターンのパスパラメータの名前と、最後のセグメントがワイルドカードかどうかを返す。
+func patternParams(pattern string) (pathParamNames []string, wildcard bool) {
+	segments, names := parsePattern(pattern)
+	return names, strings.HasPrefix(segments[len(segments)-1], "*")
+}
+
+// パスパラメータの値の制約
+// パターンのパスパラメータの後に<名前>を指定する。(例: /friend/:number<int>)
+// 組み込みの制約の名前以外は正規表現として扱い、セグメント全体が一致する必要がある。(例: /report/:date<\d{4}-\d{2}-\d{2}>)
+// 制約に一致しないリクエストは、そのルートにマッチしない(他に一致するルートが無ければ404となる)。
+type paramConstraint struct {
+	// パターンに指定した制約(例: int、\d{4}-\d{2}-\d{2})
+	name  string
+	match func(string) bool
+}
+
+func (c *paramConstraint) equal(other *paramConstraint) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return c.name == other.name
+}
+
+// 組み込みのパスパラメータの制約
+var paramConstraints = map[string]func(string) bool{
+	// 64bitの符号付き整数
+	"int": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	},
+	// 64bitの符号なし整数
+	"uint": func(s string) bool {
+		_, err := strconv.ParseUint(s, 10, 64)
+		return err == nil
+	},
+	// UUID(例: 0976b7cd-988b-45a7-a48a-af527c1ed9e3)
+	"uuid": func(s string) bool {
+		return len(s) == 36 && uuid.Validate(s) == nil
+	},
+}
+
+// パスパラメータのセグメントを名前と制約に分割する。
+// 例: ":number<int>" -> "number", int
+// 制約が無い場合はnilを返す。制約の形式が不正、または正規表現としても不正な場合はokにfalseを返す。
+func splitParam(seg string) (name string, constraint *paramConstraint, ok bool) {
+	name = seg[1:]
+	i := strings.Index(name, "<")
+	if i < 0 {
+		return name, nil, true
+	}
+	if !strings.HasSuffix(name, ">") {
+		return "", nil, false
+	}
+	c := name[i+1 : len(name)-1]
+	match, ok := paramConstraints[c]
+	if !ok {
+		re, err := regexp.Compile(`^(?:` + c + `)$`)
+		if c == "" || err != nil {
+			return "", nil, false
+		}
+		match = re.MatchString
+	}
+	return name[:i], &paramConstraint{name: c, match: match}, true
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// パスパラメータの値をデコードせずにそのまま(パーセントエンコードされた形式で)受け取るか
+var rawPathParams = false
+
+// パスパラメータの値をデコードせずに受け取るかを設定する。
+// デフォルトでは、パスパラメータの値はRFC 3986に従ってセグメントごとにデコードされる。(例: %E3%81%82 -> あ、a%2Fb -> a/b)
+// 制約(例: :id<int>)の判定と、固定のセグメントとの比較は、設定によらずデコードした値で行う。
+func SetRawPathParams(raw bool) {
+	mustNotStarted("SetRawPathParams")
+	rawPathParams = raw
+}
+
+func paramValue(raw string, decoded string) string {
+	if rawPathParams {
+		return raw
+	}
+	return decoded
+}
+
+// エスケープされたセグメントをデコードする。
+// "%"を含まない場合はそのまま返す。(アロケーションしない)
+// エスケープが不正な場合はokにfalseを返す。
+func unescapeSegment(seg string) (string, bool) {
+	if strings.IndexByte(seg, '%') < 0 {
+		return seg, true
+	}
+	s, err := url.PathUnescape(seg)
+	return s, err == nil
+}
+
+// ルーティングに使用するパス(エスケープされたパス)を返す。
+func routingPath(r *http.Request) string {
+	return r.URL.EscapedPath()
+}
+
+// パターンaが、パターンbにマッチするすべてのパスにマッチするかどうか
+// 例: /files/*path は /files/:name と /files/readme を含む。
+func patternCovers(a []string, b []string) bool {
+	for i, sa := range a {
+		if strings.HasPrefix(sa, "*") {
+			return i < len(b)
+		}
+		if i >= len(b) {
+			return false
+		}
+		sb := b[i]
+		switch {
+		case strings.HasPrefix(sb, "*"):
+			return false
+		case strings.HasPrefix(sa, ":"):
+			_, ca, _ := splitParam(sa)
+			if ca == nil {
+				continue
+			}
+			if strings.HasPrefix(sb, ":") {
+				if _, cb, _ := splitParam(sb); !ca.equal(cb) {
+					return false
+				}
+			} else if !ca.match(sb) {
+				return false
+			}
+		default:
+			if sa != sb {
+				return false
+			}
+		}
+	}
+	return len(a) == len(b)
+}
+
+// 同じメソッドのルートのうち、優先順位(Route.Priority)によって他のルートに完全に隠れる(マッチしなくなる)ルートがあればpanicとなる。
+// 優先順位が同じ場合は、より具体的なルートが優先されるため隠れることはない。
+// 優先順位はルートの登録後に設定するため、登録時ではなく起動時に検証する。
+func mustNotShadowedRoutes() {
+	if !routePriorityUsed {
+		return
+	}
+	type entry struct {
+		method   string
+		pattern  string
+		ru       *route
+		segments []string
+	}
+	entries := make([]entry, 0, len(patternIndex))
+	for key, ru := range patternIndex {
+		method, _, _ := strings.Cut(key, " ")
+		// Aliasのパスも同じ優先順位のルートとして検証する。
+		for _, pattern := range append([]string{ru.pattern}, ru.aliases...) {
+			segments, _ := parsePattern(pattern)
+			entries = append(entries, entry{method: method, pattern: pattern, ru: ru, segments: segments})
+		}
+	}
+	// panicのメッセージが実行ごとに変わらないようにする。
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Or(cmp.Compare(a.method, b.method), cmp.Compare(a.pattern, b.pattern))
+	})
+	for _, a := range entries {
+		for _, b := range entries {
+			if a.method == b.method && a.ru.priority > b.ru.priority && patternCovers(a.segments, b.segments) {
+				panic(fmt.Sprintf(PanicShadowedRoute, b.pattern, a.pattern))
+			}
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRouter$ ./server
+func TestRouter(t *testing.T) {
+	resetSetting()
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		ru := matchedRoute(r)
+		vals := make([]string, 0, len(ru.pathParamNames))
+		for _, name := range ru.pathParamNames {
+			vals = append(vals, name+"="+getPathParamVal(r, name))
+		}
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+strings.Join(vals, ",")))
+	}
+	Get("/", echo)
+	Get("/users/:id", echo)
+	Get("/users/:id/posts/:postID", echo)
+	Get("/users/me/posts/latest", echo)
+	Get("/users/:userID/files/*path", echo)
+	Get("/orgs/:org/repos/:repo/issues/:number", echo)
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"成功：ルート", "/", http.StatusOK, "/ "},
+		{"成功：パスパラメータ", "/users/1", http.StatusOK, "/users/:id id=1"},
+		{"成功：複数のパスパラメータ", "/users/1/posts/2", http.StatusOK, "/users/:id/posts/:postID id=1,postID=2"},
+		{"成功：3つのパスパラメータ", "/orgs/a/repos/b/issues/3", http.StatusOK, "/orgs/:org/repos/:repo/issues/:number org=a,repo=b,number=3"},
+		{"成功：固定のセグメントが優先", "/users/me/posts/latest", http.StatusOK, "/users/me/posts/latest "},
+		{"成功：固定のセグメントにマッチしない場合はパスパラメータに戻る", "/users/me/posts/1", http.StatusOK, "/users/:id/posts/:postID id=me,postID=1"},
+		{"成功：パスパラメータとワイルドカード", "/users/1/files/a/b.txt", http.StatusOK, "/users/:userID/files/*path userID=1,path=a/b.txt"},
+		{"失敗：セグメントが足りない", "/users/1/posts", http.StatusNotFound, string(noMethodResponse)},
+		{"失敗：セグメントが多い", "/users/1/posts/2/3", http.StatusNotFound, string(noMethodResponse)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("成功：パラメータ名が異なっても同じ位置なら重複としてpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicSameRoot, "/users/:uid/posts/:pid")))
+		}()
+		Get("/users/:uid/posts/:pid", echo)
+	})
+
+	for _, pattern := range []string{"users", "/users/:", "/a/:id/b/:id", "/:a/:b/:c/:d/:e/:f/:g/:h/:i"} {
+		t.Run("失敗：不正なパターンはpanic("+pattern+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidPattern, pattern)))
+			}()
+			Get(pattern, echo)
+		})
+	}
+
+	t.Run("成功：登録済みのルートの検索", func(t *testing.T) {
+		testutil.AssertEqual(t, getRoute("/users/:x/posts/:y", http.MethodGet).pattern, "/users/:id/posts/:postID")
+		testutil.AssertEqual(t, getRoute("/users/:x/files/*rest", http.MethodGet).pattern, "/users/:userID/files/*path")
+		testutil.AssertEqual(t, getRoute("/users/:x/posts", http.MethodGet) == nil, true)
+		testutil.AssertEqual(t, getRoute("/users/:x", http.MethodPost) == nil, true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouterParamConstraint$ ./server
+func TestRouterParamConstraint(t *testing.T) {
+	resetSetting()
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		name := matchedRoute(r).pathParamNames[0]
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+getPathParamVal(r, name)))
+	}
+	Get("/friend/:number<int>", echo)
+	Get("/item/:id<uuid>", echo)
+	Get("/item/:name", echo)
+	Get("/page/:n<uint>", echo)
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"成功：int", "/friend/-12", http.StatusOK, "/friend/:number<int> -12"},
+		{"失敗：intではない", "/friend/abc", http.StatusNotFound, string(noMethodResponse)},
+		{"失敗：intの範囲外", "/friend/9223372036854775808", http.StatusNotFound, string(noMethodResponse)},
+		{"成功：uint", "/page/3", http.StatusOK, "/page/:n<uint> 3"},
+		{"失敗：uintではない", "/page/-3", http.StatusNotFound, string(noMethodResponse)},
+		{"成功：制約を持つルートが優先", "/item/0976b7cd-988b-45a7-a48a-af527c1ed9e3", http.StatusOK, "/item/:id<uuid> 0976b7cd-988b-45a7-a48a-af527c1ed9e3"},
+		{"成功：制約に一致しない場合は制約の無いルート", "/item/book", http.StatusOK, "/item/:name book"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("失敗：同じ制約の同じ位置のルートはpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicSameRoot, "/friend/:id<int>")))
+		}()
+		Get("/friend/:id<int>", echo)
+	})
+
+	for _, pattern := range []string{"/x/:id<int", "/x/:<int>"} {
+		t.Run("失敗：不正な制約はpanic("+pattern+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidPattern, pattern)))
+			}()
+			Get(pattern, echo)
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouterRegexpConstraint$ ./server
+func TestRouterRegexpConstraint(t *testing.T) {
+	resetSetting()
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+getPathParamVal(r, "date")))
+	}
+	Get(`/report/:date<\d{4}-\d{2}-\d{2}>`, echo)
+	Get(`/report/:date<latest|oldest>/summary`, echo)
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"成功：正規表現に一致", "/report/2024-01-31", http.StatusOK, `/report/:date<\d{4}-\d{2}-\d{2}> 2024-01-31`},
+		{"失敗：正規表現に一致しない", "/report/2024-1-31", http.StatusNotFound, string(noMethodResponse)},
+		{"失敗：セグメントの一部のみ一致", "/report/x2024-01-31", http.StatusNotFound, string(noMethodResponse)},
+		{"成功：選択", "/report/latest/summary", http.StatusOK, "/report/:date<latest|oldest>/summary latest"},
+		{"失敗：選択の一部のみ一致", "/report/latestx/summary", http.StatusNotFound, string(noMethodResponse)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	for _, pattern := range []string{`/x/:id<[0-9>`, `/x/:id<>`, `/x/:id<\d+/\d+>`} {
+		t.Run("失敗：不正な正規表現はpanic("+pattern+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidPattern, pattern)))
+			}()
+			Get(pattern, echo)
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRoutePriority$ ./server
+func TestRoutePriority(t *testing.T) {
+	resetSetting()
+
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		ru := matchedRoute(r)
+		vals := make([]string, 0, len(ru.pathParamNames))
+		for _, name := range ru.pathParamNames {
+			vals = append(vals, name+"="+getPathParamVal(r, name))
+		}
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+strings.Join(vals, ",")))
+	}
+	Get("/files/:name/:sub", echo)
+	Get("/files/a/*path", echo).Priority(1)
+	Get("/files/a/readme", echo).Priority(2)
+	Get("/users/:id/profile", echo)
+	Get("/users/me/:tab", echo).Priority(-1)
+	Any("/any/:id", echo).Priority(10)
+	Get("/any/*rest", echo)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"成功：ワイルドカードがパスパラメータより優先", "/files/a/b", "/files/a/*path path=b"},
+		{"成功：優先順位の高い固定のセグメント", "/files/a/readme", "/files/a/readme "},
+		{"成功：ワイルドカードにマッチしないパス", "/files/b/c", "/files/:name/:sub name=b,sub=c"},
+		{"成功：優先順位の低い固定のセグメントより優先", "/users/me/profile", "/users/:id/profile id=me"},
+		{"成功：他にマッチしなければ優先順位の低いルート", "/users/me/settings", "/users/me/:tab tab=settings"},
+		{"成功：メソッドのルートはAnyより優先", "/any/1", "/any/*rest rest=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouterDecodePathParams$ ./server
+func TestRouterDecodePathParams(t *testing.T) {
+	setup := func(raw bool) {
+		resetSetting()
+		SetRawPathParams(raw)
+		echo := func(w http.ResponseWriter, r *http.Request) {
+			name := matchedRoute(r).pathParamNames[0]
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+getPathParamVal(r, name)))
+		}
+		Get("/friend/:name", echo)
+		Get("/friend/:name/posts", echo)
+		Get("/number/:n<int>", echo)
+		Get("/files/*path", echo)
+		Get("/あ/:id", echo)
+	}
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	for _, tt := range []struct {
+		name   string
+		raw    bool
+		target string
+		code   int
+		body   string
+	}{
+		{"成功：デコードされる", false, "/friend/%E3%81%82", http.StatusOK, "/friend/:name あ"},
+		{"成功：%2Fはセグメントの区切りにならない", false, "/friend/a%2Fb/posts", http.StatusOK, "/friend/:name/posts a/b"},
+		{"成功：制約はデコードした値で判定", false, "/number/%31%32", http.StatusOK, "/number/:n<int> 12"},
+		{"成功：ワイルドカードもデコードされる", false, "/files/a%2Fb/c%20d", http.StatusOK, "/files/*path a/b/c d"},
+		{"成功：固定のセグメントもデコードして比較", false, "/%E3%81%82/1", http.StatusOK, "/あ/:id 1"},
+		{"成功：デコードしない", true, "/friend/a%2Fb/posts", http.StatusOK, "/friend/:name/posts a%2Fb"},
+		{"成功：デコードしない場合も制約はデコードした値で判定", true, "/number/%31%32", http.StatusOK, "/number/:n<int> %31%32"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(tt.raw)
+			w := do(tt.target)
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("失敗：不正なエスケープのセグメントにはマッチしない", func(t *testing.T) {
+		setup(false)
+		var ps pathParamValues
+		testutil.AssertEqual(t, lookupRoute(http.MethodGet, "/friend/%ZZ", &ps) == nil, true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteShadowed$ ./server
+// 優先順位による隠れたルートは、HTTPHandler(サーバーの起動)の時点で検出される。
+func TestRouteShadowed(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	for _, tt := range []struct {
+		name     string
+		register func()
+		expect   any
+	}{
+		{"失敗：優先順位の高いワイルドカードに隠れる", func() {
+			Get("/files/:name", handler)
+			Get("/files/*path", handler).Priority(1)
+		}, fmt.Sprintf(PanicShadowedRoute, "/files/:name", "/files/*path")},
+		{"失敗：後から登録したルートが隠れる", func() {
+			Get("/files/*path", handler).Priority(1)
+			Get("/files/readme", handler)
+		}, fmt.Sprintf(PanicShadowedRoute, "/files/readme", "/files/*path")},
+		{"失敗：制約に一致する固定のセグメント", func() {
+			Get("/items/:id<int>", handler).Priority(1)
+			Get("/items/123", handler)
+		}, fmt.Sprintf(PanicShadowedRoute, "/items/123", "/items/:id<int>")},
+		{"失敗：優先順位の低いルートが隠れる", func() {
+			Get("/items/:id", handler)
+			Get("/items/latest", handler).Priority(-1)
+		}, fmt.Sprintf(PanicShadowedRoute, "/items/latest", "/items/:id")},
+		{"成功：優先順位が同じ場合は隠れない", func() {
+			Get("/user/:id", handler)
+			Get("/user/profile", handler)
+			Get("/user/*rest", handler)
+		}, nil},
+		{"成功：一部のみ重なる", func() {
+			Get("/items/:id<int>", handler).Priority(1)
+			Get("/items/latest", handler)
+			Get("/items/:id/:sub", handler)
+		}, nil},
+		{"成功：メソッドが異なる", func() {
+			Get("/files/*path", handler).Priority(1)
+			Post("/files/readme", handler)
+		}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSetting()
+			defer func() {
+				testutil.AssertEqual(t, recover(), tt.expect)
+			}()
+			tt.register()
+			HTTPHandler()
+		})
+	}
+}
+
+// 多数のルートを登録する。
+func setupLargeRouteTable() {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	for i := range 1000 {
+		Get(fmt.Sprintf("/api/v1/resource%d", i), handler)
+		Get(fmt.Sprintf("/api/v1/resource%d/:id<int>/items/:itemID", i), handler)
+		Post(fmt.Sprintf("/api/v1/resource%d/:id", i), handler)
+	}
+	Get("/api/v1/users/:id/posts/:postID/comments/:commentID", handler)
+	Get("/static/*path", handler)
+	Any("/proxy/:target...", handler)
+}
+
+// ルートの検索でメモリを割り当てないことを確認する。
+// (%を含むセグメントのデコードは、デコードした値の文字列を割り当てるため対象外)
+// go test -v -count=1 -timeout 60s -run ^TestLookupRouteAllocs$ ./server
+func TestLookupRouteAllocs(t *testing.T) {
+	resetSetting()
+	setupLargeRouteTable()
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"成功：固定のパス", http.MethodGet, "/api/v1/resource999"},
+		{"成功：パスパラメータ(制約有り)", http.MethodGet, "/api/v1/resource500/1/items/abc"},
+		{"成功：複数のパスパラメータ", http.MethodGet, "/api/v1/users/1/posts/2/comments/3"},
+		{"成功：ワイルドカード", http.MethodGet, "/static/css/app.css"},
+		{"成功：Anyのルート", http.MethodDelete, "/proxy/example.com/a"},
+		{"失敗：ルートが無い", http.MethodGet, "/api/v2/none"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ps pathParamValues
+			allocs := testing.AllocsPerRun(100, func() {
+				ps = pathParamValues{}
+				lookupRoute(tc.method, tc.path, &ps)
+			})
+			testutil.AssertEqual(t, allocs, float64(0))
+		})
+	}
+}
+
+// ルートの検索のみのベンチマーク(パスパラメータのcontextへのセットやハンドラの実行を含まない)
+// go test -count=1 -run ^$ -bench ^BenchmarkLookupRoute$ -benchmem ./server
+func BenchmarkLookupRoute(b *testing.B) {
+	resetSetting()
+	setupLargeRouteTable()
+
+	for _, bm := range []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"固定のパス", http.MethodGet, "/api/v1/resource999"},
+		{"パスパラメータ(制約有り)", http.MethodGet, "/api/v1/resource500/1/items/abc"},
+		{"複数のパスパラメータ", http.MethodGet, "/api/v1/users/1/posts/2/comments/3"},
+		{"ワイルドカード", http.MethodGet, "/static/css/app.css"},
+		{"Anyのルート", http.MethodDelete, "/proxy/example.com/a"},
+		{"ルートが無い", http.MethodGet, "/api/v2/none"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var ps pathParamValues
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				ps = pathParamValues{}
+				lookupRoute(bm.method, bm.path, &ps)
+			}
+		})
+	}
+	resetSetting()
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// 登録されているルートの情報
+type RouteInfo struct {
+	// メソッド(Anyで登録したルートは"*")
+	Method string
+	// 登録時のパス(例: /friend/:number<int>)
+	Pattern string
+	// パスパラメータの名前(パターンでの出現順)
+	ParamNames []string
+	// ルートごとのミドルウェアの数(Router、Mountで追加したミドルウェアを含み、共通のミドルウェアは含まない)
+	MiddlewareCount int
+	// ルートの説明(Route.Descriptionで設定)
+	Description string
+	// ルートのメタデータ(Route.Metadataで設定。設定していない場合はnil)
+	Metadata map[string]any
+	// 同じルートとして扱う別のパス(Aliasで設定)
+	Aliases []string
+}
+
+// 登録されているルートの情報をパス、メソッドの順に並べて返す。(NotFound、Router.NotFoundのハンドラは含まない)
+// 起動時のルートの一覧の出力や、ドキュメントの生成に利用する。
+//
+//	for _, ri := range server.Routes() {
+//		fmt.Printf("%-7s %s\n", ri.Method, ri.Pattern)
+//	}
+func Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(patternIndex))
+	for key, ru := range patternIndex {
+		if ru.notFound {
+			continue
+		}
+		method, _, _ := strings.Cut(key, " ")
+		routes = append(routes, RouteInfo{
+			Method:          method,
+			Pattern:         ru.pattern,
+			ParamNames:      slices.Clone(ru.pathParamNames),
+			MiddlewareCount: len(ru.middleware),
+			Description:     ru.description,
+			Metadata:        maps.Clone(ru.metadata),
+			Aliases:         slices.Clone(ru.aliases),
+		})
+	}
+	slices.SortFunc(routes, func(a, b RouteInfo) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+// ルートにメタデータ(必要な権限、タグなど)を設定する。同じキーを設定した場合は上書きする。
+// メタデータはRoutesで取得でき、ミドルウェアではRouteMetadataで取得できる。
+// ドキュメントの生成や、権限によるアクセス制御などに利用する。
+//
+//	server.Get("/users/:id", getUser).Metadata("scopes", []string{"user:read"}).Metadata("tags", []string{"user"})
+func (rt *Route) Metadata(key string, val any) *Route {
+	if rt.ru.metadata == nil {
+		rt.ru.metadata = map[string]any{}
+	}
+	rt.ru.metadata[key] = val
+	return rt
+}
+
+// リクエストのルートに設定されたメタデータを返す。
+// ルーティング処理の前のミドルウェアからも利用できる。
+// ルートが無い場合、またはキーが設定されていない場合はnil, falseを返す。
+//
+//	v, _ := server.RouteMetadata(r, "scopes")
+//	scopes, _ := v.([]string)
+func RouteMetadata(r *http.Request, key string) (any, bool) {
+	ru := requestRoute(r)
+	if ru == nil {
+		return nil, false
+	}
+	val, ok := ru.metadata[key]
+	return val, ok
+}
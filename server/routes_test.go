@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRoutes$ ./server
+func TestRoutes(t *testing.T) {
+	resetSetting()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	passThrough := func(next http.Handler) http.Handler { return next }
+	Post("/users/:id/posts/:postID<int>", handler, passThrough)
+	Get("/users", handler)
+	Any("/files/*path", handler)
+	admin := NewRouter(passThrough)
+	admin.Delete("/users/:id", handler, passThrough)
+	admin.NotFound(handler)
+	Mount("/admin", admin)
+
+	var got []string
+	for _, ri := range Routes() {
+		got = append(got, fmt.Sprintf("%s %s [%s] %d", ri.Method, ri.Pattern, strings.Join(ri.ParamNames, ","), ri.MiddlewareCount))
+	}
+	testutil.AssertEqual(t, strings.Join(got, "\n"), strings.Join([]string{
+		"DELETE /admin/users/:id [id] 2",
+		"* /files/*path [path] 0",
+		"GET /users [] 0",
+		"POST /users/:id/posts/:postID<int> [id,postID] 1",
+	}, "\n"))
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteMetadata$ ./server
+func TestRouteMetadata(t *testing.T) {
+	resetSetting()
+
+	// メタデータの権限によるアクセス制御
+	requireScope := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, _ := RouteMetadata(r, "scopes")
+			scopes, _ := v.([]string)
+			for _, scope := range scopes {
+				if !strings.Contains(r.Header.Get("X-Scopes"), scope) {
+					SetResponse(w, r, ContentTypePlainText, http.StatusForbidden, []byte("forbidden"))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	SetCommonMiddleware(requireScope)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("ok"))
+	}
+	Get("/users/:id", handler).
+		Description("ユーザーの取得").
+		Metadata("scopes", []string{"user:read"}).
+		Metadata("tags", []string{"user"})
+	Get("/healthz", handler)
+
+	t.Run("成功：Routesでメタデータを取得できる", func(t *testing.T) {
+		var got []string
+		for _, ri := range Routes() {
+			scopes, _ := ri.Metadata["scopes"].([]string)
+			tags, _ := ri.Metadata["tags"].([]string)
+			got = append(got, fmt.Sprintf("%s %s [%s] [%s]", ri.Pattern, ri.Description, strings.Join(scopes, ","), strings.Join(tags, ",")))
+		}
+		testutil.AssertEqual(t, strings.Join(got, "\n"), strings.Join([]string{
+			"/healthz  [] []",
+			"/users/:id ユーザーの取得 [user:read] [user]",
+		}, "\n"))
+	})
+
+	t.Run("成功：ミドルウェアでメタデータを取得できる", func(t *testing.T) {
+		for _, tc := range []struct {
+			target string
+			scopes string
+			code   int
+		}{
+			{"/users/1", "user:read", http.StatusOK},
+			{"/users/1", "", http.StatusForbidden},
+			{"/healthz", "", http.StatusOK},
+		} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			r.Header.Set("X-Scopes", tc.scopes)
+			HTTPHandler().ServeHTTP(w, r)
+			testutil.AssertEqual(t, w.Code, tc.code)
+		}
+	})
+
+	t.Run("失敗：ルートが無い場合は取得できない", func(t *testing.T) {
+		_, ok := RouteMetadata(httptest.NewRequest(http.MethodGet, "/none", nil), "scopes")
+		testutil.AssertEqual(t, ok, false)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRoutePattern$ ./server
+func TestRoutePattern(t *testing.T) {
+	resetSetting()
+
+	// ルーティング処理の前に、リクエストを複製するミドルウェアの外側で記録する
+	var patterns []string
+	SetCommonMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			patterns = append(patterns, RoutePattern(r))
+		})
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeNameKey{}, "copy")))
+		})
+	})
+	Get("/friend/:number", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(RoutePattern(r)))
+	})
+	Any("/files/*path", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		want   string
+	}{
+		{"成功：パスパラメータを含むルート", http.MethodGet, "/friend/1", "/friend/:number"},
+		{"成功：Anyのルート", http.MethodPost, "/files/a/b", "/files/*path"},
+		{"失敗：ルートが無い場合は空文字", http.MethodGet, "/none", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			patterns = nil
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+			testutil.AssertEqual(t, strings.Join(patterns, ","), tc.want)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/friend/2", nil))
+	testutil.AssertEqual(t, w.Body.String(), "/friend/:number")
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// 複数のインスタンスの間で排他制御を行うロック
+// 定期実行のジョブ(Schedule)を1つのインスタンスでのみ実行するために利用する。
+// Redis、etcdなどを利用して実装する。(StoreLockerを参照)
+type Locker interface {
+	// keyのロックをttlの期間取得する。既に他で取得されている場合はfalseを返す。
+	TryLock(c context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// StoreのSetNXを利用したLocker
+// redisstore.StoreなどのStoreをそのままLockerとして利用できる。
+type StoreLocker struct {
+	Store Store
+}
+
+func (s StoreLocker) TryLock(c context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.Store.SetNX(c, key, []byte("1"), ttl)
+}
+
+// 定期実行のジョブ
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	job      func(c context.Context) error
+}
+
+var (
+	scheduledJobs = []scheduledJob{}
+
+	// nilの場合は各インスタンスでジョブを実行する。
+	scheduleLocker Locker
+)
+
+// サーバーの起動中にintervalごとに実行するジョブを登録する。
+// サーバーの起動前に呼び出す必要がある。
+// ジョブはサーバーの起動後に開始され、シャットダウンの開始時にcontextがキャンセルされる。
+// エラーとpanicはログに出力し、次回以降の実行は継続する。
+// 前回の実行が終わっていない場合、その回の実行はスキップされる。
+// SetScheduleLockerでLockerを設定した場合は、複数のインスタンスのうち1つでのみ実行される。
+// nameが重複している場合、intervalが0以下の場合はpanicとなる。
+func Schedule(name string, interval time.Duration, job func(c context.Context) error) {
+	mustNotStarted("Schedule")
+	if interval <= 0 {
+		panic(fmt.Sprintf("schedule interval must be positive, got %s", interval))
+	}
+	for _, j := range scheduledJobs {
+		if j.name == name {
+			panic(fmt.Sprintf("job %s is already scheduled", name))
+		}
+	}
+	scheduledJobs = append(scheduledJobs, scheduledJob{name: name, interval: interval, job: job})
+}
+
+// 定期実行のジョブを複数のインスタンスで協調するためのLockerを設定する。
+// ジョブは実行の周期ごとにロック(キーは"schedule:ジョブ名:周期の開始時刻")を取得したインスタンスでのみ実行される。
+// ロックは周期の間保持され、解放しない。
+// サーバーの起動前に呼び出す必要がある。
+func SetScheduleLocker(locker Locker) {
+	mustNotStarted("SetScheduleLocker")
+	scheduleLocker = locker
+}
+
+// 登録されたジョブを開始する。
+// 戻り値の関数はジョブを停止し、実行中のジョブの終了を待機する。
+func startScheduledJobs(c context.Context, jobs []scheduledJob, locker Locker) (stop func()) {
+	ctx, cancel := context.WithCancel(c)
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case now := <-ticker.C:
+					runScheduledJob(ctx, j, locker, now)
+				}
+			}
+		}()
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func runScheduledJob(c context.Context, j scheduledJob, locker Locker, now time.Time) {
+	defer func() {
+		if rv := recover(); rv != nil {
+			l.Error(c, fmt.Sprintf("panic in scheduled job %s: %v\n%s", j.name, rv, debug.Stack()))
+		}
+	}()
+	if locker != nil {
+		key := fmt.Sprintf("schedule:%s:%d", j.name, now.Truncate(j.interval).Unix())
+		ok, err := locker.TryLock(c, key, j.interval)
+		if err != nil {
+			l.Error(c, fmt.Sprintf("failed to lock scheduled job %s: %s", j.name, err))
+			return
+		}
+		if !ok {
+			// 他のインスタンスで実行されている。
+			return
+		}
+	}
+	if err := j.job(c); err != nil {
+		l.Error(c, fmt.Sprintf("scheduled job %s failed: %s", j.name, err))
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestScheduledJobs$ ./server
+func TestScheduledJobs(t *testing.T) {
+	t.Run("成功：Lockerを共有する複数のインスタンスのうち1つで実行される", func(t *testing.T) {
+		var runs atomic.Int64
+		jobs := []scheduledJob{{name: "count", interval: 50 * time.Millisecond, job: func(c context.Context) error {
+			runs.Add(1)
+			return nil
+		}}}
+		locker := StoreLocker{Store: NewMemoryStore()}
+		stop1 := startScheduledJobs(context.Background(), jobs, locker)
+		stop2 := startScheduledJobs(context.Background(), jobs, locker)
+		time.Sleep(275 * time.Millisecond)
+		stop1()
+		stop2()
+		// 5周期分(タイミングによって前後する)
+		if n := runs.Load(); n < 4 || n > 6 {
+			t.Errorf("unexpected runs: %d", n)
+		}
+	})
+
+	t.Run("成功：エラーやpanicの後も実行が継続される", func(t *testing.T) {
+		lg := &recordLogger{}
+		SetLogger(lg)
+		defer SetLogger(&defaultLogger{})
+		var runs atomic.Int64
+		jobs := []scheduledJob{{name: "fail", interval: 20 * time.Millisecond, job: func(c context.Context) error {
+			if runs.Add(1) == 1 {
+				panic("dummy panic")
+			}
+			return errors.New("dummy error")
+		}}}
+		stop := startScheduledJobs(context.Background(), jobs, nil)
+		time.Sleep(70 * time.Millisecond)
+		stop()
+		if runs.Load() < 2 || !lg.contains("panic in scheduled job fail") || !lg.contains("scheduled job fail failed: dummy error") {
+			t.Errorf("unexpected result: runs=%d logs=%v", runs.Load(), lg.logs)
+		}
+	})
+
+	t.Run("成功：シャットダウンでcontextがキャンセルされる", func(t *testing.T) {
+		resetSetting()
+		canceled := make(chan struct{})
+		Schedule("wait", 10*time.Millisecond, func(c context.Context) error {
+			<-c.Done()
+			close(canceled)
+			return nil
+		})
+		go StartServer(context.Background(), "127.0.0.1", 8094)
+		time.Sleep(100 * time.Millisecond)
+		Shutdown()
+		select {
+		case <-canceled:
+		default:
+			t.Error("job context should be canceled")
+		}
+	})
+
+	t.Run("失敗：重複した名前はpanic", func(t *testing.T) {
+		resetSetting()
+		Schedule("dup", time.Second, func(c context.Context) error { return nil })
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		Schedule("dup", time.Second, func(c context.Context) error { return nil })
+	})
+}
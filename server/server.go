@@ -1,30 +1,61 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"runtime"
+	"runtime/pprof"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 /*
 
 [パスパラメータについて]
+server.Get("/users/:id/posts/:postID", ...)のように、"/"で区切ったセグメントに":名前"を指定すると
+その位置の値をパスパラメータとして受け取る。(1つのルートに最大8個)
+":number<int>"のように名前の後に制約(int、uint、uuid)を指定すると、制約に一致しない値のリクエストは
+そのルートにマッチしない。(他にマッチするルートが無ければ404となる)
+制約には":date<\d{4}-\d{2}-\d{2}>"のように正規表現も指定できる。(セグメント全体が一致する必要があり、"/"は含められない)
 ハンドラーには例として下記のような２つのパスを同時に登録可能な仕様としている。
 ・server.Get("/user/:id", ...)
 ・server.Get("/user/profile", ...)
-しかし、このようなパスの登録は非推奨。
-これは実際にリクエストパスとして"/user/profile"を実行しても、後者にはヒットせず、
-前者に id = profileとしてマッチする。
-
-ハンドラーの登録時に上記を弾くことも考えたが、
-実装が面倒(つまり、/user/* かつGETを探して存在する場合にエラーとする)
+この場合、パスパラメータを含まないルートが優先される。
+つまりリクエストパスとして"/user/profile"を実行すると後者にヒットし、
+それ以外の"/user/xxx"は前者に id = xxxとしてマッチする。
+
+また、server.Get("/files/*path", ...)のようにパスの末尾に"*名前"を指定すると、
+"/files/"以降の残りのパス全体(例: "/files/a/b.txt"の場合は"a/b.txt")をパスパラメータとして受け取る。
+"/proxy/:target..."のように":名前..."と指定した場合も同じワイルドカードとなる。
+値は他のパスパラメータと同様に、Bindでparamタグ(例: `param:"target"`)を指定したstringのフィールドで受け取れる。
+ワイルドカードのルートは、パスパラメータを含まないルート、パスパラメータを含むルートのいずれにも
+マッチしない場合に使用される。
+
+[ルートの優先順位]
+リクエストに対して複数のルートがマッチする場合は、登録の順番によらず下記の順で決定する。
+1. リクエストのメソッドで登録したルートを、パスの先頭のセグメントから順に
+   1-1. 固定のセグメント
+   1-2. パスパラメータのセグメント(制約(例: :number<int>)を持つものが先)
+   1-3. ワイルドカード
+   の順で探す。残りのセグメントでマッチするルートが無い場合は、次の候補に戻って探す。
+   (そのため、パスの完全一致 > パスパラメータ > ワイルドカードとなり、ワイルドカードはプレフィックスが長いものが優先される)
+2. Anyで登録したルート(1と同じ順で検索する)
+ただし、Route.Priorityで優先順位を設定した場合は、同じメソッドでマッチするルートのうち優先順位が最も高いルートとなる。
+(優先順位が同じ場合は上記の順。メソッドで登録したルートがAnyより優先されるのは変わらない)
+同じメソッド、同じパス(パラメータ名を除く)のルートは登録時にpanicとなるため、
+マッチするルートは常に1つに決まる。
+また、Route.Priorityによって他のルートに完全に隠れる(どのパスでもマッチしなくなる)ルートがある場合は起動時にpanicとなる。
+優先順位が同じ場合は具体的なルートが優先されるため、/user/:idと/user/profileのように重なるルートも
+それぞれマッチするパスがあり、隠れることはない。
 
 */
 
@@ -32,15 +63,221 @@ type Handler func(http.ResponseWriter, *http.Request)
 
 type Middleware func(h http.Handler) http.Handler
 
+// パスパラメータの名前と値
+type pathParam struct {
+	name  string
+	value string
+}
+
+// 1つのルートに指定できるパスパラメータの最大数
+const maxPathParams = 8
+
 // リクエストパスをパースして取得したパスパラメータを格納するためのテーブル
-// キーはパラメータ名、バリューがパラメータの値
-type pathParamTable map[string]string
+// リクエストごとにマップを生成しないように固定長の配列で保持する。
+// テーブル自体をリクエストのcontextとして利用し、context.WithValueによる割り当てを省いている。
+// GuardMiddleware、TimeoutMiddlewareやハンドラ内で起動したgoroutineは、リクエストの処理の完了後も
+// パスパラメータを参照する場合があるため、テーブルは再利用しない。
+type pathParamTable struct {
+	context.Context
+	params [maxPathParams]pathParam
+	n      int
+}
+
+var pathParamContextKey = contextKey{Key: "pathParam"}
+
+func (t *pathParamTable) Value(key any) any {
+	if key == pathParamContextKey {
+		return t
+	}
+	return t.Context.Value(key)
+}
+
+func (t *pathParamTable) get(name string) string {
+	for i := range t.n {
+		if t.params[i].name == name {
+			return t.params[i].value
+		}
+	}
+	return ""
+}
+
+func (t *pathParamTable) set(name string, value string) {
+	if t.n >= maxPathParams {
+		panic("too many path parameters")
+	}
+	t.params[t.n] = pathParam{name: name, value: value}
+	t.n++
+}
 
 type route struct {
+	// 登録時に指定されたパス(例: /friend/:number)
+	pattern string
 	handler Handler
-	// 今のところ、path parameterは1つしか使えない。
-	pathParamName string
-	middleware    []Middleware
+	// パスパラメータの名前(パターンでの出現順。ワイルドカードの場合は最後になる)
+	pathParamNames []string
+	middleware     []Middleware
+	// ミドルウェアの実行前にリクエストのcontextへセットする値
+	values []routeValue
+	// リクエストのコスト(重み)。0の場合は1として扱う。
+	cost int64
+	// Bindするリクエストの構造体の型(Route.Requestで設定)
+	requestType reflect.Type
+	// レスポンスの型(Route.Responseで設定)
+	responseType reflect.Type
+	// ルートの説明(Route.Descriptionで設定)
+	description string
+	// レスポンスを圧縮しない(Route.NoCompressで設定)
+	noCompress bool
+	// リクエスト・レスポンスの例(Route.Exampleで設定)
+	examples []routeExample
+	// panicの際にレスポンスを返す関数(Route.Recoverで設定)
+	recover func(w http.ResponseWriter, r *http.Request, rv any)
+	// アクセスログ、メトリクスなどの対象外とする(Route.NoObservabilityで設定)
+	noObservability bool
+	// サイトマップに含めるか(Route.Sitemap、Route.NoSitemapで設定)
+	sitemap sitemapMode
+	// サイトマップに含めるパス(パスパラメータを含むルートの場合)
+	sitemapPaths []string
+	// Router.NotFoundで設定したルート
+	notFound bool
+	// 複数のルートにマッチする場合の優先順位(Route.Priorityで設定)
+	priority int
+	// ルートのメタデータ(Route.Metadataで設定)
+	metadata map[string]any
+	// 同じルートとして扱う別のパス(Aliasで設定)
+	aliases []string
+	// 実行しない名前付きのミドルウェア(Route.SkipCommonで設定)
+	skipMiddleware []string
+}
+
+type routeValue struct {
+	key any
+	val any
+}
+
+// 登録したルートに対して追加の設定を行うためのハンドル
+// Get、Postなどの戻り値として取得し、メソッドをつなげて設定する。
+// 設定はサーバーの起動前に行う必要がある。
+//
+//	server.Get("/user/:id", h, auth).WithValue(scopeKey{}, "user:read")
+type Route struct {
+	ru *route
+}
+
+// ルートのミドルウェアが実行される前に、リクエストのcontextへ値をセットする。
+// ルート名や必要な権限などをセットしておくことで、
+// 汎用的なミドルウェアがルートごとに振る舞いを変えられる。
+// 値はr.Context().Value(key)で取得する。
+// キーの衝突を避けるため、context.WithValueと同様に独自の型をキーとして利用すること。
+func (rt *Route) WithValue(key any, val any) *Route {
+	rt.ru.values = append(rt.ru.values, routeValue{key: key, val: val})
+	return rt
+}
+
+// リクエストのコスト(重み)を設定する。デフォルトは1。
+// RateLimitMiddlewareやUsageMiddlewareはリクエスト数ではなくこのコストを消費するため、
+// 重いエンドポイントに大きな値を設定することで、負荷に見合った制限ができる。
+// レート制限の上限(burst、limit)を超える場合は、その上限として消費する。
+// nが1未満の場合はpanicとなる。
+func (rt *Route) Cost(n int64) *Route {
+	if n < 1 {
+		panic(fmt.Sprintf("route cost must be positive, got %d", n))
+	}
+	rt.ru.cost = n
+	return rt
+}
+
+// ルートのハンドラ、ミドルウェアでpanicが発生した際にレスポンスを返す関数を設定する。
+// 共通の500エラーのレスポンス(SetInternalServerErrorResponse)より優先される。
+// 決済など、panicの際に特定のエラーコードを返す必要があるルートで利用する。
+// panicの値とスタックトレースは共通の処理と同様にErrorで出力される。
+// ルーティング処理の前に実行されるミドルウェア(SetCommonMiddleware)でのpanicは対象外となる。
+//
+//	server.Post("/payments", pay).Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+//		server.SetResponseAsJson(w, r, http.StatusInternalServerError, map[string]string{"code": "PAYMENT_UNKNOWN"})
+//	})
+func (rt *Route) Recover(f func(w http.ResponseWriter, r *http.Request, rv any)) *Route {
+	rt.ru.recover = f
+	return rt
+}
+
+// 複数のルートにマッチする場合の優先順位を設定する。(デフォルトは0)
+// 数値が大きいルートが優先され、同じ優先順位の場合は通常の優先順位(固定のセグメント > パスパラメータ > ワイルドカード)となる。
+// 優先順位は同じメソッドのルートの間でのみ比較される。
+// 優先順位によって他のルートに完全に隠れる(どのパスでもマッチしなくなる)ルートがある場合は、
+// サーバーの起動時(HTTPHandlerの場合は呼び出し時)にpanicとなる。
+//
+//	server.Get("/users/me/:tab", myPage)
+//	server.Get("/users/:id/profile", profile).Priority(1) // /users/me/profile もこちらにマッチする
+func (rt *Route) Priority(p int) *Route {
+	rt.ru.priority = p
+	if p != 0 {
+		routePriorityUsed = true
+	}
+	return rt
+}
+
+// ルーティングで確定したルートを返す。
+// ルートが確定していない場合(ルーティング処理の前、またはルートが無い場合)はnilを返す。
+func matchedRoute(r *http.Request) *route {
+	if r.Pattern == "" {
+		return nil
+	}
+	if ru, ok := patternIndex[r.Method+" "+r.Pattern]; ok {
+		return ru
+	}
+	return patternIndex[methodAny+" "+r.Pattern]
+}
+
+// リクエストのルートを返す。
+// ルーティング処理の前のミドルウェアからも利用できるように、ルートが確定していない場合は検索する。
+// ルートが無い場合はnilを返す。
+func requestRoute(r *http.Request) *route {
+	if ru := matchedRoute(r); ru != nil {
+		return ru
+	}
+	var ps pathParamValues
+	return lookupRoute(r.Method, routingPath(normalizeRequestPath(r)), &ps)
+}
+
+// ヘルスチェックやメトリクスの収集など、頻繁に呼ばれるルートをアクセスログ、メトリクスなどの対象外とする。
+// AccessLogMiddleware、RequestStatsMiddleware、ServerTimingMiddlewareは対象のルートで何もせず、
+// GetLimiterStatsではルートごとに集計しない("-"として集計する)。
+// 独自のトレースやメトリクスのミドルウェアでは、IsObservabilityDisabledで判定する。
+//
+//	server.Get("/healthz", health).NoObservability()
+func (rt *Route) NoObservability() *Route {
+	rt.ru.noObservability = true
+	return rt
+}
+
+// リクエストのルートがRoute.NoObservabilityで対象外とされているかを返す。
+// ルーティング処理の前のミドルウェアからも利用できる。
+func IsObservabilityDisabled(r *http.Request) bool {
+	ru := requestRoute(r)
+	return ru != nil && ru.noObservability
+}
+
+// リクエストのルートの登録時のパス(例: /friend/:number)を返す。ルートが無い場合は空文字を返す。
+// ログやメトリクスを具体的なURLではなくパターンで集計する場合に利用する。(ラベルの種類が増え続けることを防ぐ)
+// ルーティング処理の前のミドルウェアからも利用できる。
+// (r.Patternはルートの確定後にセットされるため、ミドルウェアがリクエストを複製した場合などは参照できない)
+//
+//	requests.WithLabelValues(r.Method, server.RoutePattern(r)).Inc()
+func RoutePattern(r *http.Request) string {
+	if ru := requestRoute(r); ru != nil {
+		return ru.pattern
+	}
+	return ""
+}
+
+// リクエストのルートに設定されたコストを返す。
+// コストが設定されていない場合、またはルートが確定していない場合は1を返す。
+func RouteCost(r *http.Request) int64 {
+	if ru := matchedRoute(r); ru != nil && ru.cost > 0 {
+		return ru.cost
+	}
+	return 1
 }
 
 // 設定情報
@@ -54,7 +291,16 @@ type route struct {
 // そのため、各変数もスレッドセーフとはなっていない。
 var (
 	// ルーティング情報を格納する
-	router = map[string]route{}
+	// キーはメソッドで、値はそのメソッドのルーティングの木のルート(根)のノード
+	router = map[string]*node{}
+
+	// パスパラメータ、ワイルドカードを含まないルートを、木を辿らずに1回のマップの検索で見つけるためのマップ
+	// キーはメソッド、パスで、木より先に検索する。
+	staticRouter = map[string]map[string]*route{}
+
+	// 登録時のパスからルートを検索するためのインデックス
+	// キーは"METHOD pattern"(例: GET /friend/:number)
+	patternIndex = map[string]*route{}
 
 	commonMiddleware = []Middleware{}
 
@@ -74,30 +320,107 @@ var (
 
 	// Graceful shutdown時にタイムアウトとして設定する秒数
 	ShutdownTimeoutSecond = 8 * time.Second
+
+	// シャットダウンの信号を受け取ってから、Graceful shutdownを開始するまでの待機時間
+	// この間はReadinessHandlerが503を返し、リクエストの受け付けは継続する。
+	// Kubernetesなどでロードバランサーが対象から外すまでの猶予として設定する。
+	ShutdownDelay time.Duration = 0
 )
 
 const (
-	ContentTypeJSON            = "application/json"
-	ContentTypeXML             = "application/xml"
-	ContentTypePlainText       = "text/plain"
-	ContentTypeHTML            = "text/html"
-	ContentTypeFormURLEnc      = "application/x-www-form-urlencoded"
-	ContentTypeMultipart       = "multipart/form-data"
-	ContentTypeHTMLWithCharset = "text/html; charset=utf-8"
+	ContentTypeJSON                 = "application/json"
+	ContentTypeXML                  = "application/xml"
+	ContentTypePlainText            = "text/plain"
+	ContentTypeHTML                 = "text/html"
+	ContentTypeFormURLEnc           = "application/x-www-form-urlencoded"
+	ContentTypeMultipart            = "multipart/form-data"
+	ContentTypeHTMLWithCharset      = "text/html; charset=utf-8"
+	ContentTypePlainTextWithCharset = "text/plain; charset=utf-8"
 )
 
+// 任意のメソッドのハンドラの設定
+// PROPFINDなどのGet、Postなどの関数が無いメソッドを登録する。
+// methodがHTTPのメソッドとして不正(空文字、区切り文字を含む)な場合はpanicになる。
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+func Handle(method string, path string, hr Handler, middleware ...Middleware) *Route {
+	if !isValidMethod(method) {
+		panic(fmt.Sprintf(PanicInvalidMethod, method))
+	}
+	return setHandler(path, hr, method, middleware...)
+}
+
+// メソッドがRFC 9110のtokenかどうか
+func isValidMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, c := range method {
+		if !(c == '!' || c == '#' || c == '$' || c == '%' || c == '&' || c == '\'' ||
+			c == '*' || c == '+' || c == '-' || c == '.' || c == '^' || c == '_' || c == '`' || c == '|' || c == '~' ||
+			'0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Anyで登録したルートのキーに使用するメソッド
+const methodAny = "*"
+
 // GETメソッドのハンドラの設定
 // 既に存在するパスかつメソッドを設定するとpanicになる。
 // ミドルウェアは先頭から順に実行されていく。
-func Get(path string, hr Handler, middleware ...Middleware) {
-	setHandler(path, hr, http.MethodGet, middleware...)
+func Get(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodGet, middleware...)
 }
 
 // POSTメソッドのハンドラの設定
 // 既に存在するパスかつメソッドを設定するとpanicになる。
 // ミドルウェアは先頭から順に実行されていく。
-func Post(path string, hr Handler, middleware ...Middleware) {
-	setHandler(path, hr, http.MethodPost, middleware...)
+func Post(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodPost, middleware...)
+}
+
+// PUTメソッドのハンドラの設定
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+// ミドルウェアは先頭から順に実行されていく。
+func Put(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodPut, middleware...)
+}
+
+// PATCHメソッドのハンドラの設定
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+// ミドルウェアは先頭から順に実行されていく。
+func Patch(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodPatch, middleware...)
+}
+
+// DELETEメソッドのハンドラの設定
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+// ミドルウェアは先頭から順に実行されていく。
+func Delete(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodDelete, middleware...)
+}
+
+// HEADメソッドのハンドラの設定
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+// ミドルウェアは先頭から順に実行されていく。
+func Head(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodHead, middleware...)
+}
+
+// OPTIONSメソッドのハンドラの設定
+// 既に存在するパスかつメソッドを設定するとpanicになる。
+// ミドルウェアは先頭から順に実行されていく。
+func Options(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, http.MethodOptions, middleware...)
+}
+
+// すべてのメソッドに対応するハンドラの設定
+// 同じパスにメソッドを指定したルートがある場合は、そちらが優先される。
+// 既に同じパスでAnyを設定しているとpanicになる。
+func Any(path string, hr Handler, middleware ...Middleware) *Route {
+	return setHandler(path, hr, methodAny, middleware...)
 }
 
 // 共通のミドルウェア
@@ -105,6 +428,7 @@ func Post(path string, hr Handler, middleware ...Middleware) {
 // このミドルウェアはルーティング処理の前に動作する。
 // 共通のミドルウェア -> 個々のミドルウェア -> 共通の後続ミドルウェア -> ルーティング処理 -> ハンドラ処理
 func SetCommonMiddleware(m ...Middleware) {
+	mustNotStarted("SetCommonMiddleware")
 	commonMiddleware = m
 }
 
@@ -114,13 +438,16 @@ func SetCommonMiddleware(m ...Middleware) {
 // 先頭から順に実行されていく
 // このミドルウェアはルーティング処理の後に動作するため、ルートが確定する前に処理が終了した場合は実行されない。
 // 例えば、no mothodの場合は実行されない。
+// ハンドラの前に実行されるため、ハンドラの処理結果を参照する場合はSetCommonPostHandlerを利用する。
 func SetCommonAfterMiddleware(m ...Middleware) {
+	mustNotStarted("SetCommonAfterMiddleware")
 	commonAfterMiddleware = m
 }
 
 // ルートが見つからない場合のレスポンスを設定する
 // デフォルトはapplication/jsonで空のjson
 func SetNoMethodResponse(contentType string, data []byte) {
+	mustNotStarted("SetNoMethodResponse")
 	noMethodContentType = contentType
 	noMethodResponse = data
 }
@@ -128,6 +455,7 @@ func SetNoMethodResponse(contentType string, data []byte) {
 // 500エラーの場合のレスポンスを設定する
 // デフォルトはapplication/jsonで空のjson
 func SetInternalServerErrorResponse(contentType string, data []byte) {
+	mustNotStarted("SetInternalServerErrorResponse")
 	internalServerErrorContentType = contentType
 	internalServerErrorResponse = data
 }
@@ -135,15 +463,52 @@ func SetInternalServerErrorResponse(contentType string, data []byte) {
 // "application/json"としてレスポンスを返す
 // dataはjson.Marshalで変換を行ってレスポンスへセットする。
 // json.Marshalで変換に失敗した場合はpanicとなる。
+// 構造体のフィールドに"scope"タグがある場合は、リクエストのPrincipalのスコープに応じて出力を制限する。(mask.goを参照)
 func SetResponseAsJson(w http.ResponseWriter, r *http.Request, statusCode int, data any) {
-	jsn, err := json.Marshal(data)
-	if err != nil {
+	buf := jsonBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		// 大きなレスポンスで拡張されたバッファはプールに戻さない。
+		if buf.Cap() <= maxPooledJsonBufferSize {
+			jsonBufferPool.Put(buf)
+		}
+	}()
+	SetResponseAsJsonWithBuffer(w, r, statusCode, buf, data)
+}
+
+// SetResponseAsJsonと同様だが、JSONへの変換に呼び出し側が用意したバッファを利用する。
+// bufはリセットしてから利用されるため、同じバッファを繰り返し渡すことでリクエストごとの確保を避けられる。
+// 関数から戻った後はbufの内容を再利用してよい。
+func SetResponseAsJsonWithBuffer(w http.ResponseWriter, r *http.Request, statusCode int, buf *bytes.Buffer, data any) {
+	buf.Reset()
+	enc := json.NewEncoder(buf)
+	if prettyJson {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(maskByScope(r, data)); err != nil {
 		panic(err)
 	}
 
-	SetResponse(w, r, ContentTypeJSON, statusCode, jsn)
+	// json.Marshalと同じ結果になるように、Encodeが末尾に付与する改行を除く。
+	SetResponse(w, r, ContentTypeJSON, statusCode, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// SetResponseAsJsonで整形したJSONを返すかどうか
+var prettyJson = false
+
+// SetResponseAsJsonで整形(インデント)したJSONを返すかを設定する。
+// 開発時の確認用であり、デフォルトは無効。
+func SetPrettyJson(b bool) {
+	mustNotStarted("SetPrettyJson")
+	prettyJson = b
 }
 
+// SetResponseAsJsonで利用するバッファのプール
+var jsonBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+const maxPooledJsonBufferSize = 64 << 10
+
 func SetResponse(w http.ResponseWriter, r *http.Request, contentType string, statusCode int, data []byte) {
 	// headerのSetは、WriteHeader関数の前に呼ぶ必要がある。
 	// 後に呼んでも変更が発生しない。
@@ -157,8 +522,38 @@ func SetResponse(w http.ResponseWriter, r *http.Request, contentType string, sta
 // この関数を実行する前に、各ハンドラの設定を行う必要がある。
 // シャットダウンはGraceful shutdownとなる。
 func StartServer(c context.Context, host string, port int) {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", host, port),
+		Handler: HTTPHandler(),
+	}
+	serve(c, srv, serveConfig{shutdownTimeout: ShutdownTimeoutSecond, shutdownDelay: ShutdownDelay})
+}
+
+// panicのリカバリー、ミドルウェア、ルーティングを含むハンドラを返す。
+// StartServerを使わずに、既存のnet/httpのサーバーやhttptest.NewServerへ組み込む場合に利用する。
+// 他のライブラリのミドルウェアで包むことや、http.StripPrefixでプレフィックス配下に組み込むこともできる。
+//
+//	http.Handle("/", server.HTTPHandler())
+//	mux.Handle("/v1/", http.StripPrefix("/v1", server.HTTPHandler()))
+//	ts := httptest.NewServer(server.HTTPHandler())
+func HTTPHandler() http.Handler {
+	mustNotShadowedRoutes()
+	return http.HandlerFunc(recoverHandler)
+}
+
+// serveの設定
+type serveConfig struct {
+	// certFileとkeyFileが指定された場合はTLSで起動する。
+	certFile        string
+	keyFile         string
+	shutdownTimeout time.Duration
+	shutdownDelay   time.Duration
+}
+
+// サーバーを起動し、シャットダウンの信号を待機する。
+func serve(c context.Context, srv *http.Server, conf serveConfig) {
 	// 各パスごとにHandle関数でハンドラを設定するのではなく、
-	// ルート（"/"）に対して、ルートとなるハンドラを設定している。
+	// サーバーのハンドラとして、ルートとなるハンドラ(srv.Handler)を1つだけ設定している。
 	// recoverHandlerは後続処理でpanicが発生した場合のリカバリーとスタックトレース、
 	// 後続のroutingHandlerは、リクエストパスを開発者が登録したルーティング情報（ハンドラ／ミドルウェア）から検索して実行する。
 	//
@@ -166,41 +561,139 @@ func StartServer(c context.Context, host string, port int) {
 	// 上記の方がコードを簡潔に書けそうだったため。
 	// また、無効なパスも一旦はすべてハンドリングする構成にしたかったため。
 	// （ ※ http.Handle("/aaa") http.Handle("/bbb") ... といった具合。）
-	http.Handle("/", http.HandlerFunc(recoverHandler))
-	srv := &http.Server{Addr: fmt.Sprintf("%s:%d", host, port)}
 
-	// ここでgo routineを使うのはmainのスレッドではgraceful shutdownの待機をしておくため。
-	go func() {
-		// ListenAndServeでは、リクエストが来るたびにスレッドが起動される。
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	// systemdのソケットアクティベーションで渡されたリスナーがあればそれを利用する。
+	listen := func() net.Listener {
+		listener, err := sdActivatedListener()
+		if err != nil {
 			panic(fmt.Sprintf("somethig error happend on server start: %s", err))
 		}
-	}()
+		if listener == nil {
+			if listener, err = net.Listen("tcp", srv.Addr); err != nil {
+				panic(fmt.Sprintf("somethig error happend on server start: %s", err))
+			}
+		}
+		return listener
+	}
+
+	runServer(c, conf.shutdownTimeout, conf.shutdownDelay, func() {
+		listener := listen()
+		// ここでgo routineを使うのはmainのスレッドではgraceful shutdownの待機をしておくため。
+		go func() {
+			// Serveでは、リクエストが来るたびにスレッドが起動される。
+			var err error
+			if conf.certFile != "" {
+				err = srv.ServeTLS(listener, conf.certFile, conf.keyFile)
+			} else {
+				err = srv.Serve(listener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				panic(fmt.Sprintf("somethig error happend on server start: %s", err))
+			}
+		}()
+	}, func(ctx context.Context) error {
+		// net/httpのShutdown関数はgraceful shutdownを行う。
+		return srv.Shutdown(ctx)
+	})
+}
+
+// サーバーの起動からシャットダウンまでの共通の処理を行う。(StartServer、StartFastCGIServerで利用する)
+// startでリクエストの受け付けを開始し、起動時の処理、readiness、systemdへの通知、定期実行のジョブを開始する。
+// シャットダウンの信号を受け取ると、shutdownDelayの待機の後にstopで受け付けを停止する。
+// stopに渡すcontextはshutdownTimeoutでキャンセルされる。
+func runServer(c context.Context, shutdownTimeout time.Duration, shutdownDelay time.Duration, start func(), stop func(ctx context.Context) error) {
+	// 以降は設定の変更を受け付けない。
+	if !started.CompareAndSwap(false, true) {
+		panic("server is already started")
+	}
+	mustNotShadowedRoutes()
+
+	// Shutdown関数から参照されるため、受け付けの開始前に生成する。
+	resetShutdownSignal()
+	start()
+
+	// 起動時の処理が成功した場合に、systemdへ起動完了を通知する。
+	startedAt.Store(time.Now().UnixNano())
+	if err := runStartupHooks(c); err != nil {
+		l.Error(c, err.Error())
+	} else {
+		ready.Store(true)
+		if err := sdNotify("READY=1"); err != nil {
+			l.Warn(c, fmt.Sprintf("failed to notify systemd: %s", err))
+		}
+	}
+	// watchdogを開始する。
+	watchdogCtx, stopWatchdog := context.WithCancel(c)
+	defer stopWatchdog()
+	startSdWatchdog(watchdogCtx)
+	stopScheduledJobs := startScheduledJobs(c, scheduledJobs, scheduleLocker)
 
 	// シャットダウンの信号待機
-	shutdown = make(chan any, 1)
 	defer close(shutdown) // ここでcloseしないと本ファイルのShutdown関数が待ち続けてしまう。
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, os.Interrupt)
-	select {
-	case sig := <-quit:
-		// osからのシグナルで終了
-		l.Info(c, fmt.Sprintf("quit received: %v", sig))
-	case sig := <-shutdown: // Shutdown関数からチャネル送信してシャットダウン
-		l.Info(c, fmt.Sprintf("shutdown received: %v", sig))
+	// Shutdown関数から戻った時点で設定を変更できるように、closeの前に実行する。
+	defer started.Store(false)
+	waitForShutdownSignal(c)
+	ready.Store(false)
+	sdNotify("STOPPING=1")
+	stopScheduledJobs()
+	if shutdownDelay > 0 {
+		// ロードバランサーがreadinessの失敗を検知して対象から外すまで、リクエストの受け付けを継続する。
+		l.Info(c, fmt.Sprintf("waiting %s before shutdown", shutdownDelay))
+		time.Sleep(shutdownDelay)
 	}
 
 	// シャットダウン処理。タイムアウトを過ぎるとシャットダウン処理がキャンセルされる。
-	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeoutSecond)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		// net/httpのShutdown関数はgraceful shutdownを行う。
+	if err := stop(ctx); err != nil {
 		// Error from closing listeners, or context timeout:
 		panic(fmt.Sprintf("Failed to gracefully shutdown:%s", err))
 	}
+	shutdownPlugins(ctx)
 	l.Info(c, "Server successfully shutdowned")
 }
 
+// graceful shutdownを開始するシグナル
+// デフォルトはプラットフォームごとに異なる。(signal_unix.go、signal_windows.go)
+var shutdownSignals = defaultShutdownSignals()
+
+// graceful shutdownを開始するシグナルを設定する。
+// コンテナのSTOPSIGNALにSIGQUITなどが指定されている環境で利用する。
+// サーバーの起動前に呼び出す必要がある。
+func SetShutdownSignals(sigs ...os.Signal) {
+	mustNotStarted("SetShutdownSignals")
+	shutdownSignals = sigs
+}
+
+// シャットダウンの信号を待機する前に、shutdownチャネルを生成する。
+// 前回の起動の停止中に受け付けたdrainの要求は破棄する。
+func resetShutdownSignal() {
+	select {
+	case <-drainRequests:
+	default:
+	}
+	shutdownMu.Lock()
+	shutdown = make(chan any, 1)
+	shutdownMu.Unlock()
+}
+
+// OSのシグナル、Shutdown関数、またはDrainHandlerからの送信を待機する。
+// 呼び出し側でresetShutdownSignalによりshutdownチャネルを生成しておく必要がある。
+func waitForShutdownSignal(c context.Context) {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, shutdownSignals...)
+	defer signal.Stop(quit)
+	select {
+	case sig := <-quit:
+		// osからのシグナルで終了
+		l.Info(c, fmt.Sprintf("quit received: %v", sig))
+	case sig := <-shutdown: // Shutdown関数からチャネル送信してシャットダウン
+		l.Info(c, fmt.Sprintf("shutdown received: %v", sig))
+	case reason := <-drainRequests:
+		l.Info(c, fmt.Sprintf("shutdown received: %s", reason))
+	}
+}
+
 // context.Contextにセットする値の衝突を避けるために独自のキーを使う。
 type contextKey struct{ Key string }
 
@@ -217,28 +710,37 @@ func getPathParamVal(r *http.Request, pathParamName string) string {
 		// pathParamが初期化されていないケースは想定外。
 		panic("pathParam ")
 	}
-	return pathParam.(pathParamTable)[pathParamName]
+	return pathParam.(*pathParamTable).get(pathParamName)
+}
+
+// panicの値とスタックトレースをErrorで出力する。
+// recoverを行ったdeferの関数から直接呼び出す必要がある。
+func logPanic(r *http.Request, rv any) {
+	stack := make([]uintptr, 32)
+	// runtime.Callers(0), 本関数(1), deferの関数(2), panic関数(3) をスキップしてエラー箇所を起点とする。
+	n := runtime.Callers(4, stack)
+	stack = stack[:n]
+	frames := runtime.CallersFrames(stack)
+	var trace string
+	for {
+		frame, more := frames.Next()
+		trace += fmt.Sprintf("%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	l.Error(r.Context(), fmt.Sprintf("panic(server recovered): %v\n", rv)+trace)
 }
 
 func recoverHandler(w http.ResponseWriter, r *http.Request) {
+	// 後処理(SetCommonPostHandler)はpanicのレスポンスの後に実行する。
+	w, finish := startPostProcess(w, r)
+	defer finish()
 	// panicはスタックトレースを出力してすべてinternal serverエラーとして返す。
 	defer func() {
 		if rv := recover(); rv != nil {
-			stack := make([]uintptr, 32)
-			// runtime.Callers(0), 本箇所(1), panic関数(2) をスキップしてエラー箇所を起点とする。
-			n := runtime.Callers(3, stack)
-			stack = stack[:n]
-			frames := runtime.CallersFrames(stack)
-			var trace string
-			for {
-				frame, more := frames.Next()
-				trace += fmt.Sprintf("%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
-				if !more {
-					break
-				}
-			}
-			l.Error(r.Context(), fmt.Sprintf("panic(server recovered): %v\n", rv)+trace)
-			SetResponse(w, r, internalServerErrorContentType, http.StatusInternalServerError, internalServerErrorResponse)
+			logPanic(r, rv)
+			SetResponse(w, r, internalServerErrorContentType, http.StatusInternalServerError, internalServerErrorResponseFor(r))
 			return
 		}
 	}()
@@ -246,43 +748,123 @@ func recoverHandler(w http.ResponseWriter, r *http.Request) {
 	constructHandlerBeforeRouting(0).ServeHTTP(w, r)
 }
 
+// リクエストパスからルートを検索して実行する。
+// ルートが確定した時点で、http.ServeMuxと同様にr.Patternへ登録時のパス(例: /friend/:number)をセットする。
+// r.Patternは、ルーティング処理の前のミドルウェアからも後続の処理の完了後に参照できる。
 func routingHandler(w http.ResponseWriter, r *http.Request) {
-	// path paramを含むpathに対応するルートを探す
-	if splitPath := strings.Split(r.URL.Path, "/"); len(splitPath) > 2 {
-		pathParamCandidate := string(splitPath[len(splitPath)-1])
-		ru := getRoute(strings.TrimSuffix(r.URL.Path, pathParamCandidate)+":", r.Method)
-		if ru != nil {
-			if ru.pathParamName == "" {
-				panic("path parameter name is empty")
-			}
-			// いま時点だとパスパラメータはひとつしか設定していない。
-			// 今後複数必要になったら改良できるように、pathParamTableはマップになっている。
-			pathParam := pathParamTable{}
-			pathParam[ru.pathParamName] = pathParamCandidate
-			ctx := context.WithValue(r.Context(), contextKey{Key: "pathParam"}, pathParam)
-			r = r.WithContext(ctx)
-
-			constructHandlerAfterRouting(0, ru).ServeHTTP(w, r)
-			return
+	r = normalizeRequestPath(r)
+	var ps pathParamValues
+	ru := lookupRoute(r.Method, routingPath(r), &ps)
+	ru, handled := applyTrailingSlashPolicy(w, r, ru, &ps)
+	if handled {
+		return
+	}
+	if serveAutoOptions(w, r, ru) {
+		return
+	}
+	if ru == nil {
+		// pathに対応するルートが無ければno method
+		SetResponse(w, r, noMethodContentType, http.StatusNotFound, noMethodResponse)
+		return
+	}
+	r.Pattern = ru.pattern
+	if ps.n > 0 {
+		r = withPathParam(r, ru, &ps)
+	}
+	serveRoute(w, r, ru)
+}
+
+// メソッドとパス(エスケープされたパス)に対応するルートを検索する。
+// パスパラメータを含むルートの場合は、パラメータの値をpsへセットする。
+// 対応するルートが無い場合はnilを返す。
+func lookupRoute(method string, path string, ps *pathParamValues) *route {
+	if path == "" || path[0] != '/' {
+		return nil
+	}
+	if ru := lookupStaticRoute(method, path); ru != nil {
+		return ru
+	}
+	if root, ok := router[method]; ok {
+		if ru := root.find(path[1:], ps); ru != nil {
+			return ru
 		}
 	}
+	// メソッドに対応するルートが無ければAnyで登録したルートを探す
+	if ru := lookupStaticRoute(methodAny, path); ru != nil {
+		return ru
+	}
+	if root, ok := router[methodAny]; ok {
+		return root.find(path[1:], ps)
+	}
+	return nil
+}
 
-	// pathに対応するルートを探す
-	ru := getRoute(r.URL.Path, r.Method)
-	if ru != nil {
-		constructHandlerAfterRouting(0, ru).ServeHTTP(w, r)
-		return
+// パスに完全一致するルートをstaticRouterから検索する。
+// 木の検索でも固定のセグメントが最初に探されるため、結果は木の検索と同じとなる。
+// 優先順位(Route.Priority)を設定したルートがある場合は、パスパラメータのルートが優先されることがあるため検索しない。
+func lookupStaticRoute(method string, path string) *route {
+	if routePriorityUsed {
+		return nil
 	}
+	return staticRouter[method][path]
+}
 
-	// pathに対応するルートが無ければno method
-	SetResponse(w, r, noMethodContentType, http.StatusNotFound, noMethodResponse)
+// パスパラメータをテーブルへセットし、テーブルをcontextとするリクエストを返す。
+func withPathParam(r *http.Request, ru *route, ps *pathParamValues) *http.Request {
+	table := &pathParamTable{Context: r.Context()}
+	for i, name := range ru.pathParamNames {
+		table.set(name, ps.vals[i])
+	}
+	return r.WithContext(table)
 }
 
-// 各commonMiddleware -> routingHandlerの順に実行されるハンドラを構築する。
+// ルーティングで確定したルートのミドルウェアとハンドラを実行する。
+func serveRoute(w http.ResponseWriter, r *http.Request, ru *route) {
+	if ru.recover != nil {
+		defer func() {
+			if rv := recover(); rv != nil {
+				logPanic(r, rv)
+				ru.recover(w, r, rv)
+			}
+		}()
+	}
+	if len(ru.values) > 0 {
+		ctx := r.Context()
+		for _, v := range ru.values {
+			ctx = context.WithValue(ctx, v.key, v.val)
+		}
+		r = r.WithContext(ctx)
+	}
+	if isProfilingRoute(r.Method, ru.pattern) {
+		// プロファイル取得中のルートはpprofのラベルを付与して実行する。
+		pprof.Do(r.Context(), pprof.Labels(profileLabelKey, r.Method+" "+ru.pattern), func(ctx context.Context) {
+			constructHandlerAfterRouting(0, ru).ServeHTTP(w, r.WithContext(ctx))
+		})
+		return
+	}
+	if len(ru.middleware) == 0 && len(commonAfterMiddleware) == 0 {
+		// ミドルウェアが無い場合はハンドラのチェーンを構築せずに直接実行する。
+		if isMockRoute(ru) {
+			serveExample(w, r, ru)
+			return
+		}
+		ru.handler(w, r)
+		return
+	}
+	constructHandlerAfterRouting(0, ru).ServeHTTP(w, r)
+}
+
+// 各commonMiddleware -> 各pluginMiddleware -> routingHandlerの順に実行されるハンドラを構築する。
 func constructHandlerBeforeRouting(middleWareIdx int) http.Handler {
 	if middleWareIdx <= len(commonMiddleware)-1 {
 		return commonMiddleware[middleWareIdx](constructHandlerBeforeRouting(middleWareIdx + 1))
 	}
+
+	pluginMiddlewareIdx := middleWareIdx - len(commonMiddleware)
+	if pluginMiddlewareIdx <= len(pluginMiddleware)-1 {
+		return pluginMiddleware[pluginMiddlewareIdx](constructHandlerBeforeRouting(middleWareIdx + 1))
+	}
+
 	return http.HandlerFunc(routingHandler)
 }
 
@@ -299,45 +881,103 @@ func constructHandlerAfterRouting(idx int, ru *route) http.Handler {
 		return commonAfterMiddleware[commonAfterMiddlewareIdx](constructHandlerAfterRouting(idx+1, ru))
 	}
 
+	if isMockRoute(ru) {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { serveExample(w, r, ru) })
+	}
 	return http.HandlerFunc(ru.handler)
 }
 
+// サーバーが起動しているか(起動処理の開始からシャットダウンの完了まで)
+var started atomic.Bool
+
+// サーバーの起動後に設定を変更しようとした場合はpanicとする。
+// 設定はリクエストの処理中にロックせずに参照しているため、起動後の変更はデータ競合となる。
+func mustNotStarted(name string) {
+	if started.Load() {
+		panic(fmt.Sprintf("%s must be called before the server starts", name))
+	}
+}
+
 // テスト用
 // shutdownチャネルはShutdown関数の方で利用するために入れている。
+// サーバーとは別のgoroutineから参照されるため、shutdownMuで保護する。
 var shutdown chan any
+var shutdownMu sync.Mutex
 
 // テスト用。
 // shutdownチャネルに送信され、
 // StartForTest関数の方に書いているチャネル受信処理（select）で受け取り、
 // 後続のシャットダウン処理が走る。
 func Shutdown() {
-	shutdown <- struct{}{}
-	<-shutdown // chがcloseするのを待つ（シャットダウン完了を待つ）
+	shutdownMu.Lock()
+	ch := shutdown
+	shutdownMu.Unlock()
+	ch <- struct{}{}
+	<-ch // chがcloseするのを待つ（シャットダウン完了を待つ）
 }
 
-func getRoute(path string, method string) *route {
-	r, ok := router[method+" "+path]
+// 登録済みのルートのうち、パターンに対応するルートを返す。
+// パスパラメータの名前は区別しない(/users/:id と /users/:name は同じルートとなる)。
+func getRoute(pattern string, method string) *route {
+	root, ok := router[method]
 	if !ok {
 		return nil
 	}
-	return &r
+	segments, _ := parsePattern(pattern)
+	nd, wildcard := root.walk(segments, false)
+	switch {
+	case nd == nil:
+		return nil
+	case wildcard:
+		return nd.wildcard
+	default:
+		return nd.route
+	}
 }
 
-func setHandler(path string, hr Handler, method string, middleware ...Middleware) {
-	paths := strings.Split(path, ":")
-	pathParamName := ""
-	if len(paths) > 1 {
-		path = paths[0] + ":"
-		pathParamName = paths[len(paths)-1]
+func setHandler(pattern string, hr Handler, method string, middleware ...Middleware) *Route {
+	mustNotStarted("route registration")
+	ru := &route{
+		pattern:    pattern,
+		handler:    hr,
+		middleware: middleware,
 	}
+	registerRoute(method, ru)
+	return &Route{ru: ru}
+}
+
+// ルートをメソッドのルーティングの木へ登録する。
+// 同じメソッド、同じパス(パラメータ名を除く)のルートが既にある場合はpanicとなる。
+func registerRoute(method string, ru *route) {
+	ru.pathParamNames = insertRoute(method, ru.pattern, ru)
+	patternIndex[method+" "+ru.pattern] = ru
+}
 
-	if getRoute(path, method) != nil {
-		panic(fmt.Sprintf(PanicSameRoot, path))
+// メソッドのツリーのパターンの位置へルートを追加し、パターンのパスパラメータの名前を返す。
+func insertRoute(method string, pattern string, ru *route) []string {
+	segments, pathParamNames := parsePattern(pattern)
+
+	root, ok := router[method]
+	if !ok {
+		root = &node{}
+		router[method] = root
+	}
+	nd, wildcard := root.walk(segments, true)
+	if (wildcard && nd.wildcard != nil) || (!wildcard && nd.route != nil) {
+		panic(fmt.Sprintf(PanicSameRoot, pattern))
 	}
 
-	router[method+" "+path] = route{
-		handler:       hr,
-		middleware:    middleware,
-		pathParamName: pathParamName,
+	if wildcard {
+		nd.wildcard = ru
+	} else {
+		nd.route = ru
 	}
-}
\ No newline at end of file
+	// 木ではセグメントをデコードしてから比較するため、%を含むパターンはマップへ格納しない。
+	if len(pathParamNames) == 0 && !strings.Contains(pattern, "%") {
+		if staticRouter[method] == nil {
+			staticRouter[method] = map[string]*route{}
+		}
+		staticRouter[method][pattern] = ru
+	}
+	return pathParamNames
+}
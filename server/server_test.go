@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -40,7 +41,29 @@ func resetSetting() {
 	SetInternalServerErrorResponse("application/json", GetErrorResponseJson("something error"))
 	SetCommonAfterMiddleware()
 	SetCommonMiddleware()
-	router = map[string]route{}
+	SetCommonPostHandler()
+	SetCommonHeaderHook()
+	SetPrettyJson(false)
+	plugins = []Plugin{}
+	pluginMiddleware = []Middleware{}
+	router = map[string]*node{}
+	staticRouter = map[string]map[string]*route{}
+	autoOptionsHandler = DefaultAutoOptionsHandler
+	trailingSlashPolicy = TrailingSlashStrict
+	normalizePath = false
+	routePriorityUsed = false
+	rawPathParams = false
+	patternIndex = map[string]*route{}
+	scheduledJobs = []scheduledJob{}
+	scheduleLocker = nil
+	healthChecks = []healthCheck{}
+	localeConfig = nil
+	mockMode = false
+	startupHooks = []func(c context.Context) error{}
+	limiters = []*limiterStat{}
+	jsonLimits = DefaultJsonLimits
+	multipartMaxMemory = DefaultMultipartMaxMemory
+	localizedInternalServerErrorResponses = map[string][]byte{}
 }
 
 // go test -v -count=1 -timeout 60s -run ^TestServer$ ./server
@@ -735,3 +758,590 @@ func newErrRequestJsonSomethingInvalid(json string, err error) *ErrBind {
 		},
 	}
 }
+
+// go test -count=1 -run ^$ -bench ^BenchmarkRouting$ -benchmem ./server
+func BenchmarkRouting(b *testing.B) {
+	passThrough := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for _, bm := range []struct {
+		name  string
+		setup func()
+		path  string
+	}{
+		{
+			name:  "ミドルウェア無し",
+			setup: func() { Get("/bench", handler) },
+			path:  "/bench",
+		},
+		{
+			name: "多数のルート(固定のパス)",
+			setup: func() {
+				for i := range 500 {
+					Get(fmt.Sprintf("/resource%d/:id", i), handler)
+				}
+				Get("/users/me/posts", handler)
+			},
+			path: "/users/me/posts",
+		},
+		{
+			name:  "ミドルウェア無し(パスパラメータ)",
+			setup: func() { Get("/bench/:id", handler) },
+			path:  "/bench/1",
+		},
+		{
+			name: "多数のルート(複数のパスパラメータ)",
+			setup: func() {
+				for i := range 500 {
+					Get(fmt.Sprintf("/resource%d/:id", i), handler)
+				}
+				Get("/users/:id/posts/:postID", handler)
+			},
+			path: "/users/1/posts/2",
+		},
+		{
+			name:  "ルートのミドルウェア有り",
+			setup: func() { Get("/bench", handler, passThrough, passThrough) },
+			path:  "/bench",
+		},
+		{
+			name: "共通の後続ミドルウェア有り",
+			setup: func() {
+				SetCommonAfterMiddleware(passThrough, passThrough)
+				Get("/bench", handler)
+			},
+			path: "/bench",
+		},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			resetSetting()
+			bm.setup()
+			req := httptest.NewRequest(http.MethodGet, bm.path, nil)
+			res := httptest.NewRecorder()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				routingHandler(res, req)
+			}
+		})
+	}
+	resetSetting()
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStaticRoutePrecedence$ ./server
+func TestStaticRoutePrecedence(t *testing.T) {
+	resetSetting()
+	Get("/user/:id", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "id="+getPathParamVal(r, "id")))
+	})
+	Get("/user/profile", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "profile"))
+	})
+
+	t.Run("成功：パスパラメータを含まないルートが優先される", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/user/profile", nil, nil, http.StatusOK, createResponse(true, "profile"))
+	})
+
+	t.Run("成功：それ以外はパスパラメータのルートにマッチする", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/user/1234", nil, nil, http.StatusOK, createResponse(true, "id=1234"))
+	})
+
+	t.Run("成功：パスパラメータを含まないルートのみstaticRouterに格納される", func(t *testing.T) {
+		testutil.AssertEqual(t, staticRouter[http.MethodGet]["/user/profile"] == patternIndex[http.MethodGet+" /user/profile"], true)
+		testutil.AssertEqual(t, staticRouter[http.MethodGet]["/user/:id"] == nil, true)
+	})
+
+	// 登録の順番を逆にしても結果は変わらない。
+	resetSetting()
+	Any("/item/:id", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "any"))
+	})
+	Get("/item/*rest", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "rest="+getPathParamVal(r, "rest")))
+	})
+	Get("/item/:id", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "id="+getPathParamVal(r, "id")))
+	})
+	Get("/item/new", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "new"))
+	})
+
+	t.Run("成功：後から登録したパスパラメータを含まないルートが優先される", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/item/new", nil, nil, http.StatusOK, createResponse(true, "new"))
+	})
+
+	t.Run("成功：ワイルドカードよりパスパラメータが優先される", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/item/1", nil, nil, http.StatusOK, createResponse(true, "id=1"))
+	})
+
+	t.Run("成功：パスパラメータにマッチしない場合はワイルドカード", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/item/1/2", nil, nil, http.StatusOK, createResponse(true, "rest=1/2"))
+	})
+
+	t.Run("成功：メソッドで登録したルートが無い場合はAny", func(t *testing.T) {
+		execRequest(t, http.MethodPost, "/item/1", nil, nil, http.StatusOK, createResponse(true, "any"))
+	})
+
+	Any("/item/list", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, "any list"))
+	})
+
+	t.Run("成功：Anyで登録したパスパラメータを含まないルートより、メソッドで登録したルートが優先される", func(t *testing.T) {
+		execRequest(t, http.MethodGet, "/item/list", nil, nil, http.StatusOK, createResponse(true, "id=list"))
+		execRequest(t, http.MethodPost, "/item/list", nil, nil, http.StatusOK, createResponse(true, "any list"))
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestSetResponseAsJsonWithBuffer$ ./server
+func TestSetResponseAsJsonWithBuffer(t *testing.T) {
+	buf := new(bytes.Buffer)
+	buf.WriteString("garbage")
+	data := createResponse(true, map[string]string{"html": "<a>"})
+
+	res := httptest.NewRecorder()
+	SetResponseAsJsonWithBuffer(res, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, buf, data)
+
+	expect, _ := json.Marshal(data)
+	testutil.AssertEqual(t, res.Body.String(), string(expect))
+	testutil.AssertEqual(t, res.Header().Get("Content-Type"), ContentTypeJSON)
+}
+
+// go test -count=1 -run ^$ -bench ^BenchmarkSetResponseAsJson$ -benchmem ./server
+func BenchmarkSetResponseAsJson(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	data := createResponse(true, getFriendResponse{Friend: friend{ID: "1", Name: "friend1"}})
+	b.ReportAllocs()
+	for range b.N {
+		SetResponseAsJson(httptest.NewRecorder(), req, http.StatusOK, data)
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestHTTPHandler$ ./server
+func TestHTTPHandler(t *testing.T) {
+	resetSetting()
+	Get("/friend/:number", func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, &getFriendRequest{}, &getFriendResponse{}, http.StatusOK, func(req *getFriendRequest) (*friend, error) {
+			return &friend{ID: strconv.Itoa(req.Number)}, nil
+		})
+	})
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	})
+
+	ts := httptest.NewServer(HTTPHandler())
+	defer ts.Close()
+
+	t.Run("成功：httptest.NewServerで利用できる", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/friend/5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		testutil.AssertEqual(t, res.StatusCode, http.StatusOK)
+		testutil.AssertJsonExact(t, IoReaderToString(res.Body), toJsonString(createResponse(true, getFriendResponse{Friend: friend{ID: "5"}})), nil)
+	})
+
+	t.Run("成功：panicはリカバリーされる", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/panic")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		testutil.AssertEqual(t, res.StatusCode, http.StatusInternalServerError)
+	})
+
+	t.Run("成功：他のmuxのプレフィックス配下に組み込める", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.Handle("/v1/", http.StripPrefix("/v1", HTTPHandler()))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/friend/5", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertJsonExact(t, w.Body.String(), toJsonString(createResponse(true, getFriendResponse{Friend: friend{ID: "5"}})), nil)
+	})
+
+	t.Run("成功：外側のミドルウェアで包める", func(t *testing.T) {
+		wrapped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Wrapped", "1")
+			HTTPHandler().ServeHTTP(w, r)
+		})
+		w := httptest.NewRecorder()
+		wrapped.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+		testutil.AssertEqual(t, w.Header().Get("X-Wrapped"), "1")
+	})
+}
+
+type routeNameKey struct{}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteWithValue$ ./server
+func TestRouteWithValue(t *testing.T) {
+	resetSetting()
+	// ルートにセットされた値をもとに動作する汎用的なミドルウェア
+	nameMiddleware := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, _ := r.Context().Value(routeNameKey{}).(string)
+			w.Header().Set("X-Route-Name", name)
+			h.ServeHTTP(w, r)
+		})
+	}
+	SetCommonAfterMiddleware(nameMiddleware)
+	Get("/user/:id", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, r.Context().Value(routeNameKey{})))
+	}).WithValue(routeNameKey{}, "getUser")
+	Get("/user", func(w http.ResponseWriter, r *http.Request) {
+		SetResponseAsJson(w, r, http.StatusOK, createResponse(true, r.Context().Value(routeNameKey{})))
+	})
+
+	t.Run("成功：ミドルウェアとハンドラから値を参照できる", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/user/1", nil))
+		testutil.AssertEqual(t, res.Header().Get("X-Route-Name"), "getUser")
+		expect, _ := json.Marshal(createResponse(true, "getUser"))
+		testutil.AssertEqual(t, res.Body.String(), string(expect))
+	})
+
+	t.Run("成功：値をセットしていないルートには影響しない", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/user", nil))
+		testutil.AssertEqual(t, res.Header().Get("X-Route-Name"), "")
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteRecover$ ./server
+func TestRouteRecover(t *testing.T) {
+	resetSetting()
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+
+	Post("/payments", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy")
+	}).Recover(func(w http.ResponseWriter, r *http.Request, rv any) {
+		SetResponseAsJson(w, r, http.StatusBadGateway, map[string]string{"code": fmt.Sprintf("PAYMENT_UNKNOWN:%v", rv)})
+	})
+	Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy")
+	})
+
+	t.Run("成功：ルートの関数でレスポンスを返す", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/payments", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusBadGateway)
+		testutil.AssertEqual(t, w.Body.String(), `{"code":"PAYMENT_UNKNOWN:dummy"}`)
+		// スタックトレースはpanicの発生箇所から出力される。
+		testutil.AssertEqual(t, lg.contains("panic(server recovered): dummy\ngithub.com/megur0/simple-server/server.TestRouteRecover.func1"), true)
+	})
+
+	t.Run("成功：設定していないルートは共通のレスポンス", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusInternalServerError)
+		testutil.AssertEqual(t, w.Body.String(), string(GetErrorResponseJson("something error")))
+		testutil.AssertEqual(t, lg.contains("panic(server recovered): dummy\ngithub.com/megur0/simple-server/server.TestRouteRecover.func3"), true)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestSetAfterStart$ ./server
+func TestSetAfterStart(t *testing.T) {
+	resetSetting()
+	go StartServer(context.Background(), "127.0.0.1", 8097)
+	time.Sleep(time.Millisecond * 100)
+
+	for name, f := range map[string]func(){
+		"SetCommonMiddleware":            func() { SetCommonMiddleware() },
+		"SetCommonAfterMiddleware":       func() { SetCommonAfterMiddleware() },
+		"SetNoMethodResponse":            func() { SetNoMethodResponse(ContentTypeJSON, nil) },
+		"SetInternalServerErrorResponse": func() { SetInternalServerErrorResponse(ContentTypeJSON, nil) },
+		"route registration":             func() { Get("/after", func(w http.ResponseWriter, r *http.Request) {}) },
+		"OnStartup":                      func() { OnStartup(nil) },
+		"RegisterHealthCheck":            func() { RegisterHealthCheck("after", nil) },
+		"SetShutdownSignals":             func() { SetShutdownSignals() },
+		"Schedule":                       func() { Schedule("after", time.Second, nil) },
+		"SetScheduleLocker":              func() { SetScheduleLocker(nil) },
+		"RegisterPlugin":                 func() { RegisterPlugin(nil) },
+		"SetLogger":                      func() { SetLogger(nil) },
+		"SetMockMode":                    func() { SetMockMode(false) },
+		"RegisterPII":                    func() { RegisterPII() },
+	} {
+		t.Run("失敗：起動後の"+name+"はpanic", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(name+" must be called before the server starts"))
+			}()
+			f()
+		})
+	}
+
+	t.Run("成功：シャットダウン後は設定できる", func(t *testing.T) {
+		Shutdown()
+		SetCommonMiddleware()
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRouteNoObservability$ ./server
+func TestRouteNoObservability(t *testing.T) {
+	resetSetting()
+	defer func() { recentRequests = requestWindow{} }()
+	recentRequests = requestWindow{}
+	lg := &recordLogger{}
+	SetLogger(lg)
+	defer SetLogger(&defaultLogger{})
+	SetCommonMiddleware(AccessLogMiddleware, RequestStatsMiddleware, ServerTimingMiddleware)
+	Get("/healthz", func(w http.ResponseWriter, r *http.Request) {}).NoObservability()
+	Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	testutil.AssertEqual(t, w.Header().Get("Server-Timing"), "")
+	testutil.AssertEqual(t, lg.contains("GET /healthz"), false)
+	requests, _ := recentRequests.snapshot(time.Now())
+	testutil.AssertEqual(t, requests, uint64(0))
+
+	w = httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	testutil.AssertEqual(t, w.Header().Get("Server-Timing") != "", true)
+	testutil.AssertEqual(t, lg.contains("GET /users 200"), true)
+	requests, _ = recentRequests.snapshot(time.Now())
+	testutil.AssertEqual(t, requests, uint64(1))
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestMethodRoutes$ ./server
+func TestMethodRoutes(t *testing.T) {
+	resetSetting()
+
+	echoMethod := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Method+" "+getPathParamVal(r, "id")))
+	}
+	Put("/item/:id", echoMethod)
+	Patch("/item/:id", echoMethod)
+	Delete("/item/:id", echoMethod)
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run("成功："+method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(method, "/item/1", nil))
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), method+" 1")
+		})
+	}
+
+	t.Run("失敗：登録していないメソッド", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/item/1", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusNotFound)
+	})
+
+	t.Run("失敗：同じパスとメソッドの登録はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		Delete("/item/:id", echoMethod)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestAnyRoute$ ./server
+func TestAnyRoute(t *testing.T) {
+	resetSetting()
+
+	echoMethod := func(prefix string) Handler {
+		return func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(prefix+" "+r.Method+" "+r.Pattern))
+		}
+	}
+	Any("/probe", echoMethod("any"))
+	Get("/probe", echoMethod("get"))
+	Head("/head", echoMethod("head"))
+	Options("/item/:id", echoMethod("options"))
+	Any("/item/:id", echoMethod("any"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"成功：メソッドを指定したルートが優先", http.MethodGet, "/probe", "get GET /probe"},
+		{"成功：その他のメソッドはAny", http.MethodPost, "/probe", "any POST /probe"},
+		{"成功：OPTIONS(パスパラメータ)", http.MethodOptions, "/item/1", "options OPTIONS /item/:id"},
+		{"成功：Any(パスパラメータ)", http.MethodDelete, "/item/1", "any DELETE /item/:id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("成功：HEAD", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/head", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+	})
+
+	t.Run("失敗：HEADのみのルートにGET", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/head", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：Anyのルートもミドルウェアから参照できる", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "/probe", nil)
+		testutil.AssertEqual(t, requestRoute(r) != nil, true)
+		r.Pattern = "/probe"
+		testutil.AssertEqual(t, matchedRoute(r) != nil, true)
+	})
+
+	t.Run("失敗：同じパスのAnyの登録はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		Any("/probe", echoMethod("any"))
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestHandle$ ./server
+func TestHandle(t *testing.T) {
+	resetSetting()
+
+	Handle("PROPFIND", "/dav/:name", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusMultiStatus, []byte(r.Method+" "+getPathParamVal(r, "name")))
+	})
+
+	t.Run("成功：任意のメソッド", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest("PROPFIND", "/dav/file.txt", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusMultiStatus)
+		testutil.AssertEqual(t, w.Body.String(), "PROPFIND file.txt")
+	})
+
+	t.Run("失敗：登録していないメソッド", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest("MKCOL", "/dav/file.txt", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusNotFound)
+	})
+
+	for _, method := range []string{"", "GET /x", "PROP\tFIND", "ゲット"} {
+		t.Run("失敗：不正なメソッドはpanic("+method+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidMethod, method)))
+			}()
+			Handle(method, "/invalid", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	}
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestWildcardRoute$ ./server
+func TestWildcardRoute(t *testing.T) {
+	resetSetting()
+
+	echo := func(name string) Handler {
+		return func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(name+" "+r.Pattern+" "+getPathParamVal(r, "path")))
+		}
+	}
+	Get("/files/*path", echo("files"))
+	Get("/files/images/*path", echo("images"))
+	Get("/files/readme", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("readme "+r.Pattern))
+	})
+	Get("/files/:path", echo("param"))
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"成功：残りのパス全体を受け取る", "/files/a/b/c.txt", http.StatusOK, "files /files/*path a/b/c.txt"},
+		{"成功：より深いワイルドカードが優先", "/files/images/x/y.png", http.StatusOK, "images /files/images/*path x/y.png"},
+		{"成功：パスパラメータを含まないルートが優先", "/files/readme", http.StatusOK, "readme /files/readme"},
+		{"成功：パスパラメータを含むルートが優先", "/files/a.txt", http.StatusOK, "param /files/:path a.txt"},
+		{"成功：残りのパスが空", "/files/images/", http.StatusOK, "images /files/images/*path "},
+		{"失敗：プレフィックスに一致しない", "/files", http.StatusNotFound, string(noMethodResponse)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	for _, pattern := range []string{"/x/*", "/x/*rest/more"} {
+		t.Run("失敗：不正なワイルドカードはpanic("+pattern+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidWildcard, pattern)))
+			}()
+			Get(pattern, echo("invalid"))
+		})
+	}
+
+	t.Run("失敗：同じワイルドカードの登録はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		Get("/files/*other", echo("dup"))
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestTailParam$ ./server
+func TestTailParam(t *testing.T) {
+	resetSetting()
+
+	type proxyRequest struct {
+		Target string `param:"target"`
+	}
+	Get("/proxy/:target...", func(w http.ResponseWriter, r *http.Request) {
+		var req proxyRequest
+		if err := Bind(r, &req); err != nil {
+			panic(err)
+		}
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+req.Target))
+	})
+	Get("/proxy/health", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern))
+	})
+
+	for _, tt := range []struct {
+		name string
+		path string
+		code int
+		body string
+	}{
+		{"成功：残りのパス全体をBindで受け取る", "/proxy/example.com/a/b", http.StatusOK, "/proxy/:target... example.com/a/b"},
+		{"成功：1つのセグメント", "/proxy/example.com", http.StatusOK, "/proxy/:target... example.com"},
+		{"成功：固定のセグメントが優先", "/proxy/health", http.StatusOK, "/proxy/health"},
+		{"失敗：プレフィックスに一致しない", "/proxy", http.StatusNotFound, string(noMethodResponse)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			testutil.AssertEqual(t, w.Code, tt.code)
+			testutil.AssertEqual(t, w.Body.String(), tt.body)
+		})
+	}
+
+	t.Run("失敗：ワイルドカードと同じ位置はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicSameRoot, "/proxy/*other")))
+		}()
+		Get("/proxy/*other", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	for _, pattern := range []string{"/x/:...", "/x/:rest.../more"} {
+		t.Run("失敗：不正なパラメータはpanic("+pattern+")", func(t *testing.T) {
+			defer func() {
+				testutil.AssertEqual(t, recover(), any(fmt.Sprintf(PanicInvalidWildcard, pattern)))
+			}()
+			Get(pattern, func(w http.ResponseWriter, r *http.Request) {})
+		})
+	}
+}
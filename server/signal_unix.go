@@ -0,0 +1,13 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// graceful shutdownを開始するシグナルのデフォルト
+func defaultShutdownSignals() []os.Signal {
+	return []os.Signal{syscall.SIGTERM, os.Interrupt}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package server
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestShutdownSignals$ ./server
+func TestShutdownSignals(t *testing.T) {
+	defer SetShutdownSignals(defaultShutdownSignals()...)
+	SetShutdownSignals(syscall.SIGUSR1)
+	resetSetting()
+
+	done := make(chan struct{})
+	go func() {
+		StartServer(context.Background(), "127.0.0.1", 8092)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 100)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("server should shutdown on the configured signal")
+	}
+}
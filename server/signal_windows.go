@@ -0,0 +1,18 @@
+//go:build windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// graceful shutdownを開始するシグナルのデフォルト
+// Windowsでは、Ctrl+C(CTRL_C_EVENT/CTRL_BREAK_EVENT)はos.Interruptとして、
+// コンソールのクローズ、ログオフ、シャットダウン(CTRL_CLOSE_EVENT等)はsyscall.SIGTERMとして通知される。
+//
+// Windowsサービスとして動かす場合は、サービスコントロールマネージャーからの停止要求
+// (golang.org/x/sys/windows/svcなど)を受け取った箇所でShutdown関数を呼び出すこと。
+func defaultShutdownSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// /robots.txtの内容
+type RobotsTxt struct {
+	Groups []RobotsGroup
+	// サイトマップのURL
+	Sitemaps []string
+}
+
+// User-agentごとのルール
+type RobotsGroup struct {
+	// 省略した場合は"*"
+	UserAgent string
+	Allow     []string
+	Disallow  []string
+}
+
+func (rt RobotsTxt) String() string {
+	var b strings.Builder
+	for i, g := range rt.Groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		ua := g.UserAgent
+		if ua == "" {
+			ua = "*"
+		}
+		fmt.Fprintf(&b, "User-agent: %s\n", ua)
+		for _, p := range g.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", p)
+		}
+		for _, p := range g.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", p)
+		}
+	}
+	if len(rt.Sitemaps) > 0 && len(rt.Groups) > 0 {
+		b.WriteString("\n")
+	}
+	for _, s := range rt.Sitemaps {
+		fmt.Fprintf(&b, "Sitemap: %s\n", s)
+	}
+	return b.String()
+}
+
+// /robots.txtのルートを登録する。
+//
+//	server.RegisterRobotsTxt(server.RobotsTxt{
+//		Groups:   []server.RobotsGroup{{Disallow: []string{"/admin/"}}},
+//		Sitemaps: []string{"https://example.com/sitemap.xml"},
+//	})
+func RegisterRobotsTxt(rt RobotsTxt) *Route {
+	body := []byte(rt.String())
+	return Get("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainTextWithCharset, http.StatusOK, body)
+	})
+}
+
+type sitemapMode int8
+
+const (
+	sitemapDefault sitemapMode = iota
+	sitemapInclude
+	sitemapExclude
+)
+
+// ルートをサイトマップ(RegisterSitemap)に含める。
+// パスパラメータを含むルートはURLを列挙できないため、含めるパスをpathsで指定する。
+// パスパラメータを含まないルートはデフォルトで含まれる。
+//
+//	server.Get("/items/:id", item).Sitemap("/items/1", "/items/2")
+func (rt *Route) Sitemap(paths ...string) *Route {
+	rt.ru.sitemap = sitemapInclude
+	rt.ru.sitemapPaths = append(rt.ru.sitemapPaths, paths...)
+	return rt
+}
+
+// ルートをサイトマップ(RegisterSitemap)に含めない。
+func (rt *Route) NoSitemap() *Route {
+	rt.ru.sitemap = sitemapExclude
+	return rt
+}
+
+// サイトマップに含めるパスをソートして返す。
+// パスパラメータを含まないGETのルート(/.well-known/、/robots.txt、/sitemap.xmlを除く)と、
+// Route.Sitemapで指定したパスが対象となり、Route.NoSitemapを設定したルートは除外する。
+func sitemapPaths() []string {
+	var paths []string
+	for key, ru := range patternIndex {
+		if !strings.HasPrefix(key, http.MethodGet+" ") || ru.sitemap == sitemapExclude {
+			continue
+		}
+		if len(ru.pathParamNames) > 0 {
+			paths = append(paths, ru.sitemapPaths...)
+			continue
+		}
+		if ru.sitemap == sitemapDefault && (strings.HasPrefix(ru.pattern, "/.well-known/") || ru.pattern == "/robots.txt" || ru.pattern == "/sitemap.xml") {
+			continue
+		}
+		paths = append(paths, ru.pattern)
+		paths = append(paths, ru.sitemapPaths...)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// サイトマップのXML
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// 登録されているGETのルートから生成した/sitemap.xmlのルートを登録する。(sitemapPathsを参照)
+// baseURLはスキームとホスト(例: "https://example.com")で、各パスの前に付与される。
+// HTMLを返すアプリケーションで利用する。
+func RegisterSitemap(baseURL string) *Route {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return Get("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+		for _, p := range sitemapPaths() {
+			set.URLs = append(set.URLs, sitemapURL{Loc: baseURL + p})
+		}
+		body, err := xml.Marshal(set)
+		if err != nil {
+			panic(err)
+		}
+		SetResponse(w, r, ContentTypeXML, http.StatusOK, append([]byte(xml.Header), body...))
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestRegisterRobotsTxt$ ./server
+func TestRegisterRobotsTxt(t *testing.T) {
+	resetSetting()
+	RegisterRobotsTxt(RobotsTxt{
+		Groups: []RobotsGroup{
+			{Disallow: []string{"/admin/"}},
+			{UserAgent: "BadBot", Disallow: []string{"/"}},
+		},
+		Sitemaps: []string{"https://example.com/sitemap.xml"},
+	})
+	w := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	testutil.AssertEqual(t, w.Header().Get("Content-Type"), ContentTypePlainTextWithCharset)
+	testutil.AssertEqual(t, w.Body.String(), "User-agent: *\nDisallow: /admin/\n\nUser-agent: BadBot\nDisallow: /\n\nSitemap: https://example.com/sitemap.xml\n")
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRegisterSitemap$ ./server
+func TestRegisterSitemap(t *testing.T) {
+	resetSetting()
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	Get("/", h)
+	Get("/about", h)
+	Get("/admin", h).NoSitemap()
+	Get("/items/:id", h).Sitemap("/items/1", "/items/2")
+	Get("/users/:id", h)
+	Post("/contact", h)
+	RegisterRobotsTxt(RobotsTxt{})
+	RegisterSitemap("https://example.com/")
+
+	w := httptest.NewRecorder()
+	HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	testutil.AssertEqual(t, w.Code, http.StatusOK)
+	testutil.AssertEqual(t, w.Header().Get("Content-Type"), ContentTypeXML)
+	testutil.AssertEqual(t, w.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+		`<url><loc>https://example.com/</loc></url>`+
+		`<url><loc>https://example.com/about</loc></url>`+
+		`<url><loc>https://example.com/items/1</loc></url>`+
+		`<url><loc>https://example.com/items/2</loc></url>`+
+		`</urlset>`)
+}
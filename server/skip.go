@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+)
+
+// ミドルウェアに名前を付ける。
+// 名前を付けたミドルウェアは、Route.SkipCommonで名前を指定したルートでは実行されない。
+// SetCommonMiddleware、SetCommonAfterMiddlewareで設定する共通のミドルウェアを、
+// ヘルスチェックなどの一部のルートだけで除外する場合に利用する。
+//
+//	server.SetCommonMiddleware(server.AccessLogMiddleware, server.NamedMiddleware("auth", authMiddleware))
+//	server.Get("/healthz", health).SkipCommon("auth")
+func NamedMiddleware(name string, m Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		h := m(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isMiddlewareSkipped(r, name) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NamedMiddlewareで名前を付けたミドルウェアを、このルートでは実行しないようにする。
+// ルーティング処理の前に実行される共通のミドルウェアでもルートを検索して判定する。
+// 名前に一致するミドルウェアが無い場合は何もしない。
+func (rt *Route) SkipCommon(names ...string) *Route {
+	rt.ru.skipMiddleware = append(rt.ru.skipMiddleware, names...)
+	return rt
+}
+
+// リクエストのルートで、名前付きのミドルウェアが除外されているかを返す。
+func isMiddlewareSkipped(r *http.Request, name string) bool {
+	ru := requestRoute(r)
+	return ru != nil && slices.Contains(ru.skipMiddleware, name)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestSkipCommon$ ./server
+func TestSkipCommon(t *testing.T) {
+	resetSetting()
+
+	var called []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = append(called, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = append(called, "auth")
+			if r.Header.Get("Authorization") == "" {
+				SetResponse(w, r, ContentTypePlainText, http.StatusUnauthorized, []byte("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	SetCommonMiddleware(record("log"), NamedMiddleware("auth", auth))
+	SetCommonAfterMiddleware(NamedMiddleware("audit", record("audit")))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("ok"))
+	}
+	Get("/users", handler)
+	Get("/healthz", handler).SkipCommon("auth", "audit")
+	Get("/public", handler).SkipCommon("auth")
+	Get("/other", handler).SkipCommon("unknown")
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		code   int
+		called string
+	}{
+		{"成功：除外しないルート", "/users", http.StatusUnauthorized, "log,auth"},
+		{"成功：共通のミドルウェアを除外する", "/healthz", http.StatusOK, "log"},
+		{"成功：指定した名前のみ除外する", "/public", http.StatusOK, "log,audit"},
+		{"成功：一致しない名前は何もしない", "/other", http.StatusUnauthorized, "log,auth"},
+		{"成功：ルートが無い場合は除外しない", "/none", http.StatusUnauthorized, "log,auth"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			called = nil
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			testutil.AssertEqual(t, w.Code, tc.code)
+			testutil.AssertEqual(t, strings.Join(called, ","), tc.called)
+		})
+	}
+}
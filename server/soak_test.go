@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+
+[ソークテストについて]
+ミドルウェアのチェーンやBindでのボディのバッファリングにリークが無いことを確認するため、
+プロセス内でリクエストを長時間流し続け、goroutine数とヒープの増加量を監視する。
+時間がかかるため、環境変数SOAK_DURATIONが指定された場合のみ実行する。
+
+	SOAK_DURATION=5m go test -v -count=1 -timeout 0 -run ^TestSoak$ ./server
+
+閾値は以下の環境変数で変更できる。
+・SOAK_MAX_GOROUTINE_GROWTH (デフォルト: 10)
+・SOAK_MAX_HEAP_GROWTH_MB   (デフォルト: 16)
+
+*/
+
+// go test -v -count=1 -timeout 0 -run ^TestSoak$ ./server
+func TestSoak(t *testing.T) {
+	duration, err := time.ParseDuration(os.Getenv("SOAK_DURATION"))
+	if err != nil {
+		t.Skip("SOAK_DURATION is not set")
+	}
+	maxGoroutineGrowth := getEnvInt(t, "SOAK_MAX_GOROUTINE_GROWTH", 10)
+	maxHeapGrowth := uint64(getEnvInt(t, "SOAK_MAX_HEAP_GROWTH_MB", 16)) << 20
+
+	resetSetting()
+	defer resetSetting()
+	passThrough := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+	SetCommonMiddleware(passThrough)
+	SetCommonAfterMiddleware(passThrough)
+	Get("/friend/:number", func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, &getFriendRequest{}, &getFriendResponse{}, http.StatusOK, func(req *getFriendRequest) (*friend, error) {
+			return &friend{ID: strconv.Itoa(req.Number)}, nil
+		})
+	}, passThrough)
+	Post("/comment", func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, &addCommentRequest{}, (*emptyDataResponse)(nil), http.StatusCreated, func(req *addCommentRequest) (any, error) {
+			return nil, nil
+		})
+	}, passThrough)
+	Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("dummy panic")
+	})
+
+	// ログの出力でテストの結果が見づらくならないようにする。
+	SetLogger(&discardLogger{})
+	defer SetLogger(&defaultLogger{})
+
+	replay := func(until time.Time) {
+		var wg sync.WaitGroup
+		for range runtime.GOMAXPROCS(0) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for time.Now().Before(until) {
+					execRequest[any](t, http.MethodGet, "/friend/1", nil, nil, http.StatusOK, nil)
+					execRequest[any](t, http.MethodPost, "/comment", stringToIoReader(`{"comment":"test"}`), nil, http.StatusCreated, nil)
+					execRequest[any](t, http.MethodPost, "/comment", stringToIoReader(`{"comment":`), nil, http.StatusBadRequest, nil)
+					execRequest[any](t, http.MethodGet, "/panic", nil, nil, http.StatusInternalServerError, nil)
+					execRequest[any](t, http.MethodGet, "/not-found", nil, nil, http.StatusNotFound, nil)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// プールやキャッシュが温まるまで流してから基準値を取る。
+	replay(time.Now().Add(time.Second))
+	baseGoroutines, baseHeap := measure()
+
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		replay(minTime(deadline, time.Now().Add(10*time.Second)))
+		goroutines, heap := measure()
+		t.Logf("goroutines: %d (base %d), heap: %d bytes (base %d)", goroutines, baseGoroutines, heap, baseHeap)
+		if goroutines-baseGoroutines > maxGoroutineGrowth {
+			t.Fatalf("goroutine leak: %d -> %d", baseGoroutines, goroutines)
+		}
+		if heap > baseHeap && heap-baseHeap > maxHeapGrowth {
+			t.Fatalf("memory leak: %d -> %d bytes", baseHeap, heap)
+		}
+	}
+}
+
+func measure() (goroutines int, heap uint64) {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return runtime.NumGoroutine(), m.HeapAlloc
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func getEnvInt(t *testing.T, key string, defaultVal int) int {
+	t.Helper()
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultVal
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		t.Fatalf("invalid %s: %s", key, v)
+	}
+	return i
+}
+
+type discardLogger struct{}
+
+func (l *discardLogger) Info(c context.Context, args ...any)  {}
+func (l *discardLogger) Debug(c context.Context, args ...any) {}
+func (l *discardLogger) Warn(c context.Context, args ...any)  {}
+func (l *discardLogger) Error(c context.Context, args ...any) {}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// 静的ファイルの配信の設定
+type StaticConfig struct {
+	// index.htmlが無いディレクトリへのリクエストにファイルの一覧を返す。
+	// falseの場合は404を返す。
+	DirectoryListing bool
+	// 存在しないパスへのリクエストに、ルートのindex.htmlを返す(シングルページアプリケーション用)
+	// 最後のセグメントに拡張子を含むパス(例: /app/main.js)は対象外で、404を返す。
+	SPAFallback bool
+}
+
+// プレフィックス配下のGETリクエストに、ディレクトリのファイルを返すルートを登録する。
+// Content-Typeはファイルの拡張子から決定し、ディレクトリの外(..を含むパスなど)は参照できない。
+// ディレクトリの一覧は返さない。(StaticWithConfigで変更できる)
+// プレフィックスは"/"で始まり、"/"で終わらない必要がある。("/"はすべてのパスが対象)
+//
+//	server.Static("/assets", "./public") // GET /assets/css/app.css -> ./public/css/app.css
+func Static(prefix string, dir string, middleware ...Middleware) *Route {
+	return StaticWithConfig(prefix, dir, StaticConfig{}, middleware...)
+}
+
+// 設定を指定してStaticと同様のルートを登録する。
+func StaticWithConfig(prefix string, dir string, conf StaticConfig, middleware ...Middleware) *Route {
+	return staticRoute(prefix, os.DirFS(dir), conf, middleware)
+}
+
+// プレフィックス配下のGETリクエストに、fs.FS(embed.FSなど)のファイルを返すルートを登録する。
+// ビルドしたフロントエンドをバイナリに含めて配信する場合に利用する。
+// ファイルの扱いはStaticと同様。
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	sub, _ := fs.Sub(dist, "dist")
+//	server.StaticFS("/", sub, server.StaticConfig{SPAFallback: true})
+func StaticFS(prefix string, fsys fs.FS, conf StaticConfig, middleware ...Middleware) *Route {
+	return staticRoute(prefix, fsys, conf, middleware)
+}
+
+func staticRoute(prefix string, fsys fs.FS, conf StaticConfig, middleware []Middleware) *Route {
+	if prefix == "/" {
+		prefix = ""
+	} else {
+		mustValidPrefix(prefix)
+	}
+	return Get(prefix+"/*path", staticHandler(fsys, conf), middleware...)
+}
+
+func staticHandler(fsys fs.FS, conf StaticConfig) Handler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// ディレクトリへのリクエスト(末尾が"/")はディレクトリ名として扱う。
+		name := strings.TrimSuffix(getPathParamVal(r, "path"), "/")
+		if name == "" {
+			name = "."
+		}
+		// ".."などでディレクトリの外を参照させない。
+		if !fs.ValidPath(name) {
+			SetResponse(w, r, noMethodContentType, http.StatusNotFound, noMethodResponse)
+			return
+		}
+		info, err := fs.Stat(fsys, name)
+		if err == nil && info.IsDir() && !conf.DirectoryListing {
+			_, err = fs.Stat(fsys, path.Join(name, "index.html"))
+		}
+		if err != nil {
+			if conf.SPAFallback && path.Ext(name) == "" {
+				http.ServeFileFS(w, r, fsys, "index.html")
+				return
+			}
+			SetResponse(w, r, noMethodContentType, http.StatusNotFound, noMethodResponse)
+			return
+		}
+		// http.FSは"/"始まりの名前を受け取る。(ルートを"."のまま渡すとindex.htmlが"./index.html"となり解決できない)
+		if name == "." {
+			name = ""
+		}
+		http.ServeFileFS(w, r, fsys, "/"+name)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestStatic$ ./server
+func TestStatic(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"css/app.css":     "body{}",
+		"docs/index.html": "<h1>docs</h1>",
+		"empty/a.txt":     "a",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("成功：ファイルを返す", func(t *testing.T) {
+		resetSetting()
+		Static("/assets", dir)
+		w := do("/assets/css/app.css")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Header().Get("Content-Type"), "text/css; charset=utf-8")
+		testutil.AssertEqual(t, w.Body.String(), "body{}")
+	})
+
+	t.Run("成功：ディレクトリのindex.html", func(t *testing.T) {
+		resetSetting()
+		Static("/assets", dir)
+		w := do("/assets/docs/")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), "<h1>docs</h1>")
+	})
+
+	t.Run("失敗：存在しないファイル", func(t *testing.T) {
+		resetSetting()
+		Static("/assets", dir)
+		testutil.AssertEqual(t, do("/assets/none.js").Code, http.StatusNotFound)
+	})
+
+	t.Run("失敗：ディレクトリの外は参照できない", func(t *testing.T) {
+		resetSetting()
+		Static("/assets", dir)
+		testutil.AssertEqual(t, do("/assets/../secret.txt").Code, http.StatusNotFound)
+		testutil.AssertEqual(t, do("/assets/%2E%2E/secret.txt").Code, http.StatusNotFound)
+		testutil.AssertEqual(t, do("/assets/..%2Fsecret.txt").Code, http.StatusNotFound)
+	})
+
+	t.Run("失敗：デフォルトではディレクトリの一覧を返さない", func(t *testing.T) {
+		resetSetting()
+		Static("/assets", dir)
+		testutil.AssertEqual(t, do("/assets/empty/").Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：ディレクトリの一覧を返す", func(t *testing.T) {
+		resetSetting()
+		StaticWithConfig("/assets", dir, StaticConfig{DirectoryListing: true})
+		w := do("/assets/empty/")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\"a.txt\">a.txt</a>\n</pre>\n")
+	})
+
+	t.Run("成功：/はすべてのパスが対象", func(t *testing.T) {
+		resetSetting()
+		Static("/", dir)
+		testutil.AssertEqual(t, do("/css/app.css").Code, http.StatusOK)
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStaticFS$ ./server
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<div id=app></div>")},
+		"main.js":     {Data: []byte("console.log(1)")},
+		"about/a.txt": {Data: []byte("a")},
+	}
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("成功：fs.FSのファイルを返す", func(t *testing.T) {
+		resetSetting()
+		StaticFS("/app", fsys, StaticConfig{})
+		w := do("/app/main.js")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Header().Get("Content-Type"), "text/javascript; charset=utf-8")
+		testutil.AssertEqual(t, w.Body.String(), "console.log(1)")
+		testutil.AssertEqual(t, do("/app/users/1").Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：存在しないパスはindex.htmlを返す", func(t *testing.T) {
+		resetSetting()
+		StaticFS("/", fsys, StaticConfig{SPAFallback: true})
+		for _, target := range []string{"/", "/users/1", "/about/"} {
+			w := do(target)
+			testutil.AssertEqual(t, w.Code, http.StatusOK)
+			testutil.AssertEqual(t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+			testutil.AssertEqual(t, w.Body.String(), "<div id=app></div>")
+		}
+		testutil.AssertEqual(t, do("/main.js").Body.String(), "console.log(1)")
+	})
+
+	t.Run("失敗：拡張子を含むパスはindex.htmlを返さない", func(t *testing.T) {
+		resetSetting()
+		StaticFS("/", fsys, StaticConfig{SPAFallback: true})
+		testutil.AssertEqual(t, do("/chunk.js").Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：他のルートが優先される", func(t *testing.T) {
+		resetSetting()
+		StaticFS("/", fsys, StaticConfig{SPAFallback: true})
+		Get("/api/users", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte("users"))
+		})
+		testutil.AssertEqual(t, do("/api/users").Body.String(), "users")
+	})
+}
@@ -0,0 +1,38 @@
+package server
+
+import "sync/atomic"
+
+// Bind関数の内部の統計情報
+// メトリクスの収集など、Bindの性能を本番環境で観測する目的で利用する。
+type BindStats struct {
+	// Bindの実行回数
+	Binds uint64 `json:"binds"`
+	// Bindがエラーを返した回数
+	Errors uint64 `json:"errors"`
+	// Bind内でバッファリングしたリクエストボディの合計バイト数
+	BytesBuffered uint64 `json:"bytes_buffered"`
+	// 構造体のフィールド情報のキャッシュがヒットした回数
+	CacheHits uint64 `json:"cache_hits"`
+	// 構造体のフィールド情報のキャッシュがヒットせず、タグを解析した回数
+	CacheMisses uint64 `json:"cache_misses"`
+}
+
+var bindStats struct {
+	binds         atomic.Uint64
+	errors        atomic.Uint64
+	bytesBuffered atomic.Uint64
+	cacheHits     atomic.Uint64
+	cacheMisses   atomic.Uint64
+}
+
+// 現時点のBindの統計情報を返す。
+// 値はプロセス起動時からの累計となる。
+func GetBindStats() BindStats {
+	return BindStats{
+		Binds:         bindStats.binds.Load(),
+		Errors:        bindStats.errors.Load(),
+		BytesBuffered: bindStats.bytesBuffered.Load(),
+		CacheHits:     bindStats.cacheHits.Load(),
+		CacheMisses:   bindStats.cacheMisses.Load(),
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatusHandlerでエラー率を集計する期間(分)
+const statusWindowMinutes = 15
+
+// StatusHandlerで各ヘルスチェックに設定するタイムアウト
+var HealthCheckTimeout = time.Second * 3
+
+// 直近のリクエスト数とエラー(5xx)数を1分ごとに集計する。
+type requestWindow struct {
+	mu      sync.Mutex
+	buckets [statusWindowMinutes]struct {
+		minute   int64
+		requests uint64
+		errors   uint64
+	}
+}
+
+var recentRequests requestWindow
+
+func (rw *requestWindow) record(now time.Time, status int) {
+	minute := now.Unix() / 60
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	b := &rw.buckets[minute%statusWindowMinutes]
+	if b.minute != minute {
+		b.minute, b.requests, b.errors = minute, 0, 0
+	}
+	b.requests++
+	if status >= 500 {
+		b.errors++
+	}
+}
+
+// 直近statusWindowMinutes分のリクエスト数とエラー数を返す。
+func (rw *requestWindow) snapshot(now time.Time) (requests, errors uint64) {
+	minute := now.Unix() / 60
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	for _, b := range rw.buckets {
+		if minute-b.minute < statusWindowMinutes {
+			requests += b.requests
+			errors += b.errors
+		}
+	}
+	return requests, errors
+}
+
+// StatusHandlerで表示するエラー率を集計するミドルウェア
+// ステータスコードが5xxのレスポンス(panicを含む)をエラーとして数える。
+//
+//	server.SetCommonMiddleware(server.RequestStatsMiddleware)
+func RequestStatsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsObservabilityDisabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := newResponseRecorder(w)
+		completed := false
+		defer func() {
+			status := rec.statusCode()
+			if !completed {
+				status = http.StatusInternalServerError
+			}
+			recentRequests.record(time.Now(), status)
+		}()
+		next.ServeHTTP(rec, r)
+		completed = true
+	})
+}
+
+// ステータスページに出力する情報
+type statusPage struct {
+	Healthy   bool
+	Ready     bool
+	Uptime    time.Duration
+	Checks    []statusCheck
+	Window    int
+	Requests  uint64
+	Errors    uint64
+	ErrorRate string
+	Bind      BindStats
+}
+
+type statusCheck struct {
+	Name    string
+	Error   string
+	Latency time.Duration
+}
+
+// 登録されたヘルスチェックを並行して実行する。
+func runHealthChecks(c context.Context) []statusCheck {
+	results := make([]statusCheck, len(healthChecks))
+	var wg sync.WaitGroup
+	for i, h := range healthChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c, HealthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			results[i] = statusCheck{Name: h.name}
+			defer func() {
+				if rv := recover(); rv != nil {
+					results[i].Error = fmt.Sprintf("panic: %v", rv)
+				}
+				results[i].Latency = time.Since(start).Round(time.Millisecond)
+			}()
+			if err := h.check(ctx); err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func newStatusPage(c context.Context, now time.Time) statusPage {
+	p := statusPage{
+		Ready:  IsReady(),
+		Checks: runHealthChecks(c),
+		Window: statusWindowMinutes,
+		Bind:   GetBindStats(),
+	}
+	// 起動時の処理(OnStartup)が失敗した場合は、その結果を先頭に含める。
+	if err := startupErr.Load(); err != nil {
+		p.Checks = append([]statusCheck{{Name: "startup", Error: (*err).Error()}}, p.Checks...)
+	}
+	if s := startedAt.Load(); s != 0 {
+		p.Uptime = now.Sub(time.Unix(0, s)).Round(time.Second)
+	}
+	p.Healthy = p.Ready
+	for _, ch := range p.Checks {
+		if ch.Error != "" {
+			p.Healthy = false
+		}
+	}
+	p.Requests, p.Errors = recentRequests.snapshot(now)
+	p.ErrorRate = "-"
+	if p.Requests > 0 {
+		p.ErrorRate = fmt.Sprintf("%.2f%%", float64(p.Errors)*100/float64(p.Requests))
+	}
+	return p
+}
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ddd; padding: 0.2em 0.6em; text-align: left; }
+.ok { color: #1a7f37; }
+.ng { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Status: {{if .Healthy}}<span class="ok">OK</span>{{else}}<span class="ng">DEGRADED</span>{{end}}</h1>
+<table>
+<tr><th>ready</th><td>{{.Ready}}</td></tr>
+<tr><th>uptime</th><td>{{.Uptime}}</td></tr>
+</table>
+<h2>Health checks</h2>
+{{if .Checks}}<table>
+<tr><th>name</th><th>status</th><th>latency</th></tr>
+{{range .Checks}}<tr><td>{{.Name}}</td>{{if .Error}}<td class="ng">{{.Error}}</td>{{else}}<td class="ok">ok</td>{{end}}<td>{{.Latency}}</td></tr>
+{{end}}</table>{{else}}<p>no health checks</p>{{end}}
+<h2>Requests (last {{.Window}} minutes)</h2>
+<table>
+<tr><th>requests</th><td>{{.Requests}}</td></tr>
+<tr><th>errors (5xx)</th><td>{{.Errors}}</td></tr>
+<tr><th>error rate</th><td>{{.ErrorRate}}</td></tr>
+</table>
+<h2>Bind</h2>
+<table>
+<tr><th>binds</th><td>{{.Bind.Binds}}</td></tr>
+<tr><th>errors</th><td>{{.Bind.Errors}}</td></tr>
+</table>
+</body>
+</html>`))
+
+// 運用者向けにサーバーの状態をHTMLで返すハンドラ
+// readiness、起動からの経過時間、RegisterHealthCheckで登録したヘルスチェックの結果、
+// 直近のエラー率(RequestStatsMiddlewareで集計)、Bindの統計情報を表示する。
+// ヘルスチェックはリクエストのたびに実行されるため、必要に応じて認証のミドルウェアを設定する。
+//
+//	server.Get("/status", server.StatusHandler)
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	var buf strings.Builder
+	if err := statusTemplate.Execute(&buf, newStatusPage(r.Context(), time.Now())); err != nil {
+		panic(err)
+	}
+	SetResponse(w, r, ContentTypeHTMLWithCharset, http.StatusOK, []byte(buf.String()))
+}
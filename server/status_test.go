@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestStatusHandler$ ./server
+func TestStatusHandler(t *testing.T) {
+	defer func() { recentRequests = requestWindow{} }()
+
+	t.Run("成功：ヘルスチェックの結果とエラー率が表示される", func(t *testing.T) {
+		resetSetting()
+		recentRequests = requestWindow{}
+		RegisterHealthCheck("db", func(c context.Context) error { return nil })
+		RegisterHealthCheck("cache", func(c context.Context) error { return errors.New("connection refused") })
+		SetCommonMiddleware(RequestStatsMiddleware)
+		Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+			SetResponseAsJson(w, r, http.StatusOK, nil)
+		})
+		Get("/ng", func(w http.ResponseWriter, r *http.Request) {
+			SetResponseAsJson(w, r, http.StatusServiceUnavailable, nil)
+		})
+		Get("/status", StatusHandler)
+		for _, path := range []string{"/ok", "/ok", "/ok", "/ng"} {
+			HTTPHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		body := w.Body.String()
+		for _, s := range []string{
+			"DEGRADED",
+			`<td>db</td><td class="ok">ok</td>`,
+			`<td>cache</td><td class="ng">connection refused</td>`,
+			"<tr><th>errors (5xx)</th><td>1</td></tr>",
+			"<tr><th>error rate</th><td>25.00%</td></tr>",
+		} {
+			if !strings.Contains(body, s) {
+				t.Errorf("body should contain %q: %s", s, body)
+			}
+		}
+	})
+
+	t.Run("失敗：ヘルスチェックの名前の重複はpanic", func(t *testing.T) {
+		resetSetting()
+		RegisterHealthCheck("db", func(c context.Context) error { return nil })
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		RegisterHealthCheck("db", func(c context.Context) error { return nil })
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestRequestWindow$ ./server
+func TestRequestWindow(t *testing.T) {
+	var rw requestWindow
+	base := time.Unix(1700000000, 0)
+	rw.record(base, http.StatusOK)
+	rw.record(base, http.StatusInternalServerError)
+	rw.record(base.Add(time.Minute*5), http.StatusOK)
+
+	requests, errs := rw.snapshot(base.Add(time.Minute * 5))
+	testutil.AssertEqual(t, requests, uint64(3))
+	testutil.AssertEqual(t, errs, uint64(1))
+
+	// 集計期間を過ぎたものは含まれない。
+	requests, errs = rw.snapshot(base.Add(time.Minute * statusWindowMinutes))
+	testutil.AssertEqual(t, requests, uint64(1))
+	testutil.AssertEqual(t, errs, uint64(0))
+
+	// 同じバケットを再利用する場合は古い値がリセットされる。
+	rw.record(base.Add(time.Minute*statusWindowMinutes), http.StatusOK)
+	requests, _ = rw.snapshot(base.Add(time.Minute * statusWindowMinutes))
+	testutil.AssertEqual(t, requests, uint64(2))
+}
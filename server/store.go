@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 複数のインスタンスで状態を共有するためのKVストア
+// レート制限(StoreRateLimitMiddleware)などの状態を持つ機能で利用する。
+// 単一のインスタンスの場合はMemoryStore、複数のインスタンスの場合はredisstoreパッケージなどの実装を利用する。
+type Store interface {
+	// 値を取得する。キーが存在しない場合はfalseを返す。
+	Get(c context.Context, key string) ([]byte, bool, error)
+	// 値を保存する。ttlが0の場合は期限なし。
+	Set(c context.Context, key string, val []byte, ttl time.Duration) error
+	// キーが存在しない場合のみ値を保存する。保存した場合はtrueを返す。
+	SetNX(c context.Context, key string, val []byte, ttl time.Duration) (bool, error)
+	// 値を削除する。キーが存在しない場合も成功とする。
+	Delete(c context.Context, key string) error
+	// 整数の値にnを加算して、加算後の値を返す。
+	// キーが存在しない場合は0として加算し、ttlを期限として設定する。(既存のキーの期限は変更しない)
+	IncrBy(c context.Context, key string, n int64, ttl time.Duration) (int64, error)
+}
+
+// メモリ上のStore
+// 単一のインスタンスで利用する場合や、テストで利用する。
+// 期限切れの値は参照時、および保存数が一定を超えた場合に削除される。
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStoreEntry
+}
+
+type memoryStoreEntry struct {
+	val []byte
+	// ゼロ値の場合は期限なし
+	expires time.Time
+}
+
+// 期限切れの値の削除を行う保存数
+const memoryStorePurgeThreshold = 10000
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryStoreEntry{}}
+}
+
+func (e memoryStoreEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// ロックを取得した状態で、期限切れでない値を返す。
+func (s *MemoryStore) load(key string, now time.Time) (memoryStoreEntry, bool) {
+	e, ok := s.entries[key]
+	if ok && e.expired(now) {
+		delete(s.entries, key)
+		return memoryStoreEntry{}, false
+	}
+	return e, ok
+}
+
+// ロックを取得した状態で値を保存する。
+func (s *MemoryStore) store(key string, val []byte, ttl time.Duration, now time.Time) {
+	if len(s.entries) >= memoryStorePurgeThreshold {
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	e := memoryStoreEntry{val: val}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	s.entries[key] = e
+}
+
+func (s *MemoryStore) Get(c context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.load(key, time.Now())
+	return e.val, ok, nil
+}
+
+func (s *MemoryStore) Set(c context.Context, key string, val []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(key, val, ttl, time.Now())
+	return nil
+}
+
+func (s *MemoryStore) SetNX(c context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if _, ok := s.load(key, now); ok {
+		return false, nil
+	}
+	s.store(key, val, ttl, now)
+	return true, nil
+}
+
+func (s *MemoryStore) Delete(c context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) IncrBy(c context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	e, ok := s.load(key, now)
+	if !ok {
+		s.store(key, []byte(strconv.FormatInt(n, 10)), ttl, now)
+		return n, nil
+	}
+	cur, err := strconv.ParseInt(string(e.val), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of %s is not an integer", key)
+	}
+	cur += n
+	e.val = []byte(strconv.FormatInt(cur, 10))
+	s.entries[key] = e
+	return cur, nil
+}
+
+// Storeを利用した固定ウィンドウによるレート制限のミドルウェア
+// keyが返す値ごとに、windowの期間内にlimitまでのコスト(Route.Cost、デフォルトは1)を許可する。
+// 状態をStoreで共有するため、複数のインスタンスで合計した制限となる。
+// ヘッダー、429のレスポンスはRateLimitMiddlewareと同じ。
+// Storeがエラーを返した場合は、リクエストを制限せずに処理する。(エラーはログに出力する)
+// limit、windowが0以下の場合はpanicとなる。
+//
+//	server.SetCommonAfterMiddleware(server.StoreRateLimitMiddleware(redisStore, 100, time.Minute, clientIP))
+func StoreRateLimitMiddleware(store Store, limit int64, window time.Duration, key func(r *http.Request) string) Middleware {
+	if limit <= 0 || window <= 0 {
+		panic(fmt.Sprintf("rate limit must be positive, got limit=%d window=%s", limit, window))
+	}
+	stat := registerLimiter("store_rate_limit", func() map[string]int64 {
+		return map[string]int64{"limit": limit, "window_seconds": int64(window / time.Second)}
+	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			windowStart := now.Truncate(window)
+			storeKey := fmt.Sprintf("ratelimit:%s:%d", key(r), windowStart.Unix())
+			// limitを超えるコストは、常に制限されないようにlimitとして扱う。
+			used, err := store.IncrBy(r.Context(), storeKey, min(RouteCost(r), limit), window)
+			if err != nil {
+				l.Error(r.Context(), fmt.Sprintf("failed to update rate limit: %s", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			info := RateLimitInfo{Limit: limit, Remaining: max(0, limit-used), Reset: windowStart.Add(window).Sub(now)}
+			if used > limit {
+				stat.reject(r)
+				SetTooManyRequestsResponse(w, r, info)
+				return
+			}
+			SetRateLimitHeaders(w, info)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestMemoryStore$ ./server
+func TestMemoryStore(t *testing.T) {
+	c := context.Background()
+	s := NewMemoryStore()
+
+	s.Set(c, "a", []byte("1"), 0)
+	v, ok, _ := s.Get(c, "a")
+	testutil.AssertEqual(t, ok, true)
+	testutil.AssertEqual(t, string(v), "1")
+
+	set, _ := s.SetNX(c, "a", []byte("2"), 0)
+	testutil.AssertEqual(t, set, false)
+	set, _ = s.SetNX(c, "b", []byte("2"), 50*time.Millisecond)
+	testutil.AssertEqual(t, set, true)
+
+	n, _ := s.IncrBy(c, "a", 2, 0)
+	testutil.AssertEqual(t, n, int64(3))
+	_, err := s.IncrBy(c, "c", 1, 0)
+	testutil.AssertEqual(t, err, nil)
+	s.Set(c, "d", []byte("x"), 0)
+	if _, err := s.IncrBy(c, "d", 1, 0); err == nil {
+		t.Error("should fail for non integer value")
+	}
+
+	// 期限切れ
+	time.Sleep(60 * time.Millisecond)
+	_, ok, _ = s.Get(c, "b")
+	testutil.AssertEqual(t, ok, false)
+
+	s.Delete(c, "a")
+	_, ok, _ = s.Get(c, "a")
+	testutil.AssertEqual(t, ok, false)
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestStoreRateLimitMiddleware$ ./server
+func TestStoreRateLimitMiddleware(t *testing.T) {
+	resetSetting()
+	store := NewMemoryStore()
+	// 2つのインスタンスで同じStoreを共有する。
+	limiter := func() Middleware {
+		return StoreRateLimitMiddleware(store, 3, time.Hour, func(r *http.Request) string { return "client" })
+	}
+	instance1 := limiter()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	instance2 := limiter()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(h http.Handler) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+		return res
+	}
+	testutil.AssertEqual(t, serve(instance1).Code, http.StatusOK)
+	testutil.AssertEqual(t, serve(instance2).Code, http.StatusOK)
+	res := serve(instance1)
+	testutil.AssertEqual(t, res.Code, http.StatusOK)
+	testutil.AssertEqual(t, res.Header().Get("RateLimit-Remaining"), "0")
+	testutil.AssertEqual(t, serve(instance2).Code, http.StatusTooManyRequests)
+}
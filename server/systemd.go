@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+
+[systemdとの連携について]
+systemdから起動された場合(環境変数で判定)は以下を行う。それ以外の環境では何もしない。
+・起動完了時にREADY=1、シャットダウン開始時にSTOPPING=1を通知する。(Type=notify)
+・WatchdogSecが設定されている場合は、その半分の間隔でWATCHDOG=1を通知する。
+・ソケットアクティベーション(.socketユニット)で渡されたリスナーがある場合はそれを利用する。
+
+*/
+
+// 環境変数NOTIFY_SOCKETのソケットへ状態を通知する。
+// systemdから起動されていない場合は何もしない。
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// "@"で始まる場合はabstract namespaceのソケット
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// 環境変数WATCHDOG_USECから通知の間隔を取得する。
+// watchdogが無効な場合は0を返す。
+func sdWatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// タイムアウトの半分の間隔で通知する。(sd_watchdog_enabledの推奨)
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// watchdogが有効な場合、cがキャンセルされるまで定期的にWATCHDOG=1を通知する。
+func startSdWatchdog(c context.Context) {
+	interval := sdWatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Done():
+				return
+			case <-ticker.C:
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					l.Warn(c, fmt.Sprintf("failed to notify watchdog: %s", err))
+				}
+			}
+		}
+	}()
+}
+
+// ソケットアクティベーションで渡された最初のリスナーを返す。
+// 渡されていない場合はnilを返す。
+func sdActivatedListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	// 渡されたファイルディスクリプタは3から始まる。(SD_LISTEN_FDS_START)
+	f := os.NewFile(3, "LISTEN_FD_3")
+	defer f.Close() // FileListenerは複製したディスクリプタを利用する。
+	return net.FileListener(f)
+}
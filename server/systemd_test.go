@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// 通知先のソケットを作成し、NOTIFY_SOCKETに設定する。
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestSdNotify$ ./server
+func TestSdNotify(t *testing.T) {
+	t.Run("成功：NOTIFY_SOCKETが無い場合は何もしない", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		if err := sdNotify("READY=1"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+
+	t.Run("成功：起動とシャットダウンの通知", func(t *testing.T) {
+		conn := listenNotifySocket(t)
+		resetSetting()
+		go StartServer(context.Background(), "127.0.0.1", 8091)
+		testutil.AssertEqual(t, readNotify(t, conn), "READY=1")
+		Shutdown()
+		testutil.AssertEqual(t, readNotify(t, conn), "STOPPING=1")
+	})
+
+	t.Run("成功：watchdogの通知", func(t *testing.T) {
+		conn := listenNotifySocket(t)
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+		testutil.AssertEqual(t, sdWatchdogInterval(), 10*time.Millisecond)
+
+		c, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		startSdWatchdog(c)
+		testutil.AssertEqual(t, readNotify(t, conn), "WATCHDOG=1")
+	})
+
+	t.Run("成功：他のプロセス向けのwatchdogは無視する", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", "1")
+		testutil.AssertEqual(t, sdWatchdogInterval(), time.Duration(0))
+	})
+}
+
+// go test -v -count=1 -timeout 60s -run ^TestSdActivatedListener$ ./server
+func TestSdActivatedListener(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	listener, err := sdActivatedListener()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if listener != nil {
+		t.Error("listener for another process should be ignored")
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server-Timingヘッダーに出力する処理の時間
+type serverTiming struct {
+	name string
+	dur  time.Duration
+	desc string
+}
+
+// リクエストごとに記録した処理の時間
+type serverTimings struct {
+	mu      sync.Mutex
+	entries []serverTiming
+}
+
+// 処理(フェーズ)の時間を記録する。ServerTimingMiddlewareでServer-Timingヘッダーに出力される。
+// nameはヘッダーのメトリクス名(トークン)、descは説明(省略可)となる。
+// ServerTimingMiddlewareが設定されていない場合は何もしない。
+// ヘッダーはレスポンスの書き込み開始時に出力されるため、それ以降に記録したものは含まれない。
+func AddServerTiming(r *http.Request, name string, d time.Duration, desc string) {
+	t, ok := r.Context().Value(contextKey{Key: "serverTimings"}).(*serverTimings)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, serverTiming{name: name, dur: d, desc: desc})
+}
+
+// 処理の時間の計測を開始し、終了時に呼び出す関数を返す。(AddServerTimingを参照)
+//
+//	defer server.StartServerTiming(r, "db", "")()
+func StartServerTiming(r *http.Request, name string, desc string) (stop func()) {
+	start := time.Now()
+	return func() {
+		AddServerTiming(r, name, time.Since(start), desc)
+	}
+}
+
+// レスポンスにX-Response-TimeとServer-Timingヘッダーを付与するミドルウェア
+// 値はレスポンスの書き込み開始(ヘッダーの送信)までの時間となる。
+// Server-TimingにはAddServerTiming、StartServerTimingで記録した処理の時間と、合計(total)を出力する。
+// ブラウザの開発者ツールやAPMで、サーバー側の処理の内訳を確認するために利用する。
+//
+//	server.SetCommonMiddleware(server.ServerTimingMiddleware)
+func ServerTimingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsObservabilityDisabled(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		timings := &serverTimings{}
+		tw := &timingResponseWriter{ResponseWriter: w, start: time.Now(), timings: timings}
+		next.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), contextKey{Key: "serverTimings"}, timings)))
+		// 何も書き込まれていない場合も、net/httpが200を返す前にヘッダーを付与する。
+		tw.setHeaders()
+	})
+}
+
+// 最初の書き込みの前にタイミングのヘッダーを付与するhttp.ResponseWriter
+type timingResponseWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	timings     *serverTimings
+	wroteHeader bool
+}
+
+func (w *timingResponseWriter) setHeaders() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	total := time.Since(w.start)
+	h := w.Header()
+	h.Set("X-Response-Time", formatMillis(total)+"ms")
+
+	w.timings.mu.Lock()
+	defer w.timings.mu.Unlock()
+	metrics := make([]string, 0, len(w.timings.entries)+1)
+	for _, t := range w.timings.entries {
+		m := t.name + ";dur=" + formatMillis(t.dur)
+		if t.desc != "" {
+			m += ";desc=" + strconv.Quote(t.desc)
+		}
+		metrics = append(metrics, m)
+	}
+	metrics = append(metrics, "total;dur="+formatMillis(total))
+	h.Add("Server-Timing", strings.Join(metrics, ", "))
+}
+
+// ミリ秒の値を小数点以下3桁までの文字列で返す。
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
+
+func (w *timingResponseWriter) WriteHeader(statusCode int) {
+	// 1xxのレスポンスは最終的なレスポンスではないため付与しない。
+	if statusCode >= 200 {
+		w.setHeaders()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timingResponseWriter) Write(b []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timingResponseWriter) Flush() {
+	w.setHeaders()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *timingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// http.ResponseControllerから元のResponseWriterを利用できるようにする。
+func (w *timingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
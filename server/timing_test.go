@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestServerTimingMiddleware$ ./server
+func TestServerTimingMiddleware(t *testing.T) {
+	resetSetting()
+	SetCommonMiddleware(ServerTimingMiddleware)
+	Get("/timing", func(w http.ResponseWriter, r *http.Request) {
+		stop := StartServerTiming(r, "db", `query "users"`)
+		time.Sleep(time.Millisecond * 10)
+		stop()
+		AddServerTiming(r, "cache", time.Millisecond*2, "")
+		SetResponseAsJson(w, r, http.StatusOK, nil)
+		// 書き込み後に記録したものは出力されない。
+		AddServerTiming(r, "late", time.Millisecond, "")
+	})
+	Get("/empty", func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("成功：処理の時間と合計が出力される", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/timing", nil))
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		if !regexp.MustCompile(`^\d+\.\d{3}ms$`).MatchString(w.Header().Get("X-Response-Time")) {
+			t.Errorf("unexpected X-Response-Time: %s", w.Header().Get("X-Response-Time"))
+		}
+		re := regexp.MustCompile(`^db;dur=(1\d|[2-9]\d)\.\d{3};desc="query \\"users\\"", cache;dur=2\.000, total;dur=\d+\.\d{3}$`)
+		if !re.MatchString(w.Header().Get("Server-Timing")) {
+			t.Errorf("unexpected Server-Timing: %s", w.Header().Get("Server-Timing"))
+		}
+	})
+
+	t.Run("成功：何も書き込まない場合も出力される", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+		if !regexp.MustCompile(`^total;dur=\d+\.\d{3}$`).MatchString(w.Header().Get("Server-Timing")) {
+			t.Errorf("unexpected Server-Timing: %s", w.Header().Get("Server-Timing"))
+		}
+	})
+
+	t.Run("成功：ミドルウェアが無い場合は何もしない", func(t *testing.T) {
+		AddServerTiming(httptest.NewRequest(http.MethodGet, "/", nil), "db", time.Millisecond, "")
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// パスの末尾の"/"の有無が登録されたルートと異なるリクエストの扱い
+type TrailingSlashPolicy int
+
+const (
+	// 末尾の"/"の有無を区別する(/friendsと/friends/は別のパスとして扱う)
+	TrailingSlashStrict TrailingSlashPolicy = iota
+	// 末尾の"/"の有無のみが異なるルートがある場合に、そのパスへリダイレクトする
+	// GET、HEADは301、それ以外のメソッドはボディを引き継ぐために308を返す。
+	TrailingSlashRedirect
+	// 末尾の"/"の有無のみが異なるルートがある場合に、そのルートとして処理する
+	TrailingSlashMatchBoth
+)
+
+var trailingSlashPolicy = TrailingSlashStrict
+
+// パスの末尾の"/"の扱いを設定する。
+// デフォルトはTrailingSlashStrict。
+// 末尾の"/"の有無が一致するルートがある場合は、いずれの設定でもそのルートが優先される。
+func SetTrailingSlashPolicy(p TrailingSlashPolicy) {
+	mustNotStarted("SetTrailingSlashPolicy")
+	trailingSlashPolicy = p
+}
+
+// ルートが無い(またはRouter.NotFoundのルートのみ)場合に、末尾の"/"の有無を変えたパスのルートを探す。
+// リダイレクトのレスポンスを返した場合はtrueを返す。
+// TrailingSlashMatchBothでルートがある場合は、そのルートを返しpsへパスパラメータの値をセットする。
+func applyTrailingSlashPolicy(w http.ResponseWriter, r *http.Request, ru *route, ps *pathParamValues) (*route, bool) {
+	if trailingSlashPolicy == TrailingSlashStrict || (ru != nil && !ru.notFound) {
+		return ru, false
+	}
+	path := routingPath(r)
+	if path == "/" {
+		return ru, false
+	}
+	alt := path + "/"
+	if strings.HasSuffix(path, "/") {
+		alt = strings.TrimSuffix(path, "/")
+	}
+
+	var altPs pathParamValues
+	altRu := lookupRoute(r.Method, alt, &altPs)
+	if altRu == nil || altRu.notFound {
+		return ru, false
+	}
+
+	if trailingSlashPolicy == TrailingSlashRedirect {
+		// "//example.com"のような別のホストを指すURLへはリダイレクトしない。
+		if strings.HasPrefix(alt, "//") {
+			return ru, false
+		}
+		statusCode := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			statusCode = http.StatusMovedPermanently
+		}
+		// altはエスケープされたパス
+		location := alt
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+		Redirect(w, r, statusCode, location)
+		return nil, true
+	}
+	*ps = altPs
+	return altRu, false
+}
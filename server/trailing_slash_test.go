@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestTrailingSlashPolicy$ ./server
+func TestTrailingSlashPolicy(t *testing.T) {
+	setup := func(p TrailingSlashPolicy) {
+		resetSetting()
+		SetTrailingSlashPolicy(p)
+		echo := func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern))
+		}
+		Get("/friends", echo)
+		Post("/friends", echo)
+		Get("/docs/", echo)
+		Get("/friends/:id/", func(w http.ResponseWriter, r *http.Request) {
+			SetResponse(w, r, ContentTypePlainText, http.StatusOK, []byte(r.Pattern+" "+getPathParamVal(r, "id")))
+		})
+		Get("//evil.com/", echo)
+	}
+	do := func(method string, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HTTPHandler().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	t.Run("成功：Strictは区別する", func(t *testing.T) {
+		setup(TrailingSlashStrict)
+		testutil.AssertEqual(t, do(http.MethodGet, "/friends").Code, http.StatusOK)
+		testutil.AssertEqual(t, do(http.MethodGet, "/friends/").Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：Redirect", func(t *testing.T) {
+		setup(TrailingSlashRedirect)
+		w := do(http.MethodGet, "/friends/?page=2")
+		testutil.AssertEqual(t, w.Code, http.StatusMovedPermanently)
+		testutil.AssertEqual(t, w.Header().Get("Location"), "/friends?page=2")
+
+		w = do(http.MethodGet, "/docs")
+		testutil.AssertEqual(t, w.Code, http.StatusMovedPermanently)
+		testutil.AssertEqual(t, w.Header().Get("Location"), "/docs/")
+
+		w = do(http.MethodPost, "/friends/")
+		testutil.AssertEqual(t, w.Code, http.StatusPermanentRedirect)
+		testutil.AssertEqual(t, w.Header().Get("Location"), "/friends")
+
+		testutil.AssertEqual(t, do(http.MethodGet, "/friends").Code, http.StatusOK)
+		testutil.AssertEqual(t, do(http.MethodDelete, "/friends/").Code, http.StatusNotFound)
+	})
+
+	t.Run("失敗：Redirectで別のホストを指すパスにはリダイレクトしない", func(t *testing.T) {
+		setup(TrailingSlashRedirect)
+		testutil.AssertEqual(t, do(http.MethodGet, "//evil.com").Code, http.StatusNotFound)
+	})
+
+	t.Run("成功：MatchBoth", func(t *testing.T) {
+		setup(TrailingSlashMatchBoth)
+		w := do(http.MethodGet, "/friends/")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), "/friends")
+
+		w = do(http.MethodGet, "/friends/1")
+		testutil.AssertEqual(t, w.Code, http.StatusOK)
+		testutil.AssertEqual(t, w.Body.String(), "/friends/:id/ 1")
+	})
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 1リクエストごとの利用量のイベント
+type UsageEvent struct {
+	Tenant string
+	Method string
+	// 登録時のルートのパス(例: /friend/:number)。ルートが見つからない場合は空文字
+	Route    string
+	Status   int
+	Units    int64
+	Time     time.Time
+	Duration time.Duration
+}
+
+// UsageEventの送信先
+// リクエストの処理の後にリクエストのgoroutineで呼び出されるため、
+// 時間のかかる処理(外部への送信など)はバッファリングして非同期で行うこと。
+type UsageSink func(c context.Context, e UsageEvent)
+
+var usageUnitsContextKey = contextKey{Key: "usageUnits"}
+
+// リクエストの利用量のイベントをsinkへ送信するミドルウェア
+// tenantはリクエストからテナントを取得する関数。
+// 利用量はルートのコスト(Route.Cost、デフォルトは1)であり、ハンドラからAddUsageUnitsで加算できる。
+// 課金などのために、別途計測の処理を実装せずにAPIの利用量を集計できる。
+//
+//	server.SetCommonMiddleware(server.UsageMiddleware(tenantFromHost, billingSink))
+func UsageMiddleware(tenant func(r *http.Request) string, sink UsageSink) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			units := new(atomic.Int64)
+			rec := newResponseRecorder(w)
+			r = r.WithContext(context.WithValue(r.Context(), usageUnitsContextKey, units))
+			next.ServeHTTP(rec, r)
+			// ルーティング処理の前のミドルウェアとして設定した場合はr.Patternがセットされないため、ルートを検索する。
+			pattern, cost := "", int64(1)
+			if ru := requestRoute(r); ru != nil {
+				pattern = ru.pattern
+				if ru.cost > 0 {
+					cost = ru.cost
+				}
+			}
+			sink(r.Context(), UsageEvent{
+				Tenant:   tenant(r),
+				Method:   r.Method,
+				Route:    pattern,
+				Status:   rec.statusCode(),
+				Units:    cost + units.Load(),
+				Time:     start,
+				Duration: time.Since(start),
+			})
+		})
+	}
+}
+
+// リクエストの利用量を加算する。
+// 処理したレコード数など、リクエストごとに重みが異なる場合に利用する。
+// UsageMiddlewareが設定されていない場合は何もしない。
+func AddUsageUnits(r *http.Request, n int64) {
+	if units, ok := r.Context().Value(usageUnitsContextKey).(*atomic.Int64); ok {
+		units.Add(n)
+	}
+}
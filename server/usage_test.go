@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestUsageMiddleware$ ./server
+func TestUsageMiddleware(t *testing.T) {
+	resetSetting()
+	var events []UsageEvent
+	sink := func(c context.Context, e UsageEvent) {
+		events = append(events, e)
+	}
+	tenant := func(r *http.Request) string {
+		return r.Header.Get("X-Tenant")
+	}
+	// 後続のミドルウェアでリクエストを複製した場合も、ルートとコストが取得できることを確認する。
+	withValue := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{Key: "usageTest"}, true)))
+		})
+	}
+	SetCommonMiddleware(UsageMiddleware(tenant, sink), withValue)
+	Get("/friend/:number", func(w http.ResponseWriter, r *http.Request) {
+		AddUsageUnits(r, 9)
+		SetResponse(w, r, ContentTypePlainText, http.StatusCreated, nil)
+	}).Cost(2)
+	Get("/friends", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Tenant", "t1")
+		HTTPHandler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("/friend/1")
+	serve("/friends")
+	serve("/unknown")
+
+	testutil.AssertEqual(t, len(events), 3)
+	for i, expect := range []UsageEvent{
+		{Tenant: "t1", Method: http.MethodGet, Route: "/friend/:number", Status: http.StatusCreated, Units: 11},
+		{Tenant: "t1", Method: http.MethodGet, Route: "/friends", Status: http.StatusOK, Units: 1},
+		{Tenant: "t1", Method: http.MethodGet, Route: "", Status: http.StatusNotFound, Units: 1},
+	} {
+		e := events[i]
+		e.Time, e.Duration = expect.Time, expect.Duration
+		testutil.AssertEqual(t, e, expect)
+	}
+}
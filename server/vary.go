@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// レスポンスのVaryヘッダーにheadersを追加する。
+// 既に含まれるヘッダー(大文字・小文字は区別しない)は追加せず、値は1つのVaryヘッダーにまとめる。
+// Varyが"*"の場合は"*"のままとする。
+// リクエストヘッダーによってレスポンスを変えるミドルウェア(圧縮、言語など)から呼び出し、
+// 中間のキャッシュが異なるレスポンスを返さないようにする。
+// WriteHeaderの前に呼び出す必要がある。
+func AddVary(w http.ResponseWriter, headers ...string) {
+	h := w.Header()
+	var vary []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary = append(vary, name)
+			}
+		}
+	}
+	for _, header := range headers {
+		if containsVary(vary, "*") {
+			break
+		}
+		if header == "*" {
+			vary = []string{"*"}
+		} else if !containsVary(vary, header) {
+			vary = append(vary, http.CanonicalHeaderKey(header))
+		}
+	}
+	if len(vary) > 0 {
+		h.Set("Vary", strings.Join(vary, ", "))
+	}
+}
+
+func containsVary(vary []string, header string) bool {
+	for _, v := range vary {
+		if strings.EqualFold(v, header) {
+			return true
+		}
+	}
+	return false
+}
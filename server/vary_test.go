@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestAddVary$ ./server
+func TestAddVary(t *testing.T) {
+	for _, tc := range []struct {
+		existing []string
+		add      []string
+		expect   string
+	}{
+		{nil, []string{"Accept-Encoding"}, "Accept-Encoding"},
+		{[]string{"Origin"}, []string{"accept-language", "Accept-Encoding"}, "Origin, Accept-Language, Accept-Encoding"},
+		{[]string{"Origin, Accept-Encoding", "Cookie"}, []string{"accept-encoding"}, "Origin, Accept-Encoding, Cookie"},
+		{[]string{"*"}, []string{"Accept-Encoding"}, "*"},
+		{[]string{"Origin"}, []string{"*", "Cookie"}, "*"},
+	} {
+		w := httptest.NewRecorder()
+		for _, v := range tc.existing {
+			w.Header().Add("Vary", v)
+		}
+		AddVary(w, tc.add...)
+		testutil.AssertEqual(t, w.Header().Get("Vary"), tc.expect)
+	}
+}
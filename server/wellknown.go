@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// /.well-known/security.txt(RFC 9116)の内容
+type SecurityTxt struct {
+	// 脆弱性の連絡先(mailto:、https:などのURI)。必須
+	Contact []string
+	// 内容の有効期限。必須
+	Expires time.Time
+	// 以下は任意
+	Encryption         []string
+	Acknowledgments    []string
+	PreferredLanguages []string
+	Canonical          []string
+	Policy             []string
+	Hiring             []string
+}
+
+func (st SecurityTxt) String() string {
+	var b strings.Builder
+	write := func(field string, values []string) {
+		for _, v := range values {
+			fmt.Fprintf(&b, "%s: %s\n", field, v)
+		}
+	}
+	write("Contact", st.Contact)
+	write("Expires", []string{st.Expires.UTC().Format(time.RFC3339)})
+	write("Encryption", st.Encryption)
+	write("Acknowledgments", st.Acknowledgments)
+	if len(st.PreferredLanguages) > 0 {
+		write("Preferred-Languages", []string{strings.Join(st.PreferredLanguages, ", ")})
+	}
+	write("Canonical", st.Canonical)
+	write("Policy", st.Policy)
+	write("Hiring", st.Hiring)
+	return b.String()
+}
+
+// /.well-known/security.txtのルートを登録する。
+// ContactまたはExpiresが設定されていない場合はpanicとなる。
+//
+//	server.RegisterSecurityTxt(server.SecurityTxt{
+//		Contact: []string{"mailto:security@example.com"},
+//		Expires: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+//	})
+func RegisterSecurityTxt(st SecurityTxt) *Route {
+	if len(st.Contact) == 0 || st.Expires.IsZero() {
+		panic("security.txt requires Contact and Expires")
+	}
+	body := []byte(st.String())
+	return Get("/.well-known/security.txt", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypePlainTextWithCharset, http.StatusOK, body)
+	})
+}
+
+// パスワードの変更画面へリダイレクトする/.well-known/change-passwordのルートを登録する。
+// パスワードマネージャーが変更画面を見つけるために利用する。
+func RegisterChangePassword(url string) *Route {
+	return RedirectRoute("/.well-known/change-password", url, http.StatusFound)
+}
+
+// /.well-known/openid-configurationへのリクエストを、認証サーバー(issuer)の同じパスへ転送するルートを登録する。
+// 認証サーバーの設定をアプリケーションのドメインから参照させる場合に利用する。
+// issuerが絶対URLでない場合はpanicとなる。
+//
+//	server.RegisterOpenIDConfiguration("https://auth.example.com/realms/app")
+func RegisterOpenIDConfiguration(issuer string) *Route {
+	target, err := url.Parse(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		panic(fmt.Sprintf("issuer must be an absolute URL, got %q", issuer))
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			u := *target
+			pr.Out.URL = &u
+			pr.Out.Host = target.Host
+		},
+	}
+	return Get("/.well-known/openid-configuration", proxy.ServeHTTP)
+}
+
+// /.well-known/apple-app-site-association(ユニバーサルリンクなど)の内容
+type AppleAppSiteAssociation struct {
+	// ユニバーサルリンクの対象のアプリとパス
+	AppLinks []AppleAppLink
+	// パスワードの自動入力の対象のアプリ(例: "ABCDE12345.com.example.app")
+	WebCredentials []string
+}
+
+type AppleAppLink struct {
+	AppIDs []string
+	// 対象のパス(例: "/items/*")。先頭に"NOT "を付けると除外となる。
+	Paths []string
+}
+
+func (a AppleAppSiteAssociation) MarshalJSON() ([]byte, error) {
+	type component map[string]any
+	type detail struct {
+		AppIDs     []string    `json:"appIDs"`
+		Components []component `json:"components"`
+	}
+	v := map[string]any{}
+	if len(a.AppLinks) > 0 {
+		details := make([]detail, len(a.AppLinks))
+		for i, link := range a.AppLinks {
+			details[i] = detail{AppIDs: link.AppIDs, Components: []component{}}
+			for _, p := range link.Paths {
+				if path, ok := strings.CutPrefix(p, "NOT "); ok {
+					details[i].Components = append(details[i].Components, component{"/": path, "exclude": true})
+				} else {
+					details[i].Components = append(details[i].Components, component{"/": p})
+				}
+			}
+		}
+		v["applinks"] = map[string]any{"details": details}
+	}
+	if len(a.WebCredentials) > 0 {
+		v["webcredentials"] = map[string]any{"apps": a.WebCredentials}
+	}
+	return json.Marshal(v)
+}
+
+// /.well-known/apple-app-site-associationのルートを登録する。
+// Appleの仕様に従い、リダイレクトせずにapplication/jsonで返す。
+func RegisterAppleAppSiteAssociation(a AppleAppSiteAssociation) *Route {
+	body, err := json.Marshal(a)
+	if err != nil {
+		panic(err)
+	}
+	return Get("/.well-known/apple-app-site-association", func(w http.ResponseWriter, r *http.Request) {
+		SetResponse(w, r, ContentTypeJSON, http.StatusOK, body)
+	})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/megur0/testutil"
+)
+
+// go test -v -count=1 -timeout 60s -run ^TestWellKnown$ ./server
+func TestWellKnown(t *testing.T) {
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		io.WriteString(w, `{"path":"`+r.URL.Path+`"}`)
+	}))
+	defer issuer.Close()
+
+	resetSetting()
+	RegisterSecurityTxt(SecurityTxt{
+		Contact:            []string{"mailto:security@example.com", "https://example.com/security"},
+		Expires:            time.Date(2026, 1, 1, 0, 0, 0, 0, time.FixedZone("JST", 9*60*60)),
+		PreferredLanguages: []string{"ja", "en"},
+	})
+	RegisterChangePassword("/account/password")
+	RegisterOpenIDConfiguration(issuer.URL + "/realms/app/")
+	RegisterAppleAppSiteAssociation(AppleAppSiteAssociation{
+		AppLinks:       []AppleAppLink{{AppIDs: []string{"ABCDE12345.com.example.app"}, Paths: []string{"/items/*", "NOT /items/private/*"}}},
+		WebCredentials: []string{"ABCDE12345.com.example.app"},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+		location    string
+	}{
+		{"成功：security.txt", "/.well-known/security.txt", http.StatusOK, ContentTypePlainTextWithCharset,
+			"Contact: mailto:security@example.com\nContact: https://example.com/security\nExpires: 2025-12-31T15:00:00Z\nPreferred-Languages: ja, en\n", ""},
+		{"成功：change-password", "/.well-known/change-password", http.StatusFound, "", "", "/account/password"},
+		{"成功：openid-configuration", "/.well-known/openid-configuration", http.StatusOK, ContentTypeJSON,
+			`{"path":"/realms/app/.well-known/openid-configuration"}`, ""},
+		{"成功：apple-app-site-association", "/.well-known/apple-app-site-association", http.StatusOK, ContentTypeJSON,
+			`{"applinks":{"details":[{"appIDs":["ABCDE12345.com.example.app"],"components":[{"/":"/items/*"},{"/":"/items/private/*","exclude":true}]}]},"webcredentials":{"apps":["ABCDE12345.com.example.app"]}}`, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			testutil.AssertEqual(t, w.Code, tc.status)
+			if tc.contentType != "" {
+				testutil.AssertEqual(t, w.Header().Get("Content-Type"), tc.contentType)
+				testutil.AssertEqual(t, w.Body.String(), tc.body)
+			}
+			if tc.location != "" {
+				testutil.AssertEqual(t, w.Header().Get("Location"), tc.location)
+			}
+		})
+	}
+
+	t.Run("失敗：security.txtの必須項目が無い場合はpanic", func(t *testing.T) {
+		defer func() {
+			testutil.AssertEqual(t, recover() != nil, true)
+		}()
+		RegisterSecurityTxt(SecurityTxt{Contact: []string{"mailto:security@example.com"}})
+	})
+}